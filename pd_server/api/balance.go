@@ -0,0 +1,63 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type balanceHandler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newBalanceHandler(handler *server.Handler, rd *render.Render) *balanceHandler {
+	return &balanceHandler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+func (h *balanceHandler) GetPlan(w http.ResponseWriter, r *http.Request) {
+	h.plan(w, nil)
+}
+
+// PostPlan accepts the hypothetical weights of stores, such as
+// {"1": {"leader": 2, "region": 3}}.
+func (h *balanceHandler) PostPlan(w http.ResponseWriter, r *http.Request) {
+	var weights map[uint64]*server.PlanWeights
+	if err := readJSON(r.Body, &weights); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.plan(w, weights)
+}
+
+func (h *balanceHandler) plan(w http.ResponseWriter, weights map[uint64]*server.PlanWeights) {
+	plan, err := h.GetBalancePlan(weights)
+	if errors.Cause(err) == server.ErrNotBootstrapped {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err != nil {
+		// The weights are invalid.
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, plan)
+}
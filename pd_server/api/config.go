@@ -59,7 +59,10 @@ func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	h.svr.SetScheduleConfig(config.Schedule)
+	if err := h.svr.SetScheduleConfig(config.Schedule); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	h.svr.SetReplicationConfig(config.Replication)
 	h.rd.JSON(w, http.StatusOK, nil)
 }
@@ -76,7 +79,10 @@ func (h *confHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.svr.SetScheduleConfig(*config)
+	if err := h.svr.SetScheduleConfig(*config); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
@@ -126,7 +132,10 @@ func (h *confHandler) SetNamespace(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.svr.SetNamespaceConfig(name, *config)
+	if err := h.svr.SetNamespaceConfig(name, *config); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
@@ -17,6 +17,7 @@ import (
 	"net/http"
 
 	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/core"
 	"github.com/unrolled/render"
 )
 
@@ -28,6 +29,32 @@ type hotStatusHandler struct {
 type hotStoreStats struct {
 	WriteStats map[uint64]uint64 `json:"write,omitempty"`
 	ReadStats  map[uint64]uint64 `json:"read,omitempty"`
+	// The hot flow of stores normalized by the store weights, which is what
+	// the hot region scheduler balances.
+	NormalizedWriteAsLeader map[uint64]*normalizedHotStat `json:"normalized_write_as_leader,omitempty"`
+	NormalizedWriteAsPeer   map[uint64]*normalizedHotStat `json:"normalized_write_as_peer,omitempty"`
+	NormalizedReadAsLeader  map[uint64]*normalizedHotStat `json:"normalized_read_as_leader,omitempty"`
+}
+
+type normalizedHotStat struct {
+	Weight       float64 `json:"weight"`
+	FlowBytes    float64 `json:"flow_bytes"`
+	RegionsCount float64 `json:"regions_count"`
+}
+
+func newNormalizedHotStats(stats core.StoreHotRegionsStat) map[uint64]*normalizedHotStat {
+	if len(stats) == 0 {
+		return nil
+	}
+	res := make(map[uint64]*normalizedHotStat, len(stats))
+	for id, stat := range stats {
+		res[id] = &normalizedHotStat{
+			Weight:       stat.Weight,
+			FlowBytes:    stat.NormalizedFlowBytes,
+			RegionsCount: stat.NormalizedRegionsCount,
+		}
+	}
+	return res
 }
 
 func newHotStatusHandler(handler *server.Handler, rd *render.Render) *hotStatusHandler {
@@ -52,5 +79,12 @@ func (h *hotStatusHandler) GetHotStores(w http.ResponseWriter, r *http.Request)
 		WriteStats: writeStats,
 		ReadStats:  readStats,
 	}
+	if infos := h.Handler.GetHotWriteRegions(); infos != nil {
+		stats.NormalizedWriteAsLeader = newNormalizedHotStats(infos.AsLeader)
+		stats.NormalizedWriteAsPeer = newNormalizedHotStats(infos.AsPeer)
+	}
+	if infos := h.Handler.GetHotReadRegions(); infos != nil {
+		stats.NormalizedReadAsLeader = newNormalizedHotStats(infos.AsLeader)
+	}
 	h.rd.JSON(w, http.StatusOK, stats)
 }
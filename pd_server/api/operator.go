@@ -15,19 +15,33 @@ package api
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/juju/errors"
 	"github.com/pingcap/pd/server"
 	"github.com/pingcap/pd/server/schedule"
 	"github.com/unrolled/render"
 )
 
+// defaultOperatorRecordLimit is the max number of the records returned by an
+// operator history query without a limit.
+const defaultOperatorRecordLimit = 1000
+
 type operatorHandler struct {
 	*server.Handler
 	r *render.Render
 }
 
+// operatorStatus is an operator with its status changes and the reasons.
+type operatorStatus struct {
+	Operator    *schedule.Operator            `json:"operator"`
+	Status      schedule.OperatorStatus       `json:"status"`
+	Transitions []schedule.OperatorTransition `json:"transitions"`
+}
+
 func newOperatorHandler(handler *server.Handler, r *render.Render) *operatorHandler {
 	return &operatorHandler{
 		Handler: handler,
@@ -50,7 +64,11 @@ func (h *operatorHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.r.JSON(w, http.StatusOK, op)
+	h.r.JSON(w, http.StatusOK, &operatorStatus{
+		Operator:    op,
+		Status:      op.Status(),
+		Transitions: op.Transitions(),
+	})
 }
 
 func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -88,6 +106,65 @@ func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, results)
 }
 
+func (h *operatorHandler) History(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOperatorRecordFilter(r.URL.Query())
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, err := h.GetOperatorRecords(filter)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, records)
+}
+
+// parseOperatorRecordFilter parses the region_id, store_id, kind, the start
+// and end unix times, and the limit of the operator history query.
+func parseOperatorRecordFilter(query url.Values) (*server.OperatorRecordFilter, error) {
+	filter := &server.OperatorRecordFilter{Limit: defaultOperatorRecordLimit}
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if filter.Limit <= 0 {
+			return nil, errors.Errorf("invalid limit %v", v)
+		}
+	}
+	if v := query.Get("region_id"); v != "" {
+		if filter.RegionID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if v := query.Get("store_id"); v != "" {
+		if filter.StoreID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if v := query.Get("kind"); v != "" {
+		if filter.Kind, err = schedule.ParseOperatorKind(v); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for _, t := range []struct {
+		name string
+		time *time.Time
+	}{{"start", &filter.Start}, {"end", &filter.End}} {
+		if v := query.Get(t.name); v != "" {
+			sec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			*t.time = time.Unix(sec, 0)
+		}
+	}
+	return filter, nil
+}
+
 func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var input map[string]interface{}
 	if err := readJSON(r.Body, &input); err != nil {
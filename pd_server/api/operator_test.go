@@ -68,9 +68,14 @@ func (s *testOperatorSuite) TestAddRemovePeer(c *C) {
 	c.Assert(err, IsNil)
 	operator = mustReadURL(c, regionURL)
 	c.Assert(strings.Contains(operator, "add peer 1 on store 3"), IsTrue)
+	c.Assert(strings.Contains(operator, `"status": "started"`), IsTrue)
 
 	err = doDelete(regionURL)
 	c.Assert(err, IsNil)
+	history := mustReadURL(c, fmt.Sprintf("%s/operators/history?region_id=1&kind=admin", s.urlPrefix))
+	c.Assert(strings.Contains(history, `"status": "canceled-by-admin"`), IsTrue)
+	history = mustReadURL(c, fmt.Sprintf("%s/operators/history?limit=1", s.urlPrefix))
+	c.Assert(strings.Count(history, `"seq"`), Equals, 1)
 
 	err = postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"remove-peer", "region_id": 1, "store_id": 2}`))
 	c.Assert(err, IsNil)
@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/server"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
 	"github.com/unrolled/render"
 )
 
@@ -111,6 +112,41 @@ func (h *regionHandler) GetRegionByKey(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, newRegionInfo(regionInfo))
 }
 
+// Scatter scatters the region in the mode specified by the "mode" query,
+// which is "random" by default.
+func (h *regionHandler) Scatter(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	regionIDStr := vars["id"]
+	regionID, err := strconv.ParseUint(regionIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	mode, err := schedule.ParseScatterMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+	if cluster.GetRegionInfoByID(regionID) == nil {
+		h.rd.JSON(w, http.StatusNotFound, server.ErrRegionNotFound(regionID).Error())
+		return
+	}
+
+	if err := h.svr.GetHandler().AddScatterRegionOperator(regionID, mode); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 type regionsHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -16,6 +16,7 @@ package api
 import (
 	"fmt"
 	"math/rand"
+	"net/http"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -125,3 +126,11 @@ func (s *testRegionSuite) TestTopN(c *C) {
 		}
 	}
 }
+
+func (s *testRegionSuite) TestScatterNotFound(c *C) {
+	url := fmt.Sprintf("%s/region/id/%d/scatter", s.urlPrefix, 1000)
+	resp, err := server.DialClient.Post(url, "application/json", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
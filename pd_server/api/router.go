@@ -35,6 +35,7 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	operatorHandler := newOperatorHandler(handler, rd)
 	router.HandleFunc("/api/v1/operators", operatorHandler.List).Methods("GET")
 	router.HandleFunc("/api/v1/operators", operatorHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/operators/history", operatorHandler.History).Methods("GET")
 	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Delete).Methods("DELETE")
 
@@ -42,6 +43,17 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.List).Methods("GET")
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.Post).Methods("POST")
 	router.HandleFunc("/api/v1/schedulers/{name}", schedulerHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/schedulers/{name}/config", schedulerHandler.GetConfig).Methods("GET")
+	router.HandleFunc("/api/v1/schedulers/{name}/config", schedulerHandler.SetConfig).Methods("POST")
+	router.HandleFunc("/api/v1/schedulers/{name}/pause", schedulerHandler.Pause).Methods("POST")
+	router.HandleFunc("/api/v1/schedulers/{name}/preview", schedulerHandler.Preview).Methods("GET")
+	router.HandleFunc("/api/v1/schedulers/{name}/trace", schedulerHandler.GetTrace).Methods("GET")
+	router.HandleFunc("/api/v1/schedulers/{name}/trace", schedulerHandler.SetTrace).Methods("POST")
+	router.HandleFunc("/api/v1/schedulers/{name}/resume", schedulerHandler.Resume).Methods("POST")
+
+	balanceHandler := newBalanceHandler(handler, rd)
+	router.HandleFunc("/api/v1/balance/plan", balanceHandler.GetPlan).Methods("GET")
+	router.HandleFunc("/api/v1/balance/plan", balanceHandler.PostPlan).Methods("POST")
 
 	router.Handle("/api/v1/cluster", newClusterHandler(svr, rd)).Methods("GET")
 	router.HandleFunc("/api/v1/cluster/status", newClusterHandler(svr, rd).GetClusterStatus).Methods("GET")
@@ -65,6 +77,11 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	router.HandleFunc("/api/v1/store/{id}/state", storeHandler.SetState).Methods("POST")
 	router.HandleFunc("/api/v1/store/{id}/label", storeHandler.SetLabels).Methods("POST")
 	router.HandleFunc("/api/v1/store/{id}/weight", storeHandler.SetWeight).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/weight", storeHandler.ClearWeight).Methods("DELETE")
+	router.HandleFunc("/api/v1/store/{id}/weight/ramp", storeHandler.CancelWeightRamp).Methods("DELETE")
+	router.HandleFunc("/api/v1/store/{id}/capacity-limit", storeHandler.SetCapacityLimit).Methods("POST")
+	router.HandleFunc("/api/v1/store/{id}/limit", storeHandler.GetLimit).Methods("GET")
+	router.HandleFunc("/api/v1/store/{id}/limit", storeHandler.SetLimit).Methods("POST")
 	router.Handle("/api/v1/stores", newStoresHandler(svr, rd)).Methods("GET")
 
 	labelsHandler := newLabelsHandler(svr, rd)
@@ -78,6 +95,7 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 
 	regionHandler := newRegionHandler(svr, rd)
 	router.HandleFunc("/api/v1/region/id/{id}", regionHandler.GetRegionByID).Methods("GET")
+	router.HandleFunc("/api/v1/region/id/{id}/scatter", regionHandler.Scatter).Methods("POST")
 	router.HandleFunc("/api/v1/region/key/{key}", regionHandler.GetRegionByKey).Methods("GET")
 
 	regionsHandler := newRegionsHandler(svr, rd)
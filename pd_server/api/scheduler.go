@@ -15,9 +15,13 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/schedule"
 	"github.com/unrolled/render"
 )
 
@@ -34,6 +38,15 @@ func newSchedulerHandler(handler *server.Handler, r *render.Render) *schedulerHa
 }
 
 func (h *schedulerHandler) List(w http.ResponseWriter, r *http.Request) {
+	if verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); verbose {
+		statuses, err := h.GetSchedulerStatuses()
+		if err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.r.JSON(w, http.StatusOK, statuses)
+		return
+	}
 	schedulers, err := h.GetSchedulers()
 	if err != nil {
 		h.r.JSON(w, http.StatusInternalServerError, err.Error())
@@ -55,29 +68,21 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var (
+		typ  string
+		args []string
+	)
 	switch name {
 	case "balance-leader-scheduler":
-		if err := h.AddBalanceLeaderScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		typ = "balance-leader"
 	case "balance-hot-region-scheduler":
-		if err := h.AddBalanceHotRegionScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		typ = "hot-region"
 	case "balance-region-scheduler":
-		if err := h.AddBalanceRegionScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		typ = "balance-region"
 	case "label-scheduler":
-		if err := h.AddLabelScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		typ = "label"
 	case "balance-adjacent-region-scheduler":
-		var args []string
+		typ = "adjacent-region"
 		leaderLimit, ok := input["leader_limit"].(string)
 		if ok {
 			args = append(args, leaderLimit)
@@ -88,50 +93,38 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 		} else {
 			args = args[:0]
 		}
-		if err := h.AddAdjacentRegionScheduler(args...); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-	case "grant-leader-scheduler":
+	case "grant-leader-scheduler", "evict-leader-scheduler":
+		typ = strings.TrimSuffix(name, "-scheduler")
 		storeID, ok := input["store_id"].(float64)
 		if !ok {
 			h.r.JSON(w, http.StatusBadRequest, "missing store id")
 			return
 		}
-		if err := h.AddGrantLeaderScheduler(uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-	case "evict-leader-scheduler":
-		storeID, ok := input["store_id"].(float64)
-		if !ok {
-			h.r.JSON(w, http.StatusBadRequest, "missing store id")
-			return
-		}
-		if err := h.AddEvictLeaderScheduler(uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		args = append(args, strconv.FormatUint(uint64(storeID), 10))
 	case "shuffle-leader-scheduler":
-		if err := h.AddShuffleLeaderScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		typ = "shuffle-leader"
 	case "shuffle-region-scheduler":
-		if err := h.AddShuffleRegionScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		typ = "shuffle-region"
 	case "random-merge-scheduler":
-		if err := h.AddRandomMergeScheduler(); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+		typ = "random-merge"
 	default:
 		h.r.JSON(w, http.StatusBadRequest, "unknown scheduler")
 		return
 	}
 
+	// A dry-run scheduler keeps its operators for preview instead of adding
+	// them.
+	var err error
+	if dryRun, _ := input["dry_run"].(bool); dryRun {
+		err = h.AddDryRunScheduler(typ, args...)
+	} else {
+		err = h.AddScheduler(typ, args...)
+	}
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	h.r.JSON(w, http.StatusOK, nil)
 }
 
@@ -145,3 +138,118 @@ func (h *schedulerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	h.r.JSON(w, http.StatusOK, nil)
 }
+
+func (h *schedulerHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	// The scheduler is paused until resumed if the delay is not given.
+	var delay int64
+	if delayStr := r.URL.Query().Get("delay"); delayStr != "" {
+		var err error
+		delay, err = strconv.ParseInt(delayStr, 10, 64)
+		if err != nil || delay <= 0 {
+			h.r.JSON(w, http.StatusBadRequest, "delay should be a positive number of seconds")
+			return
+		}
+	}
+
+	if err := h.PauseScheduler(name, time.Duration(delay)*time.Second); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+func (h *schedulerHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := h.ResumeScheduler(name); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+func (h *schedulerHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	config, err := h.GetSchedulerConfig(name)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, config)
+}
+
+func (h *schedulerHandler) SetConfig(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The scheduler may be renamed by the config, such as evict-leader.
+	newName, err := h.UpdateSchedulerConfig(name, input)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, newName)
+}
+
+func (h *schedulerHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	previews, err := h.GetSchedulerPreviews(name)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, previews)
+}
+
+type schedulerTrace struct {
+	Enabled bool                      `json:"enabled"`
+	Traces  []*schedule.ScheduleTrace `json:"traces"`
+}
+
+func (h *schedulerHandler) GetTrace(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	enabled, traces, err := h.GetSchedulerTraces(name)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, &schedulerTrace{Enabled: enabled, Traces: traces})
+}
+
+func (h *schedulerHandler) SetTrace(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	// The tracing is enabled if the enable flag is not given.
+	enable := true
+	if enableStr := r.URL.Query().Get("enable"); enableStr != "" {
+		var err error
+		enable, err = strconv.ParseBool(enableStr)
+		if err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := h.SetSchedulerTrace(name, enable); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
@@ -96,7 +96,112 @@ func (s *testScheduleSuite) testAddAndRemoveScheduler(name, createdName string,
 	c.Assert(err, IsNil)
 	c.Assert(sches[0], Equals, createdName)
 
+	pauseURL := fmt.Sprintf("%s/%s/pause", s.urlPrefix, createdName)
+	err = postJSON(pauseURL+"?delay=60", nil)
+	c.Assert(err, IsNil)
+	err = postJSON(pauseURL+"?delay=foo", nil)
+	c.Assert(err, NotNil)
+	err = postJSON(fmt.Sprintf("%s/%s/resume", s.urlPrefix, createdName), nil)
+	c.Assert(err, IsNil)
+
 	deleteURL := fmt.Sprintf("%s/%s", s.urlPrefix, createdName)
 	err = doDelete(deleteURL)
 	c.Assert(err, IsNil)
 }
+
+func (s *testScheduleSuite) TestConfig(c *C) {
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	body, err := json.Marshal(map[string]interface{}{"name": "evict-leader-scheduler", "store_id": 1})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+
+	configURL := fmt.Sprintf("%s/evict-leader-scheduler-1/config", s.urlPrefix)
+	config := make(map[string]interface{})
+	c.Assert(readJSONWithURL(configURL, &config), IsNil)
+	c.Assert(config["store_id"], Equals, 1.0)
+
+	// Invalid config.
+	c.Assert(postJSON(configURL, []byte(`{"store_id": "foo"}`)), NotNil)
+
+	// The scheduler is renamed with the store.
+	c.Assert(postJSON(configURL, []byte(`{"store_id": 2}`)), IsNil)
+	configURL = fmt.Sprintf("%s/evict-leader-scheduler-2/config", s.urlPrefix)
+	c.Assert(readJSONWithURL(configURL, &config), IsNil)
+	c.Assert(config["store_id"], Equals, 2.0)
+	c.Assert(doDelete(fmt.Sprintf("%s/evict-leader-scheduler-2", s.urlPrefix)), IsNil)
+
+	// The scheduler without config.
+	body, err = json.Marshal(map[string]interface{}{"name": "balance-region-scheduler"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+	c.Assert(readJSONWithURL(fmt.Sprintf("%s/balance-region-scheduler/config", s.urlPrefix), &config), NotNil)
+	c.Assert(doDelete(fmt.Sprintf("%s/balance-region-scheduler", s.urlPrefix)), IsNil)
+}
+
+func (s *testScheduleSuite) TestPreview(c *C) {
+	body, err := json.Marshal(map[string]interface{}{"name": "shuffle-leader-scheduler", "dry_run": true})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+
+	var previews []map[string]interface{}
+	previewURL := fmt.Sprintf("%s/shuffle-leader-scheduler/preview", s.urlPrefix)
+	c.Assert(readJSONWithURL(previewURL, &previews), IsNil)
+	c.Assert(doDelete(fmt.Sprintf("%s/shuffle-leader-scheduler", s.urlPrefix)), IsNil)
+
+	// The scheduler not in dry-run mode.
+	body, err = json.Marshal(map[string]interface{}{"name": "shuffle-leader-scheduler"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+	c.Assert(readJSONWithURL(previewURL, &previews), NotNil)
+	c.Assert(doDelete(fmt.Sprintf("%s/shuffle-leader-scheduler", s.urlPrefix)), IsNil)
+}
+
+func (s *testScheduleSuite) TestTrace(c *C) {
+	body, err := json.Marshal(map[string]interface{}{"name": "balance-leader-scheduler"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+
+	traceURL := fmt.Sprintf("%s/balance-leader-scheduler/trace", s.urlPrefix)
+	trace := make(map[string]interface{})
+	c.Assert(readJSONWithURL(traceURL, &trace), IsNil)
+	c.Assert(trace["enabled"], Equals, false)
+
+	c.Assert(postJSON(traceURL, nil), IsNil)
+	c.Assert(readJSONWithURL(traceURL, &trace), IsNil)
+	c.Assert(trace["enabled"], Equals, true)
+	c.Assert(postJSON(traceURL+"?enable=foo", nil), NotNil)
+	c.Assert(postJSON(traceURL+"?enable=false", nil), IsNil)
+	c.Assert(readJSONWithURL(traceURL, &trace), IsNil)
+	c.Assert(trace["enabled"], Equals, false)
+
+	c.Assert(doDelete(fmt.Sprintf("%s/balance-leader-scheduler", s.urlPrefix)), IsNil)
+}
+
+func (s *testScheduleSuite) TestListVerbose(c *C) {
+	body, err := json.Marshal(map[string]interface{}{"name": "balance-region-scheduler"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+
+	cfg := s.svr.GetScheduleConfig()
+	cfg.SchedulePolicies = server.SchedulePolicies{
+		{Name: "balance-region-scheduler", Windows: []string{"01:00-06:00"}, OutsideLimit: 1},
+	}
+	c.Assert(s.svr.SetScheduleConfig(*cfg), IsNil)
+
+	var statuses []*server.SchedulerStatus
+	c.Assert(readJSONWithURL(s.urlPrefix+"?verbose=true", &statuses), IsNil)
+	var status *server.SchedulerStatus
+	for _, st := range statuses {
+		if st.Name == "balance-region-scheduler" {
+			status = st
+		}
+	}
+	c.Assert(status, NotNil)
+	c.Assert(status.Policy, NotNil)
+	c.Assert(status.Policy.Windows, DeepEquals, []string{"01:00-06:00"})
+	c.Assert(status.Policy.OutsideLimit, Equals, uint64(1))
+
+	cfg.SchedulePolicies = nil
+	c.Assert(s.svr.SetScheduleConfig(*cfg), IsNil)
+	c.Assert(doDelete(fmt.Sprintf("%s/balance-region-scheduler", s.urlPrefix)), IsNil)
+}
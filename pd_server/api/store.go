@@ -36,23 +36,34 @@ type MetaStore struct {
 
 // StoreStatus contains status about a store.
 type StoreStatus struct {
-	Capacity           typeutil.ByteSize  `json:"capacity,omitempty"`
-	Available          typeutil.ByteSize  `json:"available,omitempty"`
-	LeaderCount        int                `json:"leader_count,omitempty"`
-	LeaderWeight       float64            `json:"leader_weight,omitempty"`
-	LeaderScore        float64            `json:"leader_score,omitempty"`
-	LeaderSize         int64              `json:"leader_size,omitempty"`
-	RegionCount        int                `json:"region_count,omitempty"`
-	RegionWeight       float64            `json:"region_weight,omitempty"`
-	RegionScore        float64            `json:"region_score,omitempty"`
-	RegionSize         int64              `json:"region_size,omitempty"`
-	SendingSnapCount   uint32             `json:"sending_snap_count,omitempty"`
-	ReceivingSnapCount uint32             `json:"receiving_snap_count,omitempty"`
-	ApplyingSnapCount  uint32             `json:"applying_snap_count,omitempty"`
-	IsBusy             bool               `json:"is_busy,omitempty"`
-	StartTS            *time.Time         `json:"start_ts,omitempty"`
-	LastHeartbeatTS    *time.Time         `json:"last_heartbeat_ts,omitempty"`
-	Uptime             *typeutil.Duration `json:"uptime,omitempty"`
+	Capacity            typeutil.ByteSize     `json:"capacity,omitempty"`
+	Available           typeutil.ByteSize     `json:"available,omitempty"`
+	LeaderCount         int                   `json:"leader_count,omitempty"`
+	LeaderWeight        float64               `json:"leader_weight,omitempty"`
+	LeaderWeightFactor  float64               `json:"leader_weight_factor,omitempty"`
+	LeaderWeightSource  core.WeightSource     `json:"leader_weight_source,omitempty"`
+	LeaderWeights       core.WeightCandidates `json:"leader_weights,omitempty"`
+	LeaderWeightRamp    *core.WeightRamp      `json:"leader_weight_ramp,omitempty"`
+	LeaderScore         float64               `json:"leader_score,omitempty"`
+	LeaderSize          int64                 `json:"leader_size,omitempty"`
+	RegionCount         int                   `json:"region_count,omitempty"`
+	RegionWeight        float64               `json:"region_weight,omitempty"`
+	RegionWeightSource  core.WeightSource     `json:"region_weight_source,omitempty"`
+	RegionWeights       core.WeightCandidates `json:"region_weights,omitempty"`
+	RegionWeightRamp    *core.WeightRamp      `json:"region_weight_ramp,omitempty"`
+	RegionScore         float64               `json:"region_score,omitempty"`
+	RegionSpaceFactor   float64               `json:"region_space_factor,omitempty"`
+	RegionSize          int64                 `json:"region_size,omitempty"`
+	RegionSizeSoftLimit int64                 `json:"region_size_soft_limit,omitempty"`
+	RegionSizeHardLimit int64                 `json:"region_size_hard_limit,omitempty"`
+	RegionWeightMode    string                `json:"region_weight_mode,omitempty"`
+	SendingSnapCount    uint32                `json:"sending_snap_count,omitempty"`
+	ReceivingSnapCount  uint32                `json:"receiving_snap_count,omitempty"`
+	ApplyingSnapCount   uint32                `json:"applying_snap_count,omitempty"`
+	IsBusy              bool                  `json:"is_busy,omitempty"`
+	StartTS             *time.Time            `json:"start_ts,omitempty"`
+	LastHeartbeatTS     *time.Time            `json:"last_heartbeat_ts,omitempty"`
+	Uptime              *typeutil.Duration    `json:"uptime,omitempty"`
 }
 
 // StoreInfo contains information about a store.
@@ -66,6 +77,16 @@ const (
 	downStateName    = "Down"
 )
 
+// weightCandidates returns the candidate weights of all sources, including
+// the default weight.
+func weightCandidates(candidates core.WeightCandidates) core.WeightCandidates {
+	res := core.WeightCandidates{core.WeightSourceDefault: core.DefaultWeight}
+	for source, weight := range candidates {
+		res[source] = weight
+	}
+	return res
+}
+
 func newStoreInfo(store *core.StoreInfo, maxStoreDownTime time.Duration) *StoreInfo {
 	s := &StoreInfo{
 		Store: &MetaStore{
@@ -73,20 +94,31 @@ func newStoreInfo(store *core.StoreInfo, maxStoreDownTime time.Duration) *StoreI
 			StateName: store.State.String(),
 		},
 		Status: &StoreStatus{
-			Capacity:           typeutil.ByteSize(store.Stats.GetCapacity()),
-			Available:          typeutil.ByteSize(store.Stats.GetAvailable()),
-			LeaderCount:        store.LeaderCount,
-			LeaderWeight:       store.LeaderWeight,
-			LeaderScore:        store.LeaderScore(),
-			LeaderSize:         store.LeaderSize,
-			RegionCount:        store.RegionCount,
-			RegionWeight:       store.RegionWeight,
-			RegionScore:        store.RegionScore(),
-			RegionSize:         store.RegionSize,
-			SendingSnapCount:   store.Stats.GetSendingSnapCount(),
-			ReceivingSnapCount: store.Stats.GetReceivingSnapCount(),
-			ApplyingSnapCount:  store.Stats.GetApplyingSnapCount(),
-			IsBusy:             store.Stats.GetIsBusy(),
+			Capacity:            typeutil.ByteSize(store.Stats.GetCapacity()),
+			Available:           typeutil.ByteSize(store.Stats.GetAvailable()),
+			LeaderCount:         store.LeaderCount,
+			LeaderWeight:        store.LeaderWeight,
+			LeaderWeightFactor:  store.AdaptiveLeaderFactor,
+			LeaderWeightSource:  store.LeaderWeightSource,
+			LeaderWeights:       weightCandidates(store.LeaderWeights),
+			LeaderWeightRamp:    store.LeaderWeightRamp,
+			LeaderScore:         store.LeaderScore(),
+			LeaderSize:          store.LeaderSize,
+			RegionCount:         store.RegionCount,
+			RegionWeight:        store.RegionWeight,
+			RegionWeightSource:  store.RegionWeightSource,
+			RegionWeights:       weightCandidates(store.RegionWeights),
+			RegionWeightRamp:    store.RegionWeightRamp,
+			RegionScore:         store.RegionScore(),
+			RegionSpaceFactor:   store.SpaceFactor,
+			RegionSize:          store.RegionSize,
+			RegionSizeSoftLimit: store.RegionSizeSoftLimit,
+			RegionSizeHardLimit: store.RegionSizeHardLimit,
+			RegionWeightMode:    store.RegionWeightMode,
+			SendingSnapCount:    store.Stats.GetSendingSnapCount(),
+			ReceivingSnapCount:  store.Stats.GetReceivingSnapCount(),
+			ApplyingSnapCount:   store.Stats.GetApplyingSnapCount(),
+			IsBusy:              store.Stats.GetIsBusy(),
 		},
 	}
 
@@ -297,7 +329,211 @@ func (h *storeHandler) SetWeight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := cluster.SetStoreWeight(storeID, leader, region); err != nil {
+	rampDuration := h.svr.GetScheduleConfig().WeightRampDuration.Duration
+	if rampVal, ok := input["ramp-duration"]; ok {
+		ramp, ok := rampVal.(string)
+		if !ok {
+			h.rd.JSON(w, http.StatusBadRequest, "badformat ramp duration")
+			return
+		}
+		rampDuration, err = time.ParseDuration(ramp)
+		if err != nil || rampDuration < 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "badformat ramp duration")
+			return
+		}
+	}
+
+	if err := cluster.SetStoreWeight(storeID, leader, region, rampDuration); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *storeHandler) ClearWeight(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := cluster.ClearStoreWeight(storeID); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *storeHandler) CancelWeightRamp(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := cluster.CancelStoreWeightRamp(storeID); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *storeHandler) SetCapacityLimit(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	softVal, ok := input["soft"]
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "soft limit unset")
+		return
+	}
+	hardVal, ok := input["hard"]
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "hard limit unset")
+		return
+	}
+	soft, ok := softVal.(float64)
+	if !ok || soft < 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "badformat soft limit")
+		return
+	}
+	hard, ok := hardVal.(float64)
+	if !ok || hard < 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "badformat hard limit")
+		return
+	}
+	if soft > 0 && hard > 0 && soft > hard {
+		h.rd.JSON(w, http.StatusBadRequest, "soft limit is greater than hard limit")
+		return
+	}
+
+	if err := cluster.SetStoreRegionSizeLimit(storeID, int64(soft), int64(hard)); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// storeLimit is the rate per minute and the tokens left of a store limit.
+type storeLimit struct {
+	Rate      float64 `json:"rate"`
+	Available float64 `json:"available"`
+}
+
+func (h *storeHandler) GetLimit(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	store, err := cluster.GetStore(storeID)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	limits := make(map[string]*storeLimit)
+	for _, typ := range []core.StoreLimitType{core.StoreAddPeer, core.StoreRemovePeer} {
+		if limit := store.GetStoreLimit(typ); limit != nil {
+			limits[typ.String()] = &storeLimit{Rate: limit.Rate(), Available: limit.Available()}
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, limits)
+}
+
+func (h *storeHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, server.ErrNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	store, err := cluster.GetStore(storeID)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// The rates not in the input are kept, and zero resets to the default.
+	rates := map[core.StoreLimitType]float64{
+		core.StoreAddPeer:    store.AddPeerRate,
+		core.StoreRemovePeer: store.RemovePeerRate,
+	}
+	for name, val := range input {
+		typ, err := core.ParseStoreLimitType(name)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		rate, ok := val.(float64)
+		if !ok || rate < 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "badformat "+name+" rate")
+			return
+		}
+		rates[typ] = rate
+	}
+
+	if err := cluster.SetStoreLimit(storeID, rates[core.StoreAddPeer], rates[core.StoreRemovePeer]); err != nil {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
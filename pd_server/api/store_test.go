@@ -220,6 +220,33 @@ func (s *testStoreSuite) TestStoreSetState(c *C) {
 	c.Assert(info.Store.State, Equals, metapb.StoreState_Up)
 }
 
+func (s *testStoreSuite) TestStoreLimit(c *C) {
+	url := fmt.Sprintf("%s/store/1/limit", s.urlPrefix)
+	limits := make(map[string]*storeLimit)
+	err := readJSONWithURL(url, &limits)
+	c.Assert(err, IsNil)
+	c.Assert(limits, HasLen, 2)
+
+	err = postJSON(url, []byte(`{"add-peer": 5}`))
+	c.Assert(err, IsNil)
+	err = readJSONWithURL(url, &limits)
+	c.Assert(err, IsNil)
+	c.Assert(limits["add-peer"].Rate, Equals, 5.0)
+
+	// Invalid input.
+	err = postJSON(url, []byte(`{"add-peer": -1}`))
+	c.Assert(err, NotNil)
+	err = postJSON(url, []byte(`{"foo": 1}`))
+	c.Assert(err, NotNil)
+
+	// Reset to the default.
+	err = postJSON(url, []byte(`{"add-peer": 0}`))
+	c.Assert(err, IsNil)
+	err = readJSONWithURL(url, &limits)
+	c.Assert(err, IsNil)
+	c.Assert(limits["add-peer"].Rate, Equals, 15.0)
+}
+
 func (s *testStoreSuite) TestUrlStoreFilter(c *C) {
 	table := []struct {
 		u    string
@@ -0,0 +1,142 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sort"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
+)
+
+// PlanWeights are the hypothetical weights of a store in a balance plan. Nil
+// means using the effective weight of the store.
+type PlanWeights struct {
+	Leader *float64 `json:"leader,omitempty"`
+	Region *float64 `json:"region,omitempty"`
+}
+
+// StoreBalancePlan is the ideal leader and region size of a store under the
+// store weights. A deviation is the current size minus the ideal size in MB,
+// and a deviation ratio is the deviation divided by the ideal size.
+type StoreBalancePlan struct {
+	StoreID              uint64  `json:"store_id"`
+	Namespace            string  `json:"namespace"`
+	LeaderWeight         float64 `json:"leader_weight"`
+	LeaderSize           int64   `json:"leader_size"`
+	IdealLeaderSize      float64 `json:"ideal_leader_size"`
+	LeaderDeviation      float64 `json:"leader_deviation"`
+	LeaderDeviationRatio float64 `json:"leader_deviation_ratio"`
+	RegionWeight         float64 `json:"region_weight"`
+	RegionSize           int64   `json:"region_size"`
+	IdealRegionSize      float64 `json:"ideal_region_size"`
+	RegionDeviation      float64 `json:"region_deviation"`
+	RegionDeviationRatio float64 `json:"region_deviation_ratio"`
+}
+
+// BalancePlan is the ideal distribution of leaders and regions among the
+// stores of each namespace. LeaderMoveSize and RegionMoveSize are the
+// estimated size in MB to move for the cluster to converge.
+type BalancePlan struct {
+	Stores         []*StoreBalancePlan `json:"stores"`
+	LeaderMoveSize float64             `json:"leader_move_size"`
+	RegionMoveSize float64             `json:"region_move_size"`
+}
+
+// newBalancePlan computes the balance plan of the stores. The weights of a
+// store are replaced by the hypothetical weights if specified. Offline stores
+// are expected to be drained, so their ideal sizes are zero.
+func newBalancePlan(stores []*core.StoreInfo, classifier namespace.Classifier, weights map[uint64]*PlanWeights) (*BalancePlan, error) {
+	storeIDs := make(map[uint64]struct{}, len(stores))
+	for _, s := range stores {
+		storeIDs[s.GetId()] = struct{}{}
+	}
+	for id, w := range weights {
+		if _, ok := storeIDs[id]; !ok {
+			return nil, errors.Trace(core.ErrStoreNotFound(id))
+		}
+		if w == nil {
+			return nil, errors.Errorf("store %v has no weight", id)
+		}
+		if (w.Leader != nil && *w.Leader < 0) || (w.Region != nil && *w.Region < 0) {
+			return nil, errors.Errorf("store %v has negative weight", id)
+		}
+	}
+
+	plan := &BalancePlan{}
+	groups := make(map[string][]*StoreBalancePlan)
+	for _, s := range stores {
+		if s.IsTombstone() {
+			continue
+		}
+		s = s.Clone()
+		if w, ok := weights[s.GetId()]; ok && w.Leader != nil {
+			s.LeaderWeight = *w.Leader
+		}
+		if w, ok := weights[s.GetId()]; ok && w.Region != nil {
+			s.RegionWeight = *w.Region
+		}
+		p := &StoreBalancePlan{
+			StoreID:    s.GetId(),
+			Namespace:  classifier.GetStoreNamespace(s),
+			LeaderSize: s.LeaderSize,
+			RegionSize: s.RegionSize,
+		}
+		if s.IsUp() {
+			p.LeaderWeight = s.ResourceWeight(core.LeaderKind)
+			p.RegionWeight = s.ResourceWeight(core.RegionKind)
+		}
+		groups[p.Namespace] = append(groups[p.Namespace], p)
+		plan.Stores = append(plan.Stores, p)
+	}
+
+	for _, group := range groups {
+		var leaderSize, regionSize int64
+		var leaderWeight, regionWeight float64
+		for _, p := range group {
+			leaderSize += p.LeaderSize
+			regionSize += p.RegionSize
+			leaderWeight += p.LeaderWeight
+			regionWeight += p.RegionWeight
+		}
+		for _, p := range group {
+			if leaderWeight > 0 {
+				p.IdealLeaderSize = float64(leaderSize) * p.LeaderWeight / leaderWeight
+			}
+			if regionWeight > 0 {
+				p.IdealRegionSize = float64(regionSize) * p.RegionWeight / regionWeight
+			}
+			p.LeaderDeviation, p.LeaderDeviationRatio = deviation(p.LeaderSize, p.IdealLeaderSize)
+			p.RegionDeviation, p.RegionDeviationRatio = deviation(p.RegionSize, p.IdealRegionSize)
+			if p.LeaderDeviation > 0 {
+				plan.LeaderMoveSize += p.LeaderDeviation
+			}
+			if p.RegionDeviation > 0 {
+				plan.RegionMoveSize += p.RegionDeviation
+			}
+		}
+	}
+
+	sort.Slice(plan.Stores, func(i, j int) bool { return plan.Stores[i].StoreID < plan.Stores[j].StoreID })
+	return plan, nil
+}
+
+func deviation(size int64, ideal float64) (float64, float64) {
+	dev := float64(size) - ideal
+	if ideal == 0 {
+		return dev, 0
+	}
+	return dev, dev / ideal
+}
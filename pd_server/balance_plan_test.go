@@ -0,0 +1,89 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
+)
+
+var _ = Suite(&testBalancePlanSuite{})
+
+type testBalancePlanSuite struct{}
+
+func newPlanStore(id uint64, leaderSize, regionSize int64, regionWeight float64) *core.StoreInfo {
+	store := core.NewStoreInfo(&metapb.Store{Id: id})
+	store.LeaderSize, store.RegionSize = leaderSize, regionSize
+	store.RegionWeight = regionWeight
+	return store
+}
+
+func (s *testBalancePlanSuite) TestBalancePlan(c *C) {
+	stores := []*core.StoreInfo{
+		newPlanStore(1, 100, 300, 1),
+		newPlanStore(2, 100, 300, 2),
+		newPlanStore(3, 100, 300, 3),
+	}
+	plan, err := newBalancePlan(stores, newMapClassifer(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Stores, HasLen, 3)
+	for i, p := range plan.Stores {
+		c.Assert(p.StoreID, Equals, uint64(i+1))
+		c.Assert(p.IdealLeaderSize, Equals, 100.0)
+		c.Assert(p.LeaderDeviation, Equals, 0.0)
+		c.Assert(p.IdealRegionSize, Equals, 150.0*float64(i+1))
+	}
+	c.Assert(plan.Stores[0].RegionDeviation, Equals, 150.0)
+	c.Assert(plan.Stores[0].RegionDeviationRatio, Equals, 1.0)
+	c.Assert(plan.Stores[2].RegionDeviation, Equals, -150.0)
+	c.Assert(plan.LeaderMoveSize, Equals, 0.0)
+	c.Assert(plan.RegionMoveSize, Equals, 150.0)
+
+	// What if store 1 has the leader weight of 4.
+	leaderWeight := 4.0
+	plan, err = newBalancePlan(stores, newMapClassifer(), map[uint64]*PlanWeights{1: {Leader: &leaderWeight}})
+	c.Assert(err, IsNil)
+	c.Assert(plan.Stores[0].IdealLeaderSize, Equals, 200.0)
+	c.Assert(plan.Stores[0].RegionWeight, Equals, 1.0)
+	c.Assert(plan.LeaderMoveSize, Equals, 100.0)
+	// The stores are not changed.
+	c.Assert(stores[0].LeaderWeight, Equals, 1.0)
+
+	// Offline stores are drained.
+	stores[2].State = metapb.StoreState_Offline
+	plan, err = newBalancePlan(stores, newMapClassifer(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Stores[2].IdealRegionSize, Equals, 0.0)
+	c.Assert(plan.RegionMoveSize, Equals, 300.0)
+
+	// Stores are balanced within their namespaces.
+	stores[2].State = metapb.StoreState_Up
+	classifier := newMapClassifer()
+	classifier.setStore(3, "ns1")
+	plan, err = newBalancePlan(stores, classifier, nil)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Stores[0].IdealRegionSize, Equals, 200.0)
+	c.Assert(plan.Stores[2].IdealRegionSize, Equals, 300.0)
+	c.Assert(plan.Stores[2].Namespace, Equals, "ns1")
+
+	// Invalid weights.
+	_, err = newBalancePlan(stores, classifier, map[uint64]*PlanWeights{4: {Leader: &leaderWeight}})
+	c.Assert(err, NotNil)
+	leaderWeight = -1
+	_, err = newBalancePlan(stores, classifier, map[uint64]*PlanWeights{1: {Leader: &leaderWeight}})
+	c.Assert(err, NotNil)
+	_, err = newBalancePlan(stores, classifier, map[uint64]*PlanWeights{1: nil})
+	c.Assert(err, NotNil)
+}
@@ -38,16 +38,22 @@ type clusterInfo struct {
 	opt             *scheduleOption
 	regionStats     *regionStatistics
 	labelLevelStats *labelLevelStatistics
+	capacityWeights *capacityWeighter
+	leaderWeights   *leaderWeightController
 }
 
 func newClusterInfo(id core.IDAllocator, opt *scheduleOption, kv *core.KV) *clusterInfo {
-	return &clusterInfo{
+	c := &clusterInfo{
 		BasicCluster:    schedule.NewBasicCluster(),
 		id:              id,
 		opt:             opt,
 		kv:              kv,
 		labelLevelStats: newLabelLevelStatistics(),
 	}
+	if err := c.setWeightPolicy(opt.GetWeightPolicy()); err != nil {
+		log.Warnf("failed to set weight policy: %v", err)
+	}
+	return c
 }
 
 // Return nil if cluster is not bootstrapped.
@@ -68,6 +74,10 @@ func loadClusterInfo(id core.IDAllocator, kv *core.KV, opt *scheduleOption) (*cl
 		return nil, errors.Trace(err)
 	}
 	log.Infof("load %v stores cost %v", c.Stores.GetStoreCount(), time.Since(start))
+	for _, store := range c.Stores.GetStores() {
+		c.attachStoreLimits(store)
+		c.Stores.SetStore(store)
+	}
 
 	start = time.Now()
 	if err := kv.LoadRegions(c.Regions, kvRangeLimit); err != nil {
@@ -143,7 +153,67 @@ func (c *clusterInfo) putStoreLocked(store *core.StoreInfo) error {
 			return errors.Trace(err)
 		}
 	}
-	return c.BasicCluster.PutStore(store)
+	c.attachStoreLimits(store)
+	if err := c.BasicCluster.PutStore(store); err != nil {
+		return errors.Trace(err)
+	}
+	c.saveWeightRampsLocked()
+	return nil
+}
+
+// attachStoreLimits sets the token buckets of the store with the rates
+// overridden by the store or the default rates.
+func (c *clusterInfo) attachStoreLimits(store *core.StoreInfo) {
+	for _, typ := range []core.StoreLimitType{core.StoreAddPeer, core.StoreRemovePeer} {
+		rate := store.GetStoreLimitRate(typ)
+		if rate <= 0 {
+			rate = c.opt.GetStoreLimitRate(typ)
+		}
+		if limit := store.GetStoreLimit(typ); limit == nil {
+			store.SetStoreLimit(typ, core.NewStoreLimit(rate))
+		} else if limit.Rate() != rate {
+			limit.SetRate(rate)
+		}
+	}
+}
+
+// saveWeightRampsLocked persists the weight ramps started or stopped since
+// the last call.
+func (c *clusterInfo) saveWeightRampsLocked() {
+	for _, s := range c.Stores.TakeWeightRampUpdates() {
+		if c.kv == nil {
+			continue
+		}
+		if err := c.kv.SaveStoreWeightRamp(s.GetId(), core.LeaderKind, s.LeaderWeightRamp); err != nil {
+			log.Warnf("[store %d] failed to save leader weight ramp: %v", s.GetId(), err)
+		}
+		if err := c.kv.SaveStoreWeightRamp(s.GetId(), core.RegionKind, s.RegionWeightRamp); err != nil {
+			log.Warnf("[store %d] failed to save region weight ramp: %v", s.GetId(), err)
+		}
+	}
+}
+
+// setWeightRampDuration changes the duration for the changed store weights
+// to reach their new values.
+func (c *clusterInfo) setWeightRampDuration(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.Stores.SetWeightRampDuration(d)
+}
+
+// setWeightPolicy changes the policy used to derive the store weights.
+func (c *clusterInfo) setWeightPolicy(name string) error {
+	policy, err := core.NewWeightPolicy(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.Stores.GetWeightPolicy().GetName() != name {
+		c.Stores.SetWeightPolicy(policy)
+		c.saveWeightRampsLocked()
+	}
+	return nil
 }
 
 // BlockStore stops balancer from selecting the store.
@@ -393,16 +463,33 @@ func (c *clusterInfo) handleStoreHeartbeat(stats *pdpb.StoreStats) error {
 	}
 	store.Stats = proto.Clone(stats).(*pdpb.StoreStats)
 	store.LastHeartbeatTS = time.Now()
-	
-	// wyy add. max region size is 8G=8192M
-	if store.RegionSize >= 8192 {
-		store.Stats.Available = 0
+	store.SpaceFactor = store.ComputeSpaceFactor(c.opt.GetSpaceRatios())
+	c.attachStoreLimits(store)
+	if c.capacityWeights != nil || c.leaderWeights != nil {
+		stores := c.Stores.GetStores()
+		if c.capacityWeights != nil {
+			c.capacityWeights.update(store, stores)
+		}
+		if c.leaderWeights != nil {
+			c.leaderWeights.update(store, stores)
+		}
 	}
 
 	c.Stores.SetStore(store)
+	c.saveWeightRampsLocked()
 	return nil
 }
 
+// resetStoreLeaderWeight drops the adaptive leader weight states of a store
+// which is removed or buried, as it may stop heartbeating.
+func (c *clusterInfo) resetStoreLeaderWeight(storeID uint64) {
+	c.Lock()
+	defer c.Unlock()
+	if c.leaderWeights != nil {
+		c.leaderWeights.reset(storeID)
+	}
+}
+
 func (c *clusterInfo) updateStoreStatus(id uint64) {
 	// wyy add.
 	for _, s := range c.Stores.GetStores() {
@@ -574,6 +661,22 @@ func (c *clusterInfo) GetTolerantSizeRatio() float64 {
 	return c.opt.GetTolerantSizeRatio()
 }
 
+func (c *clusterInfo) GetReplicaWeightTolerance() float64 {
+	return c.opt.GetReplicaWeightTolerance()
+}
+
+func (c *clusterInfo) GetBalanceCandidateCount() int {
+	return c.opt.GetBalanceCandidateCount()
+}
+
+func (c *clusterInfo) GetBalanceBatchSize() uint64 {
+	return c.opt.GetBalanceBatchSize()
+}
+
+func (c *clusterInfo) GetStoreBalanceLimit() uint64 {
+	return c.opt.GetStoreBalanceLimit()
+}
+
 func (c *clusterInfo) GetMaxSnapshotCount() uint64 {
 	return c.opt.GetMaxSnapshotCount()
 }
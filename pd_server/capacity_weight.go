@@ -0,0 +1,76 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/namespace"
+	log "github.com/sirupsen/logrus"
+)
+
+// capacityWeighter derives the region weights of stores from their capacity
+// for the namespaces in capacity region weight mode.
+type capacityWeighter struct {
+	opt        *scheduleOption
+	classifier namespace.Classifier
+}
+
+func newCapacityWeighter(opt *scheduleOption, classifier namespace.Classifier) *capacityWeighter {
+	return &capacityWeighter{
+		opt:        opt,
+		classifier: classifier,
+	}
+}
+
+// update moves the policy region weight of the store toward the ratio of its
+// capacity to the smallest capacity of the stores in the same namespace. It
+// only takes effect if the store's region weight is not set by API or label.
+func (w *capacityWeighter) update(store *core.StoreInfo, stores []*core.StoreInfo) {
+	ns := w.classifier.GetStoreNamespace(store)
+	store.RegionWeightMode = w.opt.GetRegionWeightMode(ns)
+	if store.RegionWeightMode != capacityRegionWeightMode {
+		store.ClearWeight(core.RegionKind, core.WeightSourcePolicy)
+		return
+	}
+	capacity := store.Stats.GetCapacity()
+	if capacity == 0 {
+		return
+	}
+
+	minCapacity := capacity
+	for _, s := range stores {
+		if s.GetId() == store.GetId() || s.IsTombstone() || w.classifier.GetStoreNamespace(s) != ns {
+			continue
+		}
+		if c := s.Stats.GetCapacity(); c > 0 && c < minCapacity {
+			minCapacity = c
+		}
+	}
+
+	target := float64(capacity) / float64(minCapacity)
+	weight, ok := store.RegionWeights[core.WeightSourcePolicy]
+	if !ok {
+		weight = core.DefaultWeight
+	}
+	if weight > 0 && math.Abs(target-weight)/weight < w.opt.GetCapacityWeightMinChange() {
+		return
+	}
+	if weight > 0 {
+		target = weight + (target-weight)*w.opt.GetCapacityWeightSmoothing()
+	}
+	log.Debugf("[store %d] region weight is adjusted from %v to %v by capacity", store.GetId(), weight, target)
+	store.SetWeight(core.RegionKind, core.WeightSourcePolicy, target)
+}
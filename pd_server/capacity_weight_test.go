@@ -0,0 +1,82 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server/core"
+)
+
+var _ = Suite(&testCapacityWeightSuite{})
+
+type testCapacityWeightSuite struct{}
+
+func (s *testCapacityWeightSuite) heartbeat(c *C, tc *testClusterInfo, storeID uint64, capacity uint64) {
+	c.Assert(tc.handleStoreHeartbeat(&pdpb.StoreStats{StoreId: storeID, Capacity: capacity}), IsNil)
+}
+
+func (s *testCapacityWeightSuite) TestCapacityWeight(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	cfg.CapacityWeightSmoothing = 0.5
+	cfg.CapacityWeightMinChange = 0.1
+	classifier := newMapClassifer()
+	tc := newTestClusterInfo(opt)
+	tc.capacityWeights = newCapacityWeighter(opt, classifier)
+
+	tc.addRegionStore(1, 0)
+	tc.addRegionStore(2, 0)
+	tc.addRegionStore(3, 0)
+	classifier.setStore(3, "ns1")
+
+	// Manual mode keeps the weights.
+	s.heartbeat(c, tc, 2, 4096)
+	c.Assert(tc.GetStore(2).RegionWeight, Equals, 1.0)
+	c.Assert(tc.GetStore(2).RegionWeightMode, Equals, manualRegionWeightMode)
+
+	// The weight moves halfway to the capacity ratio on each heartbeat.
+	cfg.RegionWeightMode = capacityRegionWeightMode
+	s.heartbeat(c, tc, 2, 4096)
+	c.Assert(tc.GetStore(2).RegionWeight, Equals, 2.5)
+	c.Assert(tc.GetStore(2).RegionWeightMode, Equals, capacityRegionWeightMode)
+	s.heartbeat(c, tc, 2, 4096)
+	c.Assert(tc.GetStore(2).RegionWeight, Equals, 3.25)
+	s.heartbeat(c, tc, 2, 4096)
+	c.Assert(tc.GetStore(2).RegionWeight, Equals, 3.625)
+	s.heartbeat(c, tc, 2, 4096)
+	c.Assert(tc.GetStore(2).RegionWeight, Equals, 3.8125)
+	// Changes smaller than the threshold are ignored.
+	s.heartbeat(c, tc, 2, 4096)
+	c.Assert(tc.GetStore(2).RegionWeight, Equals, 3.8125)
+	c.Assert(tc.GetStore(2).RegionWeightSource, Equals, core.WeightSourcePolicy)
+
+	// The region weight label takes precedence over the capacity.
+	store := tc.GetStore(2)
+	store.Labels = append(store.Labels, &metapb.StoreLabel{Key: core.RegionWeightLabel, Value: "2"})
+	c.Assert(tc.putStore(store), IsNil)
+	s.heartbeat(c, tc, 2, 4096)
+	c.Assert(tc.GetStore(2).RegionWeight, Equals, 2.0)
+	c.Assert(tc.GetStore(2).RegionWeightSource, Equals, core.WeightSourceLabel)
+	c.Assert(tc.GetStore(2).RegionWeights[core.WeightSourcePolicy], Equals, 3.8125)
+
+	// Store 3 is the only store of ns1, which has its own mode.
+	c.Assert(tc.GetStore(3).RegionWeight, Equals, 1.0)
+	opt.ns["ns1"] = newNamespaceOption(&NamespaceConfig{RegionWeightMode: manualRegionWeightMode})
+	s.heartbeat(c, tc, 3, 8192)
+	c.Assert(tc.GetStore(3).RegionWeight, Equals, 1.0)
+	opt.ns["ns1"] = newNamespaceOption(&NamespaceConfig{RegionWeightMode: capacityRegionWeightMode})
+	s.heartbeat(c, tc, 3, 8192)
+	c.Assert(tc.GetStore(3).RegionWeight, Equals, 1.0)
+}
@@ -16,9 +16,9 @@ package server
 import (
 	"fmt"
 	"path"
+	"strconv" // wyy add
 	"sync"
 	"time"
-	"strconv"	// wyy add	
 	//"math"  // wyy add
 
 	"github.com/juju/errors"
@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/pd/pkg/logutil"
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/namespace"
+	"github.com/pingcap/pd/server/schedule"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -107,7 +108,13 @@ func (c *RaftCluster) start() error {
 	}
 	c.cachedCluster = cluster
 	c.coordinator = newCoordinator(c.cachedCluster, c.s.hbStreams, c.s.classifier)
+	if err = c.coordinator.loadOperatorHistory(); err != nil {
+		return errors.Trace(err)
+	}
 	c.cachedCluster.regionStats = newRegionStatistics(c.s.scheduleOpt, c.s.classifier)
+	c.cachedCluster.capacityWeights = newCapacityWeighter(c.s.scheduleOpt, c.s.classifier)
+	c.cachedCluster.leaderWeights = newLeaderWeightController(c.s.scheduleOpt)
+	c.cachedCluster.setWeightRampDuration(c.s.scheduleOpt.GetWeightRampDuration())
 	c.quit = make(chan struct{})
 
 	c.wg.Add(2)
@@ -314,48 +321,22 @@ func (c *RaftCluster) putStore(store *metapb.Store) error {
 		s.Address = store.Address
 		s.MergeLabels(store.Labels)
 	}
-	
-	// wyy add
-	LeaderWeight := s.GetLabelValue("LeaderWeight")
-	if LeaderWeight != "" {
-		s.LeaderWeight, _ = strconv.ParseFloat(LeaderWeight,32)
-	}
-	RegionWeight := s.GetLabelValue("RegionWeight")
-	if RegionWeight != "" {
-		s.RegionWeight, _ = strconv.ParseFloat(RegionWeight,32)
-	}	
-	
-	/*// wyy add
-	// balance leader weight : 1 + log(x/min)
-	min := 0.0
-	// find min exclude not LeaderWeight
-	for _, s1 := range cluster.GetStores() {
-		LeaderWeight := s1.GetLabelValue("LeaderWeight")
-		if LeaderWeight != "" {
-			s1.LeaderWeight, _ = strconv.ParseFloat(LeaderWeight,32)
-			if(min < 1) { min = s1.LeaderWeight }
-			if(min > s1.LeaderWeight) { min = s1.LeaderWeight }
+
+	if softLimit := s.GetLabelValue(core.RegionSizeSoftLimitLabel); softLimit != "" {
+		if v, err := strconv.ParseInt(softLimit, 10, 64); err != nil {
+			log.Warnf("invalid label %s=%q in store %v: %v", core.RegionSizeSoftLimitLabel, softLimit, s.GetId(), err)
 		} else {
-			s1.LeaderWeight = 1
-			continue
+			s.RegionSizeSoftLimit = v
 		}
 	}
-	if(min > s.LeaderWeight) { min = s.LeaderWeight } // s is put store
-	// balance
-	for _, s1 := range cluster.GetStores() {
-		LeaderWeight := s1.GetLabelValue("LeaderWeight")
-		if LeaderWeight != "" {
-			s1.LeaderWeight, _ = strconv.ParseFloat(LeaderWeight,32)
-			s1.LeaderWeight = 1 + math.Log10(s1.LeaderWeight/min)
-			log.Infof("id: %v, LeaderWeight: %v", s1.GetId(), s1.LeaderWeight)
+	if hardLimit := s.GetLabelValue(core.RegionSizeHardLimitLabel); hardLimit != "" {
+		if v, err := strconv.ParseInt(hardLimit, 10, 64); err != nil {
+			log.Warnf("invalid label %s=%q in store %v: %v", core.RegionSizeHardLimitLabel, hardLimit, s.GetId(), err)
 		} else {
-			s1.LeaderWeight = 1
-			continue
+			s.RegionSizeHardLimit = v
 		}
 	}
-	s.LeaderWeight = 1 + math.Log10(s.LeaderWeight/min)
-	log.Infof("id: %v, LeaderWeight: %v", s.GetId(), s.LeaderWeight)	
-	*/
+
 	// Check location labels.
 	for _, k := range c.cachedCluster.GetLocationLabels() {
 		if v := s.GetLabelValue(k); len(v) == 0 {
@@ -390,7 +371,11 @@ func (c *RaftCluster) RemoveStore(storeID uint64) error {
 
 	store.State = metapb.StoreState_Offline
 	log.Warnf("[store %d] store %s has been Offline", store.GetId(), store.GetAddress())
-	return cluster.putStore(store)
+	if err := cluster.putStore(store); err != nil {
+		return errors.Trace(err)
+	}
+	cluster.resetStoreLeaderWeight(storeID)
+	return nil
 }
 
 // BuryStore marks a store as tombstone in cluster.
@@ -422,7 +407,11 @@ func (c *RaftCluster) BuryStore(storeID uint64, force bool) error {
 
 	store.State = metapb.StoreState_Tombstone
 	log.Warnf("[store %d] store %s has been Tombstone", store.GetId(), store.GetAddress())
-	return cluster.putStore(store)
+	if err := cluster.putStore(store); err != nil {
+		return errors.Trace(err)
+	}
+	cluster.resetStoreLeaderWeight(storeID)
+	return nil
 }
 
 // SetStoreState sets up a store's state.
@@ -442,8 +431,9 @@ func (c *RaftCluster) SetStoreState(storeID uint64, state metapb.StoreState) err
 	return cluster.putStore(store)
 }
 
-// SetStoreWeight sets up a store's leader/region balance weight.
-func (c *RaftCluster) SetStoreWeight(storeID uint64, leader, region float64) error {
+// SetStoreWeight sets up a store's leader/region balance weight, which is
+// reached in rampDuration.
+func (c *RaftCluster) SetStoreWeight(storeID uint64, leader, region float64, rampDuration time.Duration) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -456,7 +446,81 @@ func (c *RaftCluster) SetStoreWeight(storeID uint64, leader, region float64) err
 		return errors.Trace(err)
 	}
 
-	store.LeaderWeight, store.RegionWeight = leader, region
+	store.SetWeight(core.LeaderKind, core.WeightSourceAPI, leader)
+	store.SetWeight(core.RegionKind, core.WeightSourceAPI, region)
+	store.ResolveWeights(rampDuration)
+	return c.cachedCluster.putStore(store)
+}
+
+// CancelStoreWeightRamp stops the weight ramps of a store, and sets its
+// weights to their targets at once.
+func (c *RaftCluster) CancelStoreWeightRamp(storeID uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(core.ErrStoreNotFound(storeID))
+	}
+
+	store.CancelWeightRamps()
+	return c.cachedCluster.putStore(store)
+}
+
+// ClearStoreWeight removes a store's leader/region balance weight set by
+// SetStoreWeight, so that the weight falls back to the other sources.
+func (c *RaftCluster) ClearStoreWeight(storeID uint64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(core.ErrStoreNotFound(storeID))
+	}
+
+	if err := c.s.kv.DeleteStoreWeight(storeID); err != nil {
+		return errors.Trace(err)
+	}
+
+	store.ClearWeight(core.LeaderKind, core.WeightSourceAPI)
+	store.ClearWeight(core.RegionKind, core.WeightSourceAPI)
+	return c.cachedCluster.putStore(store)
+}
+
+// SetStoreRegionSizeLimit sets up a store's soft and hard region size limit.
+func (c *RaftCluster) SetStoreRegionSizeLimit(storeID uint64, soft, hard int64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(core.ErrStoreNotFound(storeID))
+	}
+
+	if err := c.s.kv.SaveStoreRegionSizeLimit(storeID, soft, hard); err != nil {
+		return errors.Trace(err)
+	}
+
+	store.RegionSizeSoftLimit, store.RegionSizeHardLimit = soft, hard
+	return c.cachedCluster.putStore(store)
+}
+
+// SetStoreLimit sets the number of peers allowed to be added to and removed
+// from a store per minute. Zero means the default rate.
+func (c *RaftCluster) SetStoreLimit(storeID uint64, addPeer, removePeer float64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.cachedCluster.GetStore(storeID)
+	if store == nil {
+		return errors.Trace(core.ErrStoreNotFound(storeID))
+	}
+
+	if err := c.s.kv.SaveStoreLimit(storeID, addPeer, removePeer); err != nil {
+		return errors.Trace(err)
+	}
+
+	store.AddPeerRate, store.RemovePeerRate = addPeer, removePeer
 	return c.cachedCluster.putStore(store)
 }
 
@@ -484,16 +548,41 @@ func (c *RaftCluster) checkOperators() {
 		// the operator of merged region will not timeout actively
 		if c.cachedCluster.GetRegion(op.RegionID()) == nil {
 			log.Debugf("remove operator %v cause region %d is merged", op, op.RegionID)
-			co.removeOperator(op)
+			co.removeOperator(op, schedule.OperatorCanceledEpochChanged, "region is merged")
+			continue
+		}
+
+		if storeID, ok := c.hasDownTargetStore(op); ok {
+			log.Infof("[region %v] operator canceled for down store %d: %s", op.RegionID(), storeID, op)
+			operatorCounter.WithLabelValues(op.Desc(), "store_down").Inc()
+			co.removeOperator(op, schedule.OperatorCanceledStoreDown, fmt.Sprintf("target store %d is down", storeID))
 			continue
 		}
 
 		if op.IsTimeout() {
 			log.Infof("[region %v] operator timeout: %s", op.RegionID, op)
 			operatorCounter.WithLabelValues(op.Desc(), "timeout").Inc()
-			co.removeOperator(op)
+			co.removeOperator(op, schedule.OperatorTimeout, operatorTimeoutReason(op))
+		}
+	}
+}
+
+// hasDownTargetStore checks if the operator adds a peer or transfers the
+// leader to a store which is down or removed, and returns the store. A store
+// without any heartbeat since the leader starts is not treated as down, so
+// the resumed operators are not canceled.
+func (c *RaftCluster) hasDownTargetStore(op *schedule.Operator) (uint64, bool) {
+	cluster := c.cachedCluster
+	for _, storeID := range op.TargetStores() {
+		store := cluster.GetStore(storeID)
+		if store == nil || store.IsTombstone() {
+			return storeID, true
+		}
+		if !store.LastHeartbeatTS.IsZero() && store.DownTime() > cluster.GetMaxStoreDownTime() {
+			return storeID, true
 		}
 	}
+	return 0, false
 }
 
 func (c *RaftCluster) storeIsEmpty(storeID uint64) bool {
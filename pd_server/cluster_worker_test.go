@@ -579,7 +579,7 @@ func (s *testClusterWorkerSuite) TestHeartbeatSplitAddPeer(c *C) {
 	scheduleCfg := s.svr.GetScheduleConfig()
 	scheduleCfg.LeaderScheduleLimit = 0
 	scheduleCfg.RegionScheduleLimit = 0
-	s.svr.SetScheduleConfig(*scheduleCfg)
+	c.Assert(s.svr.SetScheduleConfig(*scheduleCfg), IsNil)
 
 	cluster := s.svr.GetRaftCluster()
 	c.Assert(cluster, NotNil)
@@ -28,6 +28,7 @@ import (
 	"github.com/pingcap/pd/pkg/logutil"
 	"github.com/pingcap/pd/pkg/metricutil"
 	"github.com/pingcap/pd/pkg/typeutil"
+	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/namespace"
 )
 
@@ -100,6 +101,13 @@ type Config struct {
 	// namespaces.
 	NamespaceClassifier string `toml:"namespace-classifier" json:"namespace-classifier"`
 
+	// RecordFile is the file to record the heartbeats and the splits handled
+	// by the leader, which can be replayed to reproduce the scheduling.
+	RecordFile string `toml:"record-file" json:"record-file"`
+	// RecordFileMaxSize is the max size in MB of the record file, beyond
+	// which the file is rotated to "<record-file>.1".
+	RecordFileMaxSize uint64 `toml:"record-file-max-size" json:"record-file-max-size"`
+
 	// Only test can change them.
 	nextRetryDelay             time.Duration
 	disableStrictReconfigCheck bool
@@ -133,6 +141,7 @@ func NewConfig() *Config {
 	fs.StringVar(&cfg.Log.File.Filename, "log-file", "", "log file path")
 	fs.BoolVar(&cfg.Log.File.LogRotate, "log-rotate", true, "rotate log")
 	fs.StringVar(&cfg.NamespaceClassifier, "namespace-classifier", "default", "namespace classifier (default 'default')")
+	fs.StringVar(&cfg.RecordFile, "record-file", "", "file to record the heartbeats and splits for replay")
 
 	fs.StringVar(&cfg.Security.CAPath, "cacert", "", "Path of file that contains list of trusted TLS CAs")
 	fs.StringVar(&cfg.Security.CertPath, "cert", "", "Path of file that contains X509 certificate in PEM format")
@@ -164,6 +173,8 @@ const (
 	defaultHeartbeatStreamRebindInterval = time.Minute
 
 	defaultLeaderPriorityCheckInterval = time.Minute
+
+	defaultRecordFileMaxSize = 1024
 )
 
 func adjustString(v *string, defValue string) {
@@ -300,9 +311,14 @@ func (c *Config) adjust() error {
 
 	adjustString(&c.NamespaceClassifier, "default")
 
+	adjustUint64(&c.RecordFileMaxSize, defaultRecordFileMaxSize)
+
 	adjustString(&c.Metric.PushJob, c.Name)
 
 	c.Schedule.adjust()
+	if err := c.Schedule.validate(); err != nil {
+		return errors.Trace(err)
+	}
 	c.Replication.adjust()
 
 	adjustDuration(&c.heartbeatStreamBindInterval, defaultHeartbeatStreamRebindInterval)
@@ -352,6 +368,64 @@ type ScheduleConfig struct {
 	MergeScheduleLimit uint64 `toml:"merge-schedule-limit,omitempty" json:"merge-schedule-limit"`
 	// TolerantSizeRatio is the ratio of buffer size for balance scheduler.
 	TolerantSizeRatio float64 `toml:"tolerant-size-ratio,omitempty" json:"tolerant-size-ratio"`
+	// WeightPolicy is the policy used to derive the store weights from
+	// the LeaderWeight and RegionWeight labels.
+	WeightPolicy string `toml:"weight-policy,omitempty" json:"weight-policy"`
+	// RegionWeightMode decides where the store region weights come from.
+	// "manual" uses the labels and API, "capacity" derives them from the
+	// store capacities.
+	RegionWeightMode string `toml:"region-weight-mode,omitempty" json:"region-weight-mode"`
+	// CapacityWeightSmoothing is the ratio a capacity-derived region weight
+	// moves toward its target on each store heartbeat.
+	CapacityWeightSmoothing float64 `toml:"capacity-weight-smoothing,omitempty" json:"capacity-weight-smoothing"`
+	// CapacityWeightMinChange is the min relative change of a
+	// capacity-derived region weight to be applied.
+	CapacityWeightMinChange float64 `toml:"capacity-weight-min-change,omitempty" json:"capacity-weight-min-change"`
+	// AdaptiveLeaderWeight enables adjusting the leader weights of stores
+	// by their observed read/write throughput.
+	AdaptiveLeaderWeight bool `toml:"adaptive-leader-weight,omitempty" json:"adaptive-leader-weight"`
+	// AdaptiveLeaderWeightWindow is the number of store heartbeats used to
+	// estimate the service capacity of a store.
+	AdaptiveLeaderWeightWindow uint64 `toml:"adaptive-leader-weight-window,omitempty" json:"adaptive-leader-weight-window"`
+	// AdaptiveLeaderWeightStep is the max relative change of the adaptive
+	// leader weight factor on each store heartbeat.
+	AdaptiveLeaderWeightStep float64 `toml:"adaptive-leader-weight-step,omitempty" json:"adaptive-leader-weight-step"`
+	// MinAdaptiveLeaderFactor and MaxAdaptiveLeaderFactor bound the factor
+	// multiplied to the leader weight.
+	MinAdaptiveLeaderFactor float64 `toml:"min-adaptive-leader-factor,omitempty" json:"min-adaptive-leader-factor"`
+	MaxAdaptiveLeaderFactor float64 `toml:"max-adaptive-leader-factor,omitempty" json:"max-adaptive-leader-factor"`
+	// WeightRampDuration is the duration for a changed store weight to reach
+	// its new value linearly. Zero means changing at once.
+	WeightRampDuration typeutil.Duration `toml:"weight-ramp-duration,omitempty" json:"weight-ramp-duration"`
+	// ReplicaWeightTolerance is the ratio a store's region score may exceed
+	// the weighted average before the replica checker moves its peers to an
+	// equally isolated store below the average.
+	ReplicaWeightTolerance float64 `toml:"replica-weight-tolerance,omitempty" json:"replica-weight-tolerance"`
+	// BalanceCandidateCount is the number of regions the balance schedulers
+	// sample from a store to pick the one reducing the imbalance most. Zero
+	// picks a random region.
+	BalanceCandidateCount uint64 `toml:"balance-candidate-count,omitempty" json:"balance-candidate-count"`
+	// BalanceBatchSize is the max number of operators a balance scheduler
+	// creates in a round, which is also limited by the schedule limits.
+	BalanceBatchSize uint64 `toml:"balance-batch-size,omitempty" json:"balance-batch-size"`
+	// StoreBalanceLimit is the max number of pending operators involving a
+	// store for a balance scheduler to add more on it.
+	StoreBalanceLimit uint64 `toml:"store-balance-limit,omitempty" json:"store-balance-limit"`
+	// HighSpaceRatio is the used space ratio of a store above which its
+	// region score rises, and SpaceHighWaterMark is the ratio above which
+	// the score rises steeply as the store is nearly full.
+	HighSpaceRatio     float64 `toml:"high-space-ratio,omitempty" json:"high-space-ratio"`
+	SpaceHighWaterMark float64 `toml:"space-high-water-mark,omitempty" json:"space-high-water-mark"`
+	// StoreAddPeerRate and StoreRemovePeerRate are the default number of
+	// peers allowed to be added to and removed from each store per minute.
+	StoreAddPeerRate    float64 `toml:"store-add-peer-rate,omitempty" json:"store-add-peer-rate"`
+	StoreRemovePeerRate float64 `toml:"store-remove-peer-rate,omitempty" json:"store-remove-peer-rate"`
+	// OperatorHistoryLimit is the max number of finished operators kept in
+	// the operator history.
+	OperatorHistoryLimit uint64 `toml:"operator-history-limit,omitempty" json:"operator-history-limit"`
+	// SchedulePolicies limit schedulers and checkers outside their allowed
+	// time windows.
+	SchedulePolicies SchedulePolicies `toml:"schedule-policies,omitempty" json:"schedule-policies"`
 	// Schedulers support for loding customized schedulers
 	Schedulers SchedulerConfigs `toml:"schedulers,omitempty" json:"schedulers-v2"` // json v2 is for the sake of compatible upgrade
 }
@@ -360,15 +434,35 @@ func (c *ScheduleConfig) clone() *ScheduleConfig {
 	schedulers := make(SchedulerConfigs, len(c.Schedulers))
 	copy(schedulers, c.Schedulers)
 	return &ScheduleConfig{
-		MaxSnapshotCount:     c.MaxSnapshotCount,
-		MaxStoreDownTime:     c.MaxStoreDownTime,
-		MaxMergeRegionSize:   c.MaxMergeRegionSize,
-		LeaderScheduleLimit:  c.LeaderScheduleLimit,
-		RegionScheduleLimit:  c.RegionScheduleLimit,
-		ReplicaScheduleLimit: c.ReplicaScheduleLimit,
-		MergeScheduleLimit:   c.MergeScheduleLimit,
-		TolerantSizeRatio:    c.TolerantSizeRatio,
-		Schedulers:           schedulers,
+		MaxSnapshotCount:           c.MaxSnapshotCount,
+		MaxStoreDownTime:           c.MaxStoreDownTime,
+		MaxMergeRegionSize:         c.MaxMergeRegionSize,
+		LeaderScheduleLimit:        c.LeaderScheduleLimit,
+		RegionScheduleLimit:        c.RegionScheduleLimit,
+		ReplicaScheduleLimit:       c.ReplicaScheduleLimit,
+		MergeScheduleLimit:         c.MergeScheduleLimit,
+		TolerantSizeRatio:          c.TolerantSizeRatio,
+		WeightPolicy:               c.WeightPolicy,
+		RegionWeightMode:           c.RegionWeightMode,
+		CapacityWeightSmoothing:    c.CapacityWeightSmoothing,
+		CapacityWeightMinChange:    c.CapacityWeightMinChange,
+		AdaptiveLeaderWeight:       c.AdaptiveLeaderWeight,
+		AdaptiveLeaderWeightWindow: c.AdaptiveLeaderWeightWindow,
+		AdaptiveLeaderWeightStep:   c.AdaptiveLeaderWeightStep,
+		MinAdaptiveLeaderFactor:    c.MinAdaptiveLeaderFactor,
+		MaxAdaptiveLeaderFactor:    c.MaxAdaptiveLeaderFactor,
+		WeightRampDuration:         c.WeightRampDuration,
+		ReplicaWeightTolerance:     c.ReplicaWeightTolerance,
+		BalanceCandidateCount:      c.BalanceCandidateCount,
+		BalanceBatchSize:           c.BalanceBatchSize,
+		StoreBalanceLimit:          c.StoreBalanceLimit,
+		HighSpaceRatio:             c.HighSpaceRatio,
+		SpaceHighWaterMark:         c.SpaceHighWaterMark,
+		StoreAddPeerRate:           c.StoreAddPeerRate,
+		StoreRemovePeerRate:        c.StoreRemovePeerRate,
+		OperatorHistoryLimit:       c.OperatorHistoryLimit,
+		SchedulePolicies:           c.SchedulePolicies.clone(),
+		Schedulers:                 schedulers,
 	}
 }
 
@@ -379,6 +473,13 @@ type SchedulerConfigs []SchedulerConfig
 type SchedulerConfig struct {
 	Type string   `toml:"type" json:"type"`
 	Args []string `toml:"args,omitempty" json:"args"`
+	// Paused stops the scheduler from scheduling until it is resumed, or
+	// until PausedUntil (unix seconds) if it is not 0.
+	Paused      bool  `toml:"paused,omitempty" json:"paused,omitempty"`
+	PausedUntil int64 `toml:"paused-until,omitempty" json:"paused-until,omitempty"`
+	// DryRun keeps the operators of the scheduler for preview instead of
+	// adding them.
+	DryRun bool `toml:"dry-run,omitempty" json:"dry-run,omitempty"`
 }
 
 const (
@@ -392,6 +493,28 @@ const (
 	defaultReplicaScheduleLimit = 32
 	defaultMergeScheduleLimit   = 20
 	defaultTolerantSizeRatio    = 2.5
+	defaultWeightPolicy         = core.RankExponentialWeightPolicy
+	defaultRegionWeightMode     = manualRegionWeightMode
+	defaultCapacityWeightSmooth = 0.5
+	defaultCapacityWeightChange = 0.05
+	defaultAdaptiveLeaderWindow = 30
+	defaultAdaptiveLeaderStep   = 0.02
+	defaultMinAdaptiveFactor    = 0.5
+	defaultMaxAdaptiveFactor    = 2
+	defaultReplicaWeightTol     = 0.5
+	defaultBalanceCandidates    = 0
+	defaultBalanceBatchSize     = 4
+	defaultStoreBalanceLimit    = 4
+	defaultHighSpaceRatio       = 0.6
+	defaultSpaceHighWaterMark   = 0.8
+	defaultStoreAddPeerRate     = 15
+	defaultStoreRemovePeerRate  = 15
+	defaultOperatorHistoryLimit = 10000
+)
+
+const (
+	manualRegionWeightMode   = "manual"
+	capacityRegionWeightMode = "capacity"
 )
 
 var defaultSchedulers = SchedulerConfigs{
@@ -411,9 +534,73 @@ func (c *ScheduleConfig) adjust() {
 	adjustUint64(&c.ReplicaScheduleLimit, defaultReplicaScheduleLimit)
 	adjustUint64(&c.MergeScheduleLimit, defaultMergeScheduleLimit)
 	adjustFloat64(&c.TolerantSizeRatio, defaultTolerantSizeRatio)
+	adjustString(&c.WeightPolicy, defaultWeightPolicy)
+	adjustString(&c.RegionWeightMode, defaultRegionWeightMode)
+	adjustFloat64(&c.CapacityWeightSmoothing, defaultCapacityWeightSmooth)
+	adjustFloat64(&c.CapacityWeightMinChange, defaultCapacityWeightChange)
+	adjustUint64(&c.AdaptiveLeaderWeightWindow, defaultAdaptiveLeaderWindow)
+	adjustFloat64(&c.AdaptiveLeaderWeightStep, defaultAdaptiveLeaderStep)
+	adjustFloat64(&c.MinAdaptiveLeaderFactor, defaultMinAdaptiveFactor)
+	adjustFloat64(&c.MaxAdaptiveLeaderFactor, defaultMaxAdaptiveFactor)
+	adjustFloat64(&c.ReplicaWeightTolerance, defaultReplicaWeightTol)
+	adjustUint64(&c.BalanceCandidateCount, defaultBalanceCandidates)
+	adjustUint64(&c.BalanceBatchSize, defaultBalanceBatchSize)
+	adjustUint64(&c.StoreBalanceLimit, defaultStoreBalanceLimit)
+	adjustFloat64(&c.HighSpaceRatio, defaultHighSpaceRatio)
+	adjustFloat64(&c.SpaceHighWaterMark, defaultSpaceHighWaterMark)
+	adjustFloat64(&c.StoreAddPeerRate, defaultStoreAddPeerRate)
+	adjustFloat64(&c.StoreRemovePeerRate, defaultStoreRemovePeerRate)
+	adjustUint64(&c.OperatorHistoryLimit, defaultOperatorHistoryLimit)
 	adjustSchedulers(&c.Schedulers, defaultSchedulers)
 }
 
+func (c *ScheduleConfig) validate() error {
+	if _, err := core.NewWeightPolicy(c.WeightPolicy); err != nil {
+		return errors.Trace(err)
+	}
+	if err := validateRegionWeightMode(c.RegionWeightMode); err != nil {
+		return errors.Trace(err)
+	}
+	if c.CapacityWeightSmoothing <= 0 || c.CapacityWeightSmoothing > 1 {
+		return errors.Errorf("capacity-weight-smoothing should be in (0, 1], but %v", c.CapacityWeightSmoothing)
+	}
+	if c.CapacityWeightMinChange < 0 {
+		return errors.Errorf("capacity-weight-min-change should not be negative, but %v", c.CapacityWeightMinChange)
+	}
+	if c.AdaptiveLeaderWeightWindow == 0 {
+		return errors.New("adaptive-leader-weight-window should be positive")
+	}
+	if c.AdaptiveLeaderWeightStep <= 0 || c.AdaptiveLeaderWeightStep >= 1 {
+		return errors.Errorf("adaptive-leader-weight-step should be in (0, 1), but %v", c.AdaptiveLeaderWeightStep)
+	}
+	if c.MinAdaptiveLeaderFactor <= 0 {
+		return errors.Errorf("min-adaptive-leader-factor should be positive, but %v", c.MinAdaptiveLeaderFactor)
+	}
+	if c.MinAdaptiveLeaderFactor > 1 || c.MaxAdaptiveLeaderFactor < 1 {
+		return errors.Errorf("adaptive leader factor bounds [%v, %v] should contain 1", c.MinAdaptiveLeaderFactor, c.MaxAdaptiveLeaderFactor)
+	}
+	if c.ReplicaWeightTolerance < 0 {
+		return errors.Errorf("replica-weight-tolerance should not be negative, but %v", c.ReplicaWeightTolerance)
+	}
+	if c.HighSpaceRatio <= 0 || c.HighSpaceRatio >= c.SpaceHighWaterMark || c.SpaceHighWaterMark >= 1 {
+		return errors.Errorf("high-space-ratio %v and space-high-water-mark %v should be in (0, 1) and increasing", c.HighSpaceRatio, c.SpaceHighWaterMark)
+	}
+	if c.StoreAddPeerRate <= 0 || c.StoreRemovePeerRate <= 0 {
+		return errors.Errorf("store-add-peer-rate %v and store-remove-peer-rate %v should be positive", c.StoreAddPeerRate, c.StoreRemovePeerRate)
+	}
+	if err := c.SchedulePolicies.validate(); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func validateRegionWeightMode(mode string) error {
+	if mode != manualRegionWeightMode && mode != capacityRegionWeightMode {
+		return errors.Errorf("unknown region weight mode %q", mode)
+	}
+	return nil
+}
+
 // ReplicationConfig is the replication configuration.
 type ReplicationConfig struct {
 	// MaxReplicas is the number of replicas for each region.
@@ -451,6 +638,8 @@ type NamespaceConfig struct {
 	MergeScheduleLimit uint64 `json:"merge-schedule-limit"`
 	// MaxReplicas is the number of replicas for each region.
 	MaxReplicas uint64 `json:"max-replicas"`
+	// RegionWeightMode decides where the store region weights come from.
+	RegionWeightMode string `json:"region-weight-mode"`
 }
 
 func (c *NamespaceConfig) clone() *NamespaceConfig {
@@ -460,6 +649,7 @@ func (c *NamespaceConfig) clone() *NamespaceConfig {
 		ReplicaScheduleLimit: c.ReplicaScheduleLimit,
 		MergeScheduleLimit:   c.MergeScheduleLimit,
 		MaxReplicas:          c.MaxReplicas,
+		RegionWeightMode:     c.RegionWeightMode,
 	}
 }
 
@@ -469,6 +659,7 @@ func (c *NamespaceConfig) adjust(opt *scheduleOption) {
 	adjustUint64(&c.ReplicaScheduleLimit, opt.GetReplicaScheduleLimit(namespace.DefaultNamespace))
 	adjustUint64(&c.MergeScheduleLimit, opt.GetMergeScheduleLimit(namespace.DefaultNamespace))
 	adjustUint64(&c.MaxReplicas, uint64(opt.GetMaxReplicas(namespace.DefaultNamespace)))
+	adjustString(&c.RegionWeightMode, opt.GetRegionWeightMode(namespace.DefaultNamespace))
 }
 
 // SecurityConfig is the configuration for supporting tls.
@@ -14,6 +14,8 @@
 package server
 
 import (
+	"time"
+
 	. "github.com/pingcap/check"
 )
 
@@ -33,3 +35,87 @@ func (s *testConfigSuite) TestBadFormatJoinAddr(c *C) {
 	cfg.Join = "127.0.0.1:2379" // Wrong join addr without scheme.
 	c.Assert(cfg.adjust(), NotNil)
 }
+
+func (s *testConfigSuite) TestWeightPolicy(c *C) {
+	cfg := NewTestSingleConfig()
+	c.Assert(cfg.adjust(), IsNil)
+	c.Assert(cfg.Schedule.WeightPolicy, Equals, defaultWeightPolicy)
+
+	cfg = NewTestSingleConfig()
+	cfg.Schedule.WeightPolicy = "unknown"
+	c.Assert(cfg.adjust(), NotNil)
+}
+
+func (s *testConfigSuite) TestRegionWeightMode(c *C) {
+	cfg := NewTestSingleConfig()
+	c.Assert(cfg.Schedule.RegionWeightMode, Equals, manualRegionWeightMode)
+
+	cfg.Schedule.RegionWeightMode = capacityRegionWeightMode
+	c.Assert(cfg.adjust(), IsNil)
+	cfg.Schedule.RegionWeightMode = "unknown"
+	c.Assert(cfg.adjust(), NotNil)
+
+	cfg = NewTestSingleConfig()
+	cfg.Schedule.CapacityWeightSmoothing = 2
+	c.Assert(cfg.adjust(), NotNil)
+}
+
+func (s *testConfigSuite) TestAdaptiveLeaderWeight(c *C) {
+	cfg := NewTestSingleConfig()
+	c.Assert(cfg.adjust(), IsNil)
+	c.Assert(cfg.Schedule.validate(), IsNil)
+
+	// The schedule config API validates the config without adjusting it.
+	sc := cfg.Schedule.clone()
+	sc.AdaptiveLeaderWeightWindow = 0
+	c.Assert(sc.validate(), NotNil)
+	for _, step := range []float64{0, 1, -0.1} {
+		sc = cfg.Schedule.clone()
+		sc.AdaptiveLeaderWeightStep = step
+		c.Assert(sc.validate(), NotNil)
+	}
+	sc = cfg.Schedule.clone()
+	sc.MinAdaptiveLeaderFactor = 0
+	c.Assert(sc.validate(), NotNil)
+}
+
+func (s *testConfigSuite) TestSchedulePolicy(c *C) {
+	cfg := NewTestSingleConfig()
+	cfg.Schedule.SchedulePolicies = SchedulePolicies{
+		{Name: "balance-region-scheduler", Windows: []string{"01:00-06:00"}, OutsideLimit: 1},
+		{Name: mergeCheckerName, Windows: []string{"22:30-02:00"}},
+	}
+	c.Assert(cfg.adjust(), IsNil)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2018, 8, 1, hour, minute, 0, 0, time.Local)
+	}
+	p := cfg.Schedule.SchedulePolicies[0]
+	c.Assert(p.InWindow(at(1, 0)), IsTrue)
+	c.Assert(p.InWindow(at(5, 59)), IsTrue)
+	c.Assert(p.InWindow(at(6, 0)), IsFalse)
+	limit, ok := p.limitAt(at(12, 0))
+	c.Assert(ok, IsTrue)
+	c.Assert(limit, Equals, uint64(1))
+	_, ok = p.limitAt(at(3, 0))
+	c.Assert(ok, IsFalse)
+
+	// The window crosses midnight.
+	p = cfg.Schedule.SchedulePolicies[1]
+	c.Assert(p.InWindow(at(23, 0)), IsTrue)
+	c.Assert(p.InWindow(at(0, 30)), IsTrue)
+	c.Assert(p.InWindow(at(2, 0)), IsFalse)
+	c.Assert(p.InWindow(at(22, 0)), IsFalse)
+
+	clone := cfg.Schedule.clone()
+	clone.SchedulePolicies[0].Windows[0] = "00:00-01:00"
+	c.Assert(cfg.Schedule.SchedulePolicies[0].Windows[0], Equals, "01:00-06:00")
+
+	for _, w := range []string{"01:00", "1-6", "01:00-24:00", "01:60-02:00", "03:00-03:00"} {
+		cfg.Schedule.SchedulePolicies[0].Windows = []string{w}
+		c.Assert(cfg.adjust(), NotNil)
+	}
+	cfg.Schedule.SchedulePolicies[0].Windows = nil
+	cfg.Schedule.SchedulePolicies[1].Name = "balance-region-scheduler"
+	c.Assert(cfg.adjust(), NotNil)
+}
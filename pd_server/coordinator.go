@@ -16,6 +16,7 @@ package server
 import (
 	"container/list"
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -47,6 +48,9 @@ const (
 var (
 	errSchedulerExisted  = errors.New("scheduler existed")
 	errSchedulerNotFound = errors.New("scheduler not found")
+	errNotConfigurable   = errors.New("scheduler is not configurable")
+	errNotDryRun         = errors.New("scheduler is not in dry-run mode")
+	errNotTraceable      = errors.New("scheduler is not traceable")
 )
 
 type coordinator struct {
@@ -66,11 +70,14 @@ type coordinator struct {
 	schedulers       map[string]*scheduleController
 	classifier       namespace.Classifier
 	histories        *list.List
+	operatorHistory  *operatorHistory
+	opPersister      *operatorPersister
 	hbStreams        *heartbeatStreams
 }
 
 func newCoordinator(cluster *clusterInfo, hbStreams *heartbeatStreams, classifier namespace.Classifier) *coordinator {
 	ctx, cancel := context.WithCancel(context.Background())
+	persister := newOperatorPersister(cluster.kv)
 	return &coordinator{
 		ctx:              ctx,
 		cancel:           cancel,
@@ -84,6 +91,8 @@ func newCoordinator(cluster *clusterInfo, hbStreams *heartbeatStreams, classifie
 		schedulers:       make(map[string]*scheduleController),
 		classifier:       classifier,
 		histories:        list.New(),
+		operatorHistory:  newOperatorHistory(cluster.kv, persister),
+		opPersister:      persister,
 		hbStreams:        hbStreams,
 	}
 }
@@ -92,9 +101,20 @@ func (c *coordinator) dispatch(region *core.RegionInfo) {
 	// Check existed operator.
 	if op := c.getOperator(region.GetId()); op != nil {
 		timeout := op.IsTimeout()
-		if step := op.Check(region); step != nil && !timeout {
+		currentStep := op.CurrentStep()
+		step := op.Check(region)
+		if err := op.CheckEpoch(region); err != nil {
+			log.Infof("[region %v] operator canceled for epoch changed: %v, %s", region.GetId(), err, op)
+			operatorCounter.WithLabelValues(op.Desc(), "epoch_changed").Inc()
+			c.removeOperator(op, schedule.OperatorCanceledEpochChanged, err.Error())
+			return
+		}
+		if step != nil && !timeout {
 			operatorCounter.WithLabelValues(op.Desc(), "check").Inc()
-			c.sendScheduleCommand(region, step)
+			if op.CurrentStep() != currentStep {
+				c.saveOperator(op)
+			}
+			c.sendOperatorStep(op, region, step)
 			return
 		}
 		if op.IsFinish() {
@@ -102,11 +122,11 @@ func (c *coordinator) dispatch(region *core.RegionInfo) {
 			operatorCounter.WithLabelValues(op.Desc(), "finish").Inc()
 			operatorDuration.WithLabelValues(op.Desc()).Observe(op.ElapsedTime().Seconds())
 			c.pushHistory(op)
-			c.removeOperator(op)
+			c.removeOperator(op, schedule.OperatorSuccess, "all steps finished")
 		} else if timeout {
 			log.Infof("[region %v] operator timeout: %s", region.GetId(), op)
 			operatorCounter.WithLabelValues(op.Desc(), "timeout").Inc()
-			c.removeOperator(op)
+			c.removeOperator(op, schedule.OperatorTimeout, operatorTimeoutReason(op))
 		}
 	}
 }
@@ -135,6 +155,9 @@ func (c *coordinator) patrolRegions() {
 			continue
 		}
 
+		now := time.Now()
+		replicaLimit := c.getCheckerLimit(replicaCheckerName, c.cluster.GetReplicaScheduleLimit(), now)
+		mergeLimit := c.getCheckerLimit(mergeCheckerName, c.cluster.GetMergeScheduleLimit(), now)
 		for _, region := range regions {
 			key = region.GetEndKey()
 			
@@ -143,14 +166,14 @@ func (c *coordinator) patrolRegions() {
 				c.addOperator(op)
 				break
 			}
-			if c.limiter.OperatorCount(schedule.OpReplica) < c.cluster.GetReplicaScheduleLimit() {
+			if c.limiter.OperatorCount(schedule.OpReplica) < replicaLimit {
 				if op := c.replicaChecker.Check(region); op != nil {
 					//log.Info("replicaChecker addOperator op: %s", op)	// wyy add
 					c.addOperator(op)
 					break
 				}
 			}
-			if c.limiter.OperatorCount(schedule.OpMerge) < c.cluster.GetMergeScheduleLimit() {
+			if c.limiter.OperatorCount(schedule.OpMerge) < mergeLimit {
 				if op1, op2 := c.mergeChecker.Check(region); op1 != nil && op2 != nil {
 					//log.Info("mergeChecker addOperator op1: %s, op2: %s", op1, op2)	// wyy add
 					// make sure two operators can add successfully altogether
@@ -165,7 +188,19 @@ func (c *coordinator) patrolRegions() {
 	}
 }
 
+// getCheckerLimit returns the schedule limit of the checker capped by its
+// schedule policy at the time.
+func (c *coordinator) getCheckerLimit(name string, limit uint64, t time.Time) uint64 {
+	if policyLimit, ok := c.cluster.opt.GetSchedulePolicyLimit(name, t); ok {
+		return minUint64(limit, policyLimit)
+	}
+	return limit
+}
+
 func (c *coordinator) run() {
+	c.wg.Add(1)
+	go c.persistOperators()
+
 	ticker := time.NewTicker(runSchedulerCheckInterval)
 	defer ticker.Stop()
 	log.Info("coordinator: Start collect cluster information")
@@ -181,6 +216,8 @@ func (c *coordinator) run() {
 			return
 		}
 	}
+	c.loadOperators()
+
 	log.Info("coordinator: Run scheduler")
 
 	k := 0
@@ -191,8 +228,15 @@ func (c *coordinator) run() {
 			log.Errorf("can not create scheduler %s: %v", schedulerCfg.Type, err)
 		} else {
 			log.Infof("create scheduler %s", s.GetName())
-			if err = c.addScheduler(s, schedulerCfg.Args...); err != nil {
+			if schedulerCfg.DryRun {
+				err = c.addDryRunScheduler(s, schedulerCfg.Args...)
+			} else {
+				err = c.addScheduler(s, schedulerCfg.Args...)
+			}
+			if err != nil {
 				log.Errorf("can not add scheduler %s: %v", s.GetName(), err)
+			} else if schedulerCfg.Paused {
+				c.restoreSchedulerPause(s.GetName(), schedulerCfg.PausedUntil)
 			}
 		}
 
@@ -262,6 +306,38 @@ func (c *coordinator) getSchedulers() []string {
 	return names
 }
 
+// SchedulerStatus is the state of a running scheduler.
+type SchedulerStatus struct {
+	Name   string `json:"name"`
+	Paused bool   `json:"paused"`
+	DryRun bool   `json:"dry-run"`
+	// Policy is the schedule policy of the scheduler, and InWindow is true
+	// if it has none or it is in one of the time windows now.
+	Policy   *SchedulePolicy `json:"policy,omitempty"`
+	InWindow bool            `json:"in-window"`
+	Allowed  bool            `json:"allowed"`
+}
+
+func (c *coordinator) getSchedulerStatuses() []*SchedulerStatus {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := time.Now()
+	statuses := make([]*SchedulerStatus, 0, len(c.schedulers))
+	for name, s := range c.schedulers {
+		policy := c.cluster.opt.GetSchedulePolicy(name)
+		statuses = append(statuses, &SchedulerStatus{
+			Name:     name,
+			Paused:   s.IsPaused(),
+			DryRun:   s.previews != nil,
+			Policy:   policy,
+			InWindow: policy == nil || policy.InWindow(now),
+			Allowed:  s.AllowSchedule(),
+		})
+	}
+	return statuses
+}
+
 func (c *coordinator) collectSchedulerMetrics() {
 	c.RLock()
 	defer c.RUnlock()
@@ -271,6 +347,11 @@ func (c *coordinator) collectSchedulerMetrics() {
 			allowScheduler = 1
 		}
 		schedulerStatusGauge.WithLabelValues(s.GetName(), "allow").Set(allowScheduler)
+		var pausedScheduler float64
+		if s.IsPaused() {
+			pausedScheduler = 1
+		}
+		schedulerStatusGauge.WithLabelValues(s.GetName(), "paused").Set(pausedScheduler)
 	}
 }
 
@@ -334,6 +415,16 @@ func (c *coordinator) shouldRun() bool {
 }
 
 func (c *coordinator) addScheduler(scheduler schedule.Scheduler, args ...string) error {
+	return c.addSchedulerWithMode(scheduler, false, args...)
+}
+
+// addDryRunScheduler adds a scheduler whose operators are kept for preview
+// instead of being added.
+func (c *coordinator) addDryRunScheduler(scheduler schedule.Scheduler, args ...string) error {
+	return c.addSchedulerWithMode(scheduler, true, args...)
+}
+
+func (c *coordinator) addSchedulerWithMode(scheduler schedule.Scheduler, dryRun bool, args ...string) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -342,6 +433,9 @@ func (c *coordinator) addScheduler(scheduler schedule.Scheduler, args ...string)
 	}
 
 	s := newScheduleController(c, scheduler)
+	if dryRun {
+		s.previews = newSchedulerPreviews()
+	}
 	if err := s.Prepare(c.cluster); err != nil {
 		return errors.Trace(err)
 	}
@@ -350,10 +444,27 @@ func (c *coordinator) addScheduler(scheduler schedule.Scheduler, args ...string)
 	go c.runScheduler(s)
 	c.schedulers[s.GetName()] = s
 	c.cluster.opt.AddSchedulerCfg(s.GetType(), args)
+	if dryRun {
+		return errors.Trace(c.cluster.opt.SetSchedulerDryRun(s.GetName(), true))
+	}
 
 	return nil
 }
 
+func (c *coordinator) getSchedulerPreviews(name string) ([]*SchedulerPreview, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, errSchedulerNotFound
+	}
+	if s.previews == nil {
+		return nil, errNotDryRun
+	}
+	return s.previews.list(), nil
+}
+
 func (c *coordinator) removeScheduler(name string) error {
 	c.Lock()
 	defer c.Unlock()
@@ -373,6 +484,144 @@ func (c *coordinator) removeScheduler(name string) error {
 	return nil
 }
 
+func (c *coordinator) getSchedulerConfig(name string) (map[string]interface{}, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, errSchedulerNotFound
+	}
+	cs, ok := s.Scheduler.(schedule.ConfigurableScheduler)
+	if !ok {
+		return nil, errNotConfigurable
+	}
+	return cs.GetConfig(), nil
+}
+
+// updateSchedulerConfig updates the config of the scheduler. The scheduler
+// may be renamed by the config, and the new name is returned.
+func (c *coordinator) updateSchedulerConfig(name string, config map[string]interface{}) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	s, ok := c.schedulers[name]
+	if !ok {
+		return "", errSchedulerNotFound
+	}
+	cs, ok := s.Scheduler.(schedule.ConfigurableScheduler)
+	if !ok {
+		return "", errNotConfigurable
+	}
+	args, err := cs.ParseConfig(config)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	// To create a temporary scheduler is just used to get the new name.
+	tmp, err := schedule.CreateScheduler(s.GetType(), schedule.NewLimiter(), args...)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	newName := tmp.GetName()
+	if _, ok := c.schedulers[newName]; ok && newName != name {
+		return "", errSchedulerExisted
+	}
+
+	// Persist the config before applying it, and restore the config if it
+	// fails to be applied.
+	opt, old := c.cluster.opt, c.cluster.opt.load()
+	if err = opt.SetSchedulerArgs(name, newName, args); err != nil {
+		return "", errors.Trace(err)
+	}
+	if err = opt.persist(c.cluster.kv); err != nil {
+		opt.store(old)
+		return "", errors.Trace(err)
+	}
+	if err = cs.UpdateConfig(s.wrapCluster(c.cluster), config); err != nil {
+		opt.store(old)
+		if e := opt.persist(c.cluster.kv); e != nil {
+			log.Errorf("can not restore scheduler %s config: %v", name, e)
+		}
+		return "", errors.Trace(err)
+	}
+	log.Infof("update scheduler %s config: %v", name, cs.GetConfig())
+
+	if newName != name {
+		delete(c.schedulers, name)
+		c.schedulers[newName] = s
+		schedulerStatusGauge.DeleteLabelValues(name, "allow")
+		schedulerStatusGauge.DeleteLabelValues(name, "paused")
+	}
+	return newName, nil
+}
+
+func (c *coordinator) getSchedulerTracer(name string) (*schedule.Tracer, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, errSchedulerNotFound
+	}
+	tracer := s.getTracer()
+	if tracer == nil {
+		return nil, errNotTraceable
+	}
+	return tracer, nil
+}
+
+// pauseScheduler stops the scheduler from scheduling for the duration, or
+// until it is resumed if the duration is 0.
+func (c *coordinator) pauseScheduler(name string, d time.Duration) error {
+	var until time.Time
+	if d > 0 {
+		until = time.Now().Add(d)
+	}
+	return c.setSchedulerPaused(name, true, until)
+}
+
+func (c *coordinator) resumeScheduler(name string) error {
+	return c.setSchedulerPaused(name, false, time.Time{})
+}
+
+func (c *coordinator) setSchedulerPaused(name string, paused bool, until time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+
+	s, ok := c.schedulers[name]
+	if !ok {
+		return errSchedulerNotFound
+	}
+
+	if paused {
+		log.Infof("pause scheduler %s until %v", name, until)
+		s.Pause(until)
+	} else {
+		log.Infof("resume scheduler %s", name)
+		s.Resume()
+	}
+
+	return errors.Trace(c.cluster.opt.SetSchedulerPaused(name, paused, until))
+}
+
+// restoreSchedulerPause pauses the scheduler restored from the config, unless
+// the pause has expired.
+func (c *coordinator) restoreSchedulerPause(name string, pausedUntil int64) {
+	var until time.Time
+	if pausedUntil != 0 {
+		until = time.Unix(pausedUntil, 0)
+		if !until.After(time.Now()) {
+			return
+		}
+	}
+	c.RLock()
+	defer c.RUnlock()
+	if s, ok := c.schedulers[name]; ok {
+		log.Infof("restore paused scheduler %s until %v", name, until)
+		s.Pause(until)
+	}
+}
+
 func (c *coordinator) runScheduler(s *scheduleController) {
 	defer logutil.LogPanic()
 	defer c.wg.Done()
@@ -389,13 +638,26 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 				continue
 			}
 			opInfluence := schedule.NewOpInfluence(c.getOperators(), c.cluster)
+			if s.previews != nil {
+				// Schedule may change the influence for the batch.
+				assumed := opInfluence.Clone()
+				if op := s.Schedule(c.cluster, opInfluence); op != nil {
+					s.previews.add(op, assumed)
+				}
+				continue
+			}
 			if op := s.Schedule(c.cluster, opInfluence); op != nil {
 				if len(op) == 1 {
 					//log.Info("runScheduler addOperator op[0]: %s", op[0])	// wyy add
 					c.addOperator(op[0])
-				} else {
+				} else if op[0].Kind()&schedule.OpMerge != 0 {
 					//log.Info("runScheduler addOperator op...: %s", op[0])	// wyy add
 					c.addOperators(op...)
+				} else {
+					// A batch of balance operators are added independently.
+					for _, o := range op {
+						c.addOperator(o)
+					}
 				}
 			}
 
@@ -411,6 +673,20 @@ func (c *coordinator) addOperatorLocked(op *schedule.Operator) bool {
 
 	log.Infof("[region %v] add operator: %s", regionID, op)
 
+	// The region may have changed since the operator is created.
+	region := c.cluster.GetRegion(regionID)
+	var step schedule.OperatorStep
+	if region != nil {
+		step = op.Check(region)
+		if err := op.CheckEpoch(region); err != nil {
+			log.Infof("[region %v] cancel add operator for epoch changed: %v", regionID, err)
+			operatorCounter.WithLabelValues(op.Desc(), "epoch_changed").Inc()
+			op.SetStatus(schedule.OperatorCanceledEpochChanged, err.Error())
+			c.recordOperator(op)
+			return false
+		}
+	}
+
 	// If the new operator passed in has higher priorities than the old one,
 	// then replace the old operator.
 	if old, ok := c.operators[regionID]; ok {
@@ -420,22 +696,45 @@ func (c *coordinator) addOperatorLocked(op *schedule.Operator) bool {
 		}
 		log.Infof("[region %v] replace old operator: %s", regionID, old)
 		operatorCounter.WithLabelValues(old.Desc(), "replaced").Inc()
-		c.removeOperatorLocked(old)
+		c.removeOperatorLocked(old, schedule.OperatorReplaced, fmt.Sprintf("replaced by %s", op.Desc()))
 	}
 
 	c.operators[regionID] = op
 	c.limiter.UpdateCounts(c.operators)
+	c.saveOperator(op)
+	c.takeStoreLimits(op)
 
-	if region := c.cluster.GetRegion(op.RegionID()); region != nil {
-		if step := op.Check(region); step != nil {
-			c.sendScheduleCommand(region, step)
-		}
+	if step != nil {
+		c.sendOperatorStep(op, region, step)
 	}
 
 	operatorCounter.WithLabelValues(op.Desc(), "create").Inc()
 	return true
 }
 
+// takeStoreLimits takes the tokens of the stores for the peers to be added
+// and removed by the operator.
+func (c *coordinator) takeStoreLimits(op *schedule.Operator) {
+	for i := op.CurrentStep(); i < op.Len(); i++ {
+		switch s := op.Step(i).(type) {
+		case schedule.AddPeer:
+			c.takeStoreLimit(s.ToStore, core.StoreAddPeer)
+		case schedule.RemovePeer:
+			c.takeStoreLimit(s.FromStore, core.StoreRemovePeer)
+		}
+	}
+}
+
+func (c *coordinator) takeStoreLimit(storeID uint64, typ core.StoreLimitType) {
+	store := c.cluster.GetStore(storeID)
+	if store == nil {
+		return
+	}
+	if limit := store.GetStoreLimit(typ); limit != nil {
+		limit.Take(1)
+	}
+}
+
 func (c *coordinator) addOperator(op *schedule.Operator) bool {
 	c.Lock()
 	defer c.Unlock()
@@ -452,6 +751,13 @@ func (c *coordinator) addOperators(ops ...*schedule.Operator) bool {
 			log.Infof("[region %v] cancel add operators, old: %s", op.RegionID(), old)
 			return false
 		}
+		if region := c.cluster.GetRegion(op.RegionID()); region != nil {
+			op.Check(region)
+			if err := op.CheckEpoch(region); err != nil {
+				log.Infof("[region %v] cancel add operators for epoch changed: %v", op.RegionID(), err)
+				return false
+			}
+		}
 	}
 	for _, op := range ops {
 		c.addOperatorLocked(op)
@@ -483,19 +789,151 @@ func (c *coordinator) pruneHistory() {
 	}
 }
 
-func (c *coordinator) removeOperator(op *schedule.Operator) {
+func (c *coordinator) removeOperator(op *schedule.Operator, status schedule.OperatorStatus, reason string) {
 	c.Lock()
 	defer c.Unlock()
-	c.removeOperatorLocked(op)
+	c.removeOperatorLocked(op, status, reason)
 }
 
-func (c *coordinator) removeOperatorLocked(op *schedule.Operator) {
+// removeOperatorLocked ends the operator with the status and the reason. It
+// does nothing if the operator has ended.
+func (c *coordinator) removeOperatorLocked(op *schedule.Operator, status schedule.OperatorStatus, reason string) {
+	if !op.SetStatus(status, reason) {
+		return
+	}
+	log.Infof("[region %v] operator %s: %s", op.RegionID(), status, reason)
 	regionID := op.RegionID()
 	delete(c.operators, regionID)
 	c.limiter.UpdateCounts(c.operators)
+	c.deleteSavedOperator(regionID)
+	c.recordOperator(op)
 	operatorCounter.WithLabelValues(op.Desc(), "remove").Inc()
 }
 
+// operatorTimeoutReason returns the reason of the timed out operator, which
+// tells the step not finished.
+func operatorTimeoutReason(op *schedule.Operator) string {
+	return fmt.Sprintf("step %v not finished in %v", op.Step(op.CurrentStep()), schedule.MaxOperatorWaitTime)
+}
+
+// saveOperator saves the operator with its current step, so that the next
+// leader can resume it. It is written to the KV by persistOperators.
+func (c *coordinator) saveOperator(op *schedule.Operator) {
+	if c.cluster.kv == nil {
+		return
+	}
+	c.opPersister.save(op.Meta())
+}
+
+func (c *coordinator) deleteSavedOperator(regionID uint64) {
+	if c.cluster.kv == nil {
+		return
+	}
+	c.opPersister.delete(regionID)
+}
+
+// persistOperators writes the saved operators to the KV, so that the KV
+// round trips are out of the coordinator lock and the heartbeat path.
+func (c *coordinator) persistOperators() {
+	defer c.wg.Done()
+	c.opPersister.run(c.ctx)
+}
+
+// recordOperator appends the ended operator to the operator history. It is
+// written to the KV by persistOperators.
+func (c *coordinator) recordOperator(op *schedule.Operator) {
+	if c.cluster.kv == nil {
+		return
+	}
+	c.operatorHistory.append(op.Record(), c.cluster.opt.GetOperatorHistoryLimit())
+}
+
+// loadOperatorHistory loads the operator history kept by the previous
+// leader. It must be called before any operator is recorded.
+func (c *coordinator) loadOperatorHistory() error {
+	if c.cluster.kv == nil {
+		return nil
+	}
+	return errors.Trace(c.operatorHistory.load(c.cluster.opt.GetOperatorHistoryLimit()))
+}
+
+// getOperatorRecords returns the records of the operator history matching
+// the filter.
+func (c *coordinator) getOperatorRecords(filter *OperatorRecordFilter) []*schedule.OperatorRecord {
+	if c.cluster.kv == nil {
+		return nil
+	}
+	return c.operatorHistory.query(filter, c.cluster.opt.GetOperatorHistoryLimit())
+}
+
+// loadOperators resumes the operators saved by the previous leader. The
+// timed out operators are rolled back, and the ones whose regions are gone
+// or which have finished are dropped.
+func (c *coordinator) loadOperators() {
+	if c.cluster.kv == nil {
+		return
+	}
+	values, err := c.cluster.kv.LoadOperators(kvRangeLimit)
+	if err != nil {
+		log.Errorf("load operators error: %v", err)
+		return
+	}
+	for _, value := range values {
+		meta := &schedule.OperatorMeta{}
+		if err = json.Unmarshal([]byte(value), meta); err != nil {
+			log.Errorf("unmarshal operator error: %v", err)
+			continue
+		}
+		c.resumeOperator(meta)
+	}
+}
+
+func (c *coordinator) resumeOperator(meta *schedule.OperatorMeta) {
+	op, err := schedule.NewOperatorFromMeta(meta)
+	if err != nil {
+		log.Errorf("[region %v] drop saved operator: %v", meta.RegionID, err)
+		c.deleteSavedOperator(meta.RegionID)
+		return
+	}
+	region := c.cluster.GetRegion(meta.RegionID)
+	if region == nil {
+		log.Warnf("[region %v] drop saved operator of missing region: %s", meta.RegionID, op)
+		c.deleteSavedOperator(meta.RegionID)
+		return
+	}
+
+	op.Check(region)
+	switch {
+	case op.IsFinish():
+		log.Infof("[region %v] saved operator finish: %s", region.GetId(), op)
+		c.deleteSavedOperator(region.GetId())
+		op.SetStatus(schedule.OperatorSuccess, "all steps finished before resumed")
+		c.recordOperator(op)
+	case op.IsTimeout():
+		c.deleteSavedOperator(region.GetId())
+		op.SetStatus(schedule.OperatorTimeout, operatorTimeoutReason(op))
+		c.recordOperator(op)
+		if rollback := schedule.CreateRollbackOperator(c.cluster, op, region); rollback != nil {
+			log.Infof("[region %v] roll back saved operator: %s", region.GetId(), op)
+			operatorCounter.WithLabelValues(op.Desc(), "rollback").Inc()
+			c.addOperator(rollback)
+		}
+	default:
+		log.Infof("[region %v] resume saved operator: %s", region.GetId(), op)
+		operatorCounter.WithLabelValues(op.Desc(), "resume").Inc()
+		c.addOperator(op)
+	}
+}
+
+// sendOperatorStep sends the step of the operator to the region, and starts
+// the operator on its first step.
+func (c *coordinator) sendOperatorStep(op *schedule.Operator, region *core.RegionInfo, step schedule.OperatorStep) {
+	if op.Status() == schedule.OperatorCreated {
+		op.SetStatus(schedule.OperatorStarted, fmt.Sprintf("send step %v", step))
+	}
+	c.sendScheduleCommand(region, step)
+}
+
 func (c *coordinator) getOperator(regionID uint64) *schedule.Operator {
 	c.RLock()
 	defer c.RUnlock()
@@ -584,6 +1022,13 @@ type scheduleController struct {
 	nextInterval time.Duration
 	ctx          context.Context
 	cancel       context.CancelFunc
+
+	pauseMu     sync.Mutex
+	paused      bool
+	pausedUntil time.Time
+
+	// previews is not nil if the scheduler is in dry-run mode.
+	previews *schedulerPreviews
 }
 
 func newScheduleController(c *coordinator, s schedule.Scheduler) *scheduleController {
@@ -599,6 +1044,23 @@ func newScheduleController(c *coordinator, s schedule.Scheduler) *scheduleContro
 	}
 }
 
+// wrapCluster keeps a dry-run scheduler from changing the cluster when it
+// prepares, cleans up or updates config.
+func (s *scheduleController) wrapCluster(cluster schedule.Cluster) schedule.Cluster {
+	if s.previews != nil {
+		return dryRunCluster{cluster}
+	}
+	return cluster
+}
+
+func (s *scheduleController) Prepare(cluster schedule.Cluster) error {
+	return s.Scheduler.Prepare(s.wrapCluster(cluster))
+}
+
+func (s *scheduleController) Cleanup(cluster schedule.Cluster) {
+	s.Scheduler.Cleanup(s.wrapCluster(cluster))
+}
+
 func (s *scheduleController) Ctx() context.Context {
 	return s.ctx
 }
@@ -608,9 +1070,16 @@ func (s *scheduleController) Stop() {
 }
 
 func (s *scheduleController) Schedule(cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
+	if limit, ok := s.cluster.opt.GetSchedulePolicyLimit(s.GetName(), time.Now()); ok {
+		cluster = policyCluster{Cluster: cluster, limit: limit}
+	}
+	tracer := s.getTracer()
 	for i := 0; i < maxScheduleRetries; i++ {
+		tracer.Begin()
+		op := scheduleByNamespace(cluster, s.classifier, s.Scheduler, opInfluence)
+		tracer.End(len(op))
 		// If we have schedule, reset interval to the minimal interval.
-		if op := scheduleByNamespace(cluster, s.classifier, s.Scheduler, opInfluence); op != nil {
+		if op != nil {
 			s.nextInterval = s.Scheduler.GetMinInterval()
 			return op
 		}
@@ -619,10 +1088,51 @@ func (s *scheduleController) Schedule(cluster schedule.Cluster, opInfluence sche
 	return nil
 }
 
+// getTracer returns the tracer of the scheduler, or nil if it is not
+// traceable.
+func (s *scheduleController) getTracer() *schedule.Tracer {
+	if ts, ok := s.Scheduler.(schedule.TraceableScheduler); ok {
+		return ts.GetTracer()
+	}
+	return nil
+}
+
 func (s *scheduleController) GetInterval() time.Duration {
 	return s.nextInterval
 }
 
 func (s *scheduleController) AllowSchedule() bool {
-	return s.Scheduler.IsScheduleAllowed(s.cluster)
+	if s.IsPaused() {
+		return false
+	}
+	limit, ok := s.cluster.opt.GetSchedulePolicyLimit(s.GetName(), time.Now())
+	if !ok {
+		return s.Scheduler.IsScheduleAllowed(s.cluster)
+	}
+	return limit > 0 && s.Scheduler.IsScheduleAllowed(policyCluster{Cluster: s.cluster, limit: limit})
+}
+
+// Pause stops the scheduler from scheduling until the time, or until it is
+// resumed if the time is zero.
+func (s *scheduleController) Pause(until time.Time) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	s.paused, s.pausedUntil = true, until
+}
+
+// Resume lets the paused scheduler schedule again.
+func (s *scheduleController) Resume() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	s.paused, s.pausedUntil = false, time.Time{}
+}
+
+// IsPaused checks if the scheduler is paused now.
+func (s *scheduleController) IsPaused() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.paused && !s.pausedUntil.IsZero() && !time.Now().Before(s.pausedUntil) {
+		s.paused, s.pausedUntil = false, time.Time{}
+	}
+	return s.paused
 }
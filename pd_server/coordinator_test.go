@@ -14,6 +14,7 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -29,7 +30,7 @@ import (
 )
 
 func newTestOperator(regionID uint64, kind schedule.OperatorKind) *schedule.Operator {
-	return schedule.NewOperator("test", regionID, kind)
+	return schedule.NewOperator("test", regionID, nil, kind)
 }
 
 func newTestScheduleConfig() (*ScheduleConfig, *scheduleOption) {
@@ -145,7 +146,7 @@ func (s *testCoordinatorSuite) TestBasic(c *C) {
 	c.Assert(l.OperatorCount(op2.Kind()), Equals, uint64(0))
 
 	// Remove the operator manually, then we can add a new operator.
-	co.removeOperator(op1)
+	co.removeOperator(op1, schedule.OperatorCanceledByAdmin, "test")
 	co.addOperator(op2)
 	c.Assert(l.OperatorCount(op2.Kind()), Equals, uint64(1))
 	c.Assert(co.getOperator(1).RegionID(), Equals, op2.RegionID())
@@ -531,6 +532,89 @@ func (s *testCoordinatorSuite) TestRestart(c *C) {
 	co.stop()
 }
 
+func (s *testCoordinatorSuite) TestResumeOperators(c *C) {
+	// Turn off schedulers and checkers.
+	cfg, opt := newTestScheduleConfig()
+	cfg.LeaderScheduleLimit = 0
+	cfg.RegionScheduleLimit = 0
+	cfg.ReplicaScheduleLimit = 0
+	cfg.MergeScheduleLimit = 0
+
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 1)
+	tc.addRegionStore(4, 0)
+	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.addLeaderRegion(2, 1, 2, 3)
+	tc.activeRegions = 2
+
+	// Region 1 is moving a peer from store 3 to store 4.
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	op1 := schedule.CreateMovePeerOperator("test", tc, tc.GetRegion(1), schedule.OpBalance, 3, 4, 100)
+	c.Assert(co.addOperator(op1), IsTrue)
+	// The operator is saved when the changes are flushed.
+	values, err := tc.kv.LoadOperators(kvRangeLimit)
+	c.Assert(err, IsNil)
+	c.Assert(values, HasLen, 0)
+	co.opPersister.flush()
+
+	// Region 2 has added the peer on store 4, but the operator has timed out.
+	region := tc.GetRegion(2).Clone()
+	region.Peers = append(region.Peers, &metapb.Peer{Id: 101, StoreId: 4})
+	tc.putRegion(region)
+	op2 := schedule.CreateMovePeerOperator("test", tc, region, schedule.OpBalance, 3, 4, 101)
+	meta := op2.Meta()
+	meta.CreateTime = time.Now().Add(-schedule.MaxOperatorWaitTime - time.Minute)
+	c.Assert(tc.kv.SaveOperator(2, meta), IsNil)
+
+	// Region 3 is gone.
+	meta = schedule.NewOperator("test", 3, nil, schedule.OpLeader, schedule.TransferLeader{FromStore: 1, ToStore: 2}).Meta()
+	c.Assert(tc.kv.SaveOperator(3, meta), IsNil)
+
+	// The new leader resumes the operators before store 4 sends any
+	// heartbeat to it.
+	store := tc.GetStore(4)
+	store.LastHeartbeatTS = time.Time{}
+	tc.putStore(store)
+	co = newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	co.run()
+	defer co.stop()
+
+	op := co.getOperator(1)
+	c.Assert(op, NotNil)
+	c.Assert(op.Desc(), Equals, "test")
+	c.Assert(op.CurrentStep(), Equals, 0)
+	c.Assert(op.Step(0), Equals, schedule.AddPeer{ToStore: 4, PeerID: 100})
+
+	op = co.getOperator(2)
+	c.Assert(op, NotNil)
+	c.Assert(op.Desc(), Equals, "rollback-test")
+	c.Assert(op.Len(), Equals, 1)
+	c.Assert(op.Step(0), Equals, schedule.RemovePeer{FromStore: 4})
+
+	c.Assert(co.getOperator(3), IsNil)
+	co.opPersister.flush()
+	values, err = tc.kv.LoadOperators(kvRangeLimit)
+	c.Assert(err, IsNil)
+	c.Assert(values, HasLen, 2)
+
+	// The operator to store 4 is kept until the store is down.
+	cluster := &RaftCluster{cachedCluster: tc.clusterInfo, coordinator: co}
+	cluster.checkOperators()
+	op = co.getOperator(1)
+	c.Assert(op, NotNil)
+	store = tc.GetStore(4)
+	store.LastHeartbeatTS = time.Now().Add(-tc.GetMaxStoreDownTime() - time.Minute)
+	tc.putStore(store)
+	cluster.checkOperators()
+	c.Assert(co.getOperator(1), IsNil)
+	c.Assert(op.Status(), Equals, schedule.OperatorCanceledStoreDown)
+}
+
 func waitOperator(c *C, co *coordinator, regionID uint64) {
 	testutil.WaitUntil(c, func(c *C) bool {
 		return co.getOperator(regionID) != nil
@@ -617,7 +701,7 @@ func (s *testScheduleControllerSuite) TestController(c *C) {
 	c.Assert(co.addOperator(op2), IsTrue)
 	// count = 2
 	c.Assert(sc.AllowSchedule(), IsFalse)
-	co.removeOperator(op1)
+	co.removeOperator(op1, schedule.OperatorCanceledByAdmin, "test")
 	// count = 1
 	c.Assert(sc.AllowSchedule(), IsTrue)
 
@@ -628,7 +712,7 @@ func (s *testScheduleControllerSuite) TestController(c *C) {
 	c.Assert(sc.AllowSchedule(), IsFalse)
 	c.Assert(co.addOperator(op3), IsTrue)
 	c.Assert(sc.AllowSchedule(), IsTrue)
-	co.removeOperator(op3)
+	co.removeOperator(op3, schedule.OperatorCanceledByAdmin, "test")
 
 	// add a admin operator will remove old operator
 	c.Assert(co.addOperator(op2), IsTrue)
@@ -712,3 +796,460 @@ func waitNoResponse(c *C, stream *mockHeartbeatStream) {
 		return res == nil
 	})
 }
+
+func (s *testCoordinatorSuite) TestOperatorHistory(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	cfg.OperatorHistoryLimit = 3
+
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 1)
+	tc.addLeaderRegion(1, 1, 2)
+	tc.addLeaderRegion(2, 1, 2)
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	start := time.Now().Add(-time.Second)
+
+	// The leader operator of region 1 is replaced by an admin operator, which
+	// is then canceled.
+	op1 := schedule.NewOperator("test", 1, nil, schedule.OpLeader, schedule.TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(co.addOperator(op1), IsTrue)
+	op2 := schedule.NewOperator("test", 1, nil, schedule.OpAdmin|schedule.OpRegion, schedule.AddPeer{ToStore: 3, PeerID: 3})
+	op2.SetPriorityLevel(core.HighPriority)
+	c.Assert(co.addOperator(op2), IsTrue)
+	co.removeOperator(op2, schedule.OperatorCanceledByAdmin, "test")
+	// The operator of region 2 finishes.
+	op3 := schedule.NewOperator("test", 2, nil, schedule.OpLeader, schedule.TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(co.addOperator(op3), IsTrue)
+	co.removeOperator(op3, schedule.OperatorSuccess, "test")
+
+	records := co.getOperatorRecords(&OperatorRecordFilter{})
+	c.Assert(records, HasLen, 3)
+	for i, status := range []schedule.OperatorStatus{schedule.OperatorReplaced, schedule.OperatorCanceledByAdmin, schedule.OperatorSuccess} {
+		c.Assert(records[i].Seq, Equals, uint64(i))
+		c.Assert(records[i].Status, Equals, status)
+	}
+	c.Assert(records[1].Stores, DeepEquals, []uint64{3})
+	c.Assert(records[1].Steps, DeepEquals, op2.Meta().Steps)
+	c.Assert(records[0].Transitions[1].Status, Equals, schedule.OperatorStarted)
+	c.Assert(records[0].Transitions[2].Reason, Equals, "replaced by test")
+	c.Assert(op1.Status(), Equals, schedule.OperatorReplaced)
+
+	records = co.getOperatorRecords(&OperatorRecordFilter{RegionID: 1})
+	c.Assert(records, HasLen, 2)
+	records = co.getOperatorRecords(&OperatorRecordFilter{StoreID: 3})
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Status, Equals, schedule.OperatorCanceledByAdmin)
+	records = co.getOperatorRecords(&OperatorRecordFilter{Kind: schedule.OpLeader})
+	c.Assert(records, HasLen, 2)
+	records = co.getOperatorRecords(&OperatorRecordFilter{Start: start, End: time.Now()})
+	c.Assert(records, HasLen, 3)
+	records = co.getOperatorRecords(&OperatorRecordFilter{End: start})
+	c.Assert(records, HasLen, 0)
+
+	// The history is kept by the new leader, and the oldest record is
+	// deleted once the limit is reached.
+	co.opPersister.flush()
+	co = newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	c.Assert(co.loadOperatorHistory(), IsNil)
+	op4 := schedule.NewOperator("test", 2, nil, schedule.OpLeader, schedule.TransferLeader{FromStore: 2, ToStore: 1})
+	c.Assert(co.addOperator(op4), IsTrue)
+	co.removeOperator(op4, schedule.OperatorTimeout, "test")
+	records = co.getOperatorRecords(&OperatorRecordFilter{})
+	c.Assert(records, HasLen, 3)
+	c.Assert(records[0].Status, Equals, schedule.OperatorCanceledByAdmin)
+	c.Assert(records[2].Seq, Equals, uint64(3))
+	c.Assert(records[2].Status, Equals, schedule.OperatorTimeout)
+	records = co.getOperatorRecords(&OperatorRecordFilter{Limit: 2})
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[1].Seq, Equals, uint64(3))
+	co.opPersister.flush()
+	c.Assert(loadOperatorRecordSeqs(c, tc.kv), DeepEquals, []uint64{1, 2, 3})
+
+	// The records beyond a reduced limit are deleted.
+	cfg.OperatorHistoryLimit = 2
+	co.recordOperator(op4)
+	c.Assert(co.getOperatorRecords(&OperatorRecordFilter{}), HasLen, 2)
+	co.opPersister.flush()
+	c.Assert(loadOperatorRecordSeqs(c, tc.kv), DeepEquals, []uint64{3, 4})
+
+	// The records are kept up to a raised limit.
+	cfg.OperatorHistoryLimit = 4
+	for i := 0; i < 3; i++ {
+		co.recordOperator(op4)
+	}
+	co.opPersister.flush()
+	c.Assert(loadOperatorRecordSeqs(c, tc.kv), DeepEquals, []uint64{4, 5, 6, 7})
+
+	// The new leader deletes the records beyond the limit.
+	cfg.OperatorHistoryLimit = 1
+	co = newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	c.Assert(co.loadOperatorHistory(), IsNil)
+	records = co.getOperatorRecords(&OperatorRecordFilter{})
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].Seq, Equals, uint64(7))
+	co.opPersister.flush()
+	c.Assert(loadOperatorRecordSeqs(c, tc.kv), DeepEquals, []uint64{7})
+}
+
+func loadOperatorRecordSeqs(c *C, kv *core.KV) []uint64 {
+	values, err := kv.LoadOperatorRecords(0, kvRangeLimit)
+	c.Assert(err, IsNil)
+	var seqs []uint64
+	for _, value := range values {
+		record := &schedule.OperatorRecord{}
+		c.Assert(json.Unmarshal([]byte(value), record), IsNil)
+		seqs = append(seqs, record.Seq)
+	}
+	return seqs
+}
+
+func (s *testCoordinatorSuite) TestEpochChanged(c *C) {
+	_, opt := newTestScheduleConfig()
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 0)
+	tc.addLeaderRegion(1, 1, 2)
+	region := tc.GetRegion(1).Clone()
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 1, Version: 1}
+	tc.putRegion(region)
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	op1 := schedule.CreateMovePeerOperator("test", tc, region, schedule.OpBalance, 2, 3, 100)
+	c.Assert(co.addOperator(op1), IsTrue)
+
+	// The peer on store 3 is being added.
+	region = region.Clone()
+	region.Peers = append(region.Peers, &metapb.Peer{Id: 100, StoreId: 3})
+	region.PendingPeers = []*metapb.Peer{region.GetStorePeer(3)}
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 2, Version: 1}
+	co.dispatch(region)
+	c.Assert(co.getOperator(1), Equals, op1)
+
+	// The region is split.
+	region = region.Clone()
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 2, Version: 2}
+	tc.putRegion(region)
+	co.dispatch(region)
+	c.Assert(co.getOperator(1), IsNil)
+	c.Assert(op1.Status(), Equals, schedule.OperatorCanceledEpochChanged)
+
+	// The operator created before the split is not added.
+	op2 := schedule.NewOperator("test", 1, &metapb.RegionEpoch{ConfVer: 2, Version: 1}, schedule.OpLeader, schedule.TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(co.addOperator(op2), IsFalse)
+	c.Assert(op2.Status(), Equals, schedule.OperatorCanceledEpochChanged)
+	records := co.getOperatorRecords(&OperatorRecordFilter{RegionID: 1})
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[1].Status, Equals, schedule.OperatorCanceledEpochChanged)
+
+	op3 := schedule.NewOperator("test", 1, region.GetRegionEpoch(), schedule.OpLeader, schedule.TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(co.addOperator(op3), IsTrue)
+	c.Assert(op3.Status(), Equals, schedule.OperatorStarted)
+}
+
+func (s *testCoordinatorSuite) TestStoreLimit(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	cfg.StoreAddPeerRate = 1
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 0)
+	tc.addLeaderRegion(1, 1, 2)
+	tc.addLeaderRegion(2, 1, 2)
+	c.Assert(tc.GetStore(3).IsAvailable(core.StoreAddPeer), IsTrue)
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	op := schedule.CreateMovePeerOperator("test", tc, tc.GetRegion(1), schedule.OpBalance, 2, 3, 100)
+	c.Assert(co.addOperator(op), IsTrue)
+	c.Assert(tc.GetStore(3).IsAvailable(core.StoreAddPeer), IsFalse)
+	c.Assert(tc.GetStore(2).GetStoreLimit(core.StoreRemovePeer).Available() < cfg.StoreRemovePeerRate, IsTrue)
+
+	// The filter stops adding more peers to store 3.
+	filter := schedule.NewStoreLimitFilter()
+	c.Assert(filter.FilterTarget(tc, tc.GetStore(3)), IsTrue)
+	c.Assert(filter.FilterSource(tc, tc.GetStore(2)), IsFalse)
+
+	// The rate is raised by the store override.
+	store := tc.GetStore(3)
+	store.AddPeerRate = 10
+	tc.putStore(store)
+	c.Assert(tc.GetStore(3).GetStoreLimit(core.StoreAddPeer).Rate(), Equals, 10.0)
+}
+
+func (s *testCoordinatorSuite) TestPauseScheduler(c *C) {
+	_, opt := newTestScheduleConfig()
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	co.run()
+	defer co.stop()
+
+	name := "balance-region-scheduler"
+	c.Assert(co.schedulers[name].AllowSchedule(), IsTrue)
+	c.Assert(co.pauseScheduler("foo", 0), Equals, errSchedulerNotFound)
+
+	// Paused until resumed.
+	c.Assert(co.pauseScheduler(name, 0), IsNil)
+	c.Assert(co.schedulers[name].AllowSchedule(), IsFalse)
+	i, err := findSchedulerCfg(opt.GetSchedulers(), name)
+	c.Assert(err, IsNil)
+	c.Assert(opt.GetSchedulers()[i].Paused, IsTrue)
+	c.Assert(opt.GetSchedulers()[i].PausedUntil, Equals, int64(0))
+
+	c.Assert(co.resumeScheduler(name), IsNil)
+	c.Assert(co.schedulers[name].AllowSchedule(), IsTrue)
+	c.Assert(opt.GetSchedulers()[i].Paused, IsFalse)
+
+	// Paused with a duration.
+	c.Assert(co.pauseScheduler(name, time.Hour), IsNil)
+	c.Assert(co.schedulers[name].IsPaused(), IsTrue)
+	c.Assert(opt.GetSchedulers()[i].PausedUntil > time.Now().Unix(), IsTrue)
+	co.schedulers[name].Pause(time.Now().Add(-time.Second))
+	c.Assert(co.schedulers[name].IsPaused(), IsFalse)
+
+	// The pause is restored from the config unless it has expired.
+	co.schedulers[name].Resume()
+	co.restoreSchedulerPause(name, time.Now().Add(-time.Minute).Unix())
+	c.Assert(co.schedulers[name].IsPaused(), IsFalse)
+	co.restoreSchedulerPause(name, time.Now().Add(time.Minute).Unix())
+	c.Assert(co.schedulers[name].IsPaused(), IsTrue)
+}
+
+func (s *testCoordinatorSuite) TestUpdateSchedulerConfig(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	cfg.SchedulePolicies = SchedulePolicies{
+		{Name: "grant-leader-scheduler-1", Windows: []string{"01:00-06:00"}},
+		{Name: "grant-leader-scheduler-2", Windows: []string{"02:00-06:00"}},
+	}
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+	tc.addLeaderStore(1, 1)
+	tc.addLeaderStore(2, 1)
+	tc.addLeaderStore(3, 1)
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	co.run()
+	defer co.stop()
+
+	_, err := co.getSchedulerConfig("balance-region-scheduler")
+	c.Assert(err, Equals, errNotConfigurable)
+
+	gls, err := schedule.CreateScheduler("grant-leader", co.limiter, "1")
+	c.Assert(err, IsNil)
+	c.Assert(co.addScheduler(gls, "1"), IsNil)
+	config, err := co.getSchedulerConfig("grant-leader-scheduler-1")
+	c.Assert(err, IsNil)
+	c.Assert(config["store_id"], Equals, uint64(1))
+
+	_, err = co.updateSchedulerConfig("grant-leader-scheduler-1", map[string]interface{}{"store_id": "foo"})
+	c.Assert(err, NotNil)
+
+	// The scheduler is renamed, and the args are updated in the config.
+	name, err := co.updateSchedulerConfig("grant-leader-scheduler-1", map[string]interface{}{"store_id": 2.0})
+	c.Assert(err, IsNil)
+	c.Assert(name, Equals, "grant-leader-scheduler-2")
+	c.Assert(co.schedulers, HasKey, name)
+	c.Assert(co.schedulers, Not(HasKey), "grant-leader-scheduler-1")
+	i, err := findSchedulerCfg(opt.GetSchedulers(), name)
+	c.Assert(err, IsNil)
+	c.Assert(opt.GetSchedulers()[i].Args, DeepEquals, []string{"2"})
+	c.Assert(tc.BlockStore(1), IsNil)
+	// The policy follows the scheduler, and the config is persisted.
+	c.Assert(opt.GetSchedulePolicy("grant-leader-scheduler-1"), IsNil)
+	c.Assert(opt.GetSchedulePolicy(name).Windows, DeepEquals, []string{"01:00-06:00"})
+	persisted := &Config{}
+	_, err = tc.kv.LoadConfig(persisted)
+	c.Assert(err, IsNil)
+	c.Assert(persisted.Schedule.SchedulePolicies, HasLen, 1)
+	i, err = findSchedulerCfg(persisted.Schedule.Schedulers, name)
+	c.Assert(err, IsNil)
+	c.Assert(i, Not(Equals), -1)
+
+	// The scheduler is not renamed to an existing one.
+	gls, err = schedule.CreateScheduler("grant-leader", co.limiter, "3")
+	c.Assert(err, IsNil)
+	c.Assert(co.addScheduler(gls, "3"), IsNil)
+	_, err = co.updateSchedulerConfig(name, map[string]interface{}{"store_id": 3.0})
+	c.Assert(err, Equals, errSchedulerExisted)
+
+	// The config is restored if it fails to be applied.
+	_, err = co.updateSchedulerConfig(name, map[string]interface{}{"store_id": 4.0})
+	c.Assert(err, NotNil)
+	c.Assert(co.schedulers, HasKey, name)
+	i, err = findSchedulerCfg(opt.GetSchedulers(), name)
+	c.Assert(err, IsNil)
+	c.Assert(opt.GetSchedulers()[i].Args, DeepEquals, []string{"2"})
+	c.Assert(opt.GetSchedulePolicy(name), NotNil)
+}
+
+func (s *testCoordinatorSuite) TestDryRunScheduler(c *C) {
+	_, opt := newTestScheduleConfig()
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	co.run()
+	defer co.stop()
+	for _, name := range co.getSchedulers() {
+		c.Assert(co.removeScheduler(name), IsNil)
+	}
+
+	tc.addLeaderStore(1, 0)
+	tc.addLeaderStore(2, 1)
+	tc.addLeaderRegion(1, 2, 1)
+
+	gls, err := schedule.CreateScheduler("grant-leader", co.limiter, "1")
+	c.Assert(err, IsNil)
+	c.Assert(co.addDryRunScheduler(gls, "1"), IsNil)
+	_, err = co.getSchedulerPreviews("foo")
+	c.Assert(err, Equals, errSchedulerNotFound)
+
+	// The operators are kept for preview instead of being added.
+	var previews []*SchedulerPreview
+	testutil.WaitUntil(c, func(c *C) bool {
+		previews, err = co.getSchedulerPreviews(gls.GetName())
+		c.Assert(err, IsNil)
+		return len(previews) > 0
+	})
+	c.Assert(previews[0].Operators[0].RegionID(), Equals, uint64(1))
+	c.Assert(co.getOperator(1), IsNil)
+	// The store is not blocked by the dry-run scheduler.
+	c.Assert(tc.BlockStore(1), IsNil)
+	tc.UnblockStore(1)
+
+	i, err := findSchedulerCfg(opt.GetSchedulers(), gls.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(opt.GetSchedulers()[i].DryRun, IsTrue)
+
+	sls, err := schedule.CreateScheduler("shuffle-leader", co.limiter)
+	c.Assert(err, IsNil)
+	c.Assert(co.addScheduler(sls), IsNil)
+	_, err = co.getSchedulerPreviews("shuffle-leader-scheduler")
+	c.Assert(err, Equals, errNotDryRun)
+
+	// The previews are bounded.
+	p := newSchedulerPreviews()
+	for i := 0; i < schedulerPreviewLimit+10; i++ {
+		p.add(nil, schedule.OpInfluence{uint64(i): &schedule.StoreInfluence{}})
+	}
+	list := p.list()
+	c.Assert(list, HasLen, schedulerPreviewLimit)
+	c.Assert(list[0].OpInfluence, HasKey, uint64(10))
+	c.Assert(list[schedulerPreviewLimit-1].OpInfluence, HasKey, uint64(schedulerPreviewLimit+9))
+}
+
+func (s *testCoordinatorSuite) TestSchedulerTrace(c *C) {
+	_, opt := newTestScheduleConfig()
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	_, err := co.getSchedulerTracer("foo")
+	c.Assert(err, Equals, errSchedulerNotFound)
+
+	// Only the instrumented schedulers are traceable.
+	ls, err := schedule.CreateScheduler("label", co.limiter)
+	c.Assert(err, IsNil)
+	co.schedulers[ls.GetName()] = newScheduleController(co, ls)
+	_, err = co.getSchedulerTracer(ls.GetName())
+	c.Assert(err, Equals, errNotTraceable)
+
+	lb, err := schedule.CreateScheduler("balance-leader", co.limiter)
+	c.Assert(err, IsNil)
+	sc := newScheduleController(co, lb)
+	co.schedulers[sc.GetName()] = sc
+	tracer, err := co.getSchedulerTracer(sc.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(tracer.IsEnabled(), IsFalse)
+
+	// Each attempt of the scheduler is recorded once enabled.
+	tracer.Enable(true)
+	c.Assert(sc.Schedule(tc, schedule.NewOpInfluence(nil, tc)), IsNil)
+	traces := tracer.GetTraces()
+	c.Assert(traces, HasLen, maxScheduleRetries)
+	c.Assert(traces[0].Events[0].Event, Equals, "no-store")
+}
+
+func (s *testCoordinatorSuite) TestSchedulePolicy(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	tc := newTestClusterInfo(opt)
+	hbStreams := newHeartbeatStreams(tc.getClusterID())
+	defer hbStreams.Close()
+
+	co := newCoordinator(tc.clusterInfo, hbStreams, namespace.DefaultClassifier)
+	co.run()
+	defer co.stop()
+
+	name := "balance-region-scheduler"
+	now := time.Now()
+	window := func(from, to time.Duration) string {
+		return fmt.Sprintf("%s-%s", now.Add(from).Format("15:04"), now.Add(to).Format("15:04"))
+	}
+	setPolicies := func(policies ...SchedulePolicy) {
+		cfg.SchedulePolicies = policies
+		opt.store(cfg.clone())
+	}
+	c.Assert(co.schedulers[name].AllowSchedule(), IsTrue)
+
+	// Not allowed outside the windows.
+	setPolicies(SchedulePolicy{Name: name, Windows: []string{window(time.Hour, 2*time.Hour)}})
+	c.Assert(co.schedulers[name].AllowSchedule(), IsFalse)
+
+	// Allowed in a window.
+	setPolicies(SchedulePolicy{Name: name, Windows: []string{window(time.Hour, 2*time.Hour), window(-time.Hour, time.Hour)}})
+	c.Assert(co.schedulers[name].AllowSchedule(), IsTrue)
+
+	// Capped by the outside limit.
+	setPolicies(SchedulePolicy{Name: name, Windows: []string{window(time.Hour, 2*time.Hour)}, OutsideLimit: 1})
+	c.Assert(co.schedulers[name].AllowSchedule(), IsTrue)
+	c.Assert(co.limiter.OperatorCount(schedule.OpRegion), Equals, uint64(0))
+	co.limiter.UpdateCounts(map[uint64]*schedule.Operator{1: newTestOperator(1, schedule.OpRegion)})
+	c.Assert(co.schedulers[name].AllowSchedule(), IsFalse)
+	co.limiter.UpdateCounts(nil)
+
+	statuses := co.getSchedulerStatuses()
+	for _, status := range statuses {
+		if status.Name == name {
+			c.Assert(status.Policy, NotNil)
+			c.Assert(status.InWindow, IsFalse)
+		} else {
+			c.Assert(status.Policy, IsNil)
+			c.Assert(status.InWindow, IsTrue)
+		}
+	}
+
+	// Schedulers with limits of their own are capped too.
+	ar, err := schedule.CreateScheduler("adjacent-region", co.limiter)
+	c.Assert(err, IsNil)
+	sc := newScheduleController(co, ar)
+	co.limiter.UpdateCounts(map[uint64]*schedule.Operator{1: newTestOperator(1, schedule.OpAdjacent|schedule.OpLeader)})
+	c.Assert(sc.AllowSchedule(), IsTrue)
+	setPolicies(SchedulePolicy{Name: ar.GetName(), Windows: []string{window(time.Hour, 2*time.Hour)}, OutsideLimit: 1})
+	c.Assert(sc.AllowSchedule(), IsFalse)
+	co.limiter.UpdateCounts(nil)
+	c.Assert(sc.AllowSchedule(), IsTrue)
+
+	// Checkers.
+	setPolicies(
+		SchedulePolicy{Name: replicaCheckerName, Windows: []string{window(time.Hour, 2*time.Hour)}, OutsideLimit: 2},
+		SchedulePolicy{Name: mergeCheckerName, Windows: []string{window(-time.Hour, time.Hour)}},
+	)
+	c.Assert(co.getCheckerLimit(replicaCheckerName, 32, now), Equals, uint64(2))
+	c.Assert(co.getCheckerLimit(mergeCheckerName, 20, now), Equals, uint64(20))
+}
@@ -64,6 +64,30 @@ func (kv *KV) storeRegionWeightPath(storeID uint64) string {
 	return path.Join(schedulePath, "store_weight", fmt.Sprintf("%020d", storeID), "region")
 }
 
+func (kv *KV) storeWeightRampPath(storeID uint64, kind ResourceKind) string {
+	return path.Join(schedulePath, "store_weight_ramp", fmt.Sprintf("%020d", storeID), kind.String())
+}
+
+func (kv *KV) storeRegionSizeSoftLimitPath(storeID uint64) string {
+	return path.Join(schedulePath, "store_region_size_limit", fmt.Sprintf("%020d", storeID), "soft")
+}
+
+func (kv *KV) storeRegionSizeHardLimitPath(storeID uint64) string {
+	return path.Join(schedulePath, "store_region_size_limit", fmt.Sprintf("%020d", storeID), "hard")
+}
+
+func (kv *KV) storeLimitPath(storeID uint64, typ StoreLimitType) string {
+	return path.Join(schedulePath, "store_limit", fmt.Sprintf("%020d", storeID), typ.String())
+}
+
+func (kv *KV) operatorPath(regionID uint64) string {
+	return path.Join(schedulePath, "operator", fmt.Sprintf("%020d", regionID))
+}
+
+func (kv *KV) operatorRecordPath(seq uint64) string {
+	return path.Join(schedulePath, "operator_history", fmt.Sprintf("%020d", seq))
+}
+
 // LoadMeta loads cluster meta from KV store.
 func (kv *KV) LoadMeta(meta *metapb.Cluster) (bool, error) {
 	return kv.loadProto(clusterPath, meta)
@@ -127,6 +151,7 @@ func (kv *KV) LoadConfig(cfg interface{}) (bool, error) {
 // LoadStores loads all stores from KV to StoresInfo.
 func (kv *KV) LoadStores(stores *StoresInfo, rangeLimit int) error {
 	nextID := uint64(0)
+	var ramps []*StoreInfo
 	endKey := kv.storePath(math.MaxUint64)
 	for {
 		key := kv.storePath(nextID)
@@ -140,24 +165,64 @@ func (kv *KV) LoadStores(stores *StoresInfo, rangeLimit int) error {
 				return errors.Trace(err)
 			}
 			storeInfo := NewStoreInfo(store)
-			leaderWeight, err := kv.loadFloatWithDefaultValue(kv.storeLeaderWeightPath(storeInfo.GetId()), 1.0)
+			leaderWeight, ok, err := kv.loadFloat(kv.storeLeaderWeightPath(storeInfo.GetId()))
 			if err != nil {
 				return errors.Trace(err)
 			}
-			storeInfo.LeaderWeight = leaderWeight
-			regionWeight, err := kv.loadFloatWithDefaultValue(kv.storeRegionWeightPath(storeInfo.GetId()), 1.0)
+			if ok {
+				storeInfo.SetWeight(LeaderKind, WeightSourceAPI, leaderWeight)
+			}
+			regionWeight, ok, err := kv.loadFloat(kv.storeRegionWeightPath(storeInfo.GetId()))
 			if err != nil {
 				return errors.Trace(err)
 			}
-			storeInfo.RegionWeight = regionWeight
+			if ok {
+				storeInfo.SetWeight(RegionKind, WeightSourceAPI, regionWeight)
+			}
+			softLimit, err := kv.loadIntWithDefaultValue(kv.storeRegionSizeSoftLimitPath(storeInfo.GetId()), 0)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			storeInfo.RegionSizeSoftLimit = softLimit
+			hardLimit, err := kv.loadIntWithDefaultValue(kv.storeRegionSizeHardLimitPath(storeInfo.GetId()), 0)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			storeInfo.RegionSizeHardLimit = hardLimit
+			if storeInfo.AddPeerRate, _, err = kv.loadFloat(kv.storeLimitPath(storeInfo.GetId(), StoreAddPeer)); err != nil {
+				return errors.Trace(err)
+			}
+			if storeInfo.RemovePeerRate, _, err = kv.loadFloat(kv.storeLimitPath(storeInfo.GetId(), StoreRemovePeer)); err != nil {
+				return errors.Trace(err)
+			}
+			leaderRamp, err := kv.loadWeightRamp(storeInfo.GetId(), LeaderKind)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			regionRamp, err := kv.loadWeightRamp(storeInfo.GetId(), RegionKind)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if leaderRamp != nil || regionRamp != nil {
+				ramps = append(ramps, &StoreInfo{Store: store, LeaderWeightRamp: leaderRamp, RegionWeightRamp: regionRamp})
+			}
 
 			nextID = store.GetId() + 1
 			stores.SetStore(storeInfo)
 		}
 		if len(res) < rangeLimit {
-			return nil
+			break
 		}
 	}
+
+	// Resume the weight ramps after all stores are loaded, since the weights
+	// derived by the weight policy depend on the other stores.
+	for _, r := range ramps {
+		store := stores.GetStore(r.GetId())
+		store.LeaderWeightRamp, store.RegionWeightRamp = r.LeaderWeightRamp, r.RegionWeightRamp
+		stores.SetStore(store)
+	}
+	return nil
 }
 
 // SaveStoreWeight saves a store's leader and region weight to KV.
@@ -173,8 +238,127 @@ func (kv *KV) SaveStoreWeight(storeID uint64, leader, region float64) error {
 	return nil
 }
 
-func (kv *KV) loadFloatWithDefaultValue(path string, def float64) (float64, error) {
-	//println("loadFloatWithDefaultValue path: %s", path)	// wyy add
+// DeleteStoreWeight deletes a store's leader and region weight from KV.
+func (kv *KV) DeleteStoreWeight(storeID uint64) error {
+	if err := kv.Delete(kv.storeLeaderWeightPath(storeID)); err != nil {
+		return errors.Trace(err)
+	}
+	if err := kv.Delete(kv.storeRegionWeightPath(storeID)); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// SaveStoreWeightRamp saves a store's leader or region weight ramp to KV. A
+// nil ramp is deleted.
+func (kv *KV) SaveStoreWeightRamp(storeID uint64, kind ResourceKind, ramp *WeightRamp) error {
+	key := kv.storeWeightRampPath(storeID, kind)
+	if ramp == nil {
+		return errors.Trace(kv.Delete(key))
+	}
+	value, err := json.Marshal(ramp)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(kv.Save(key, string(value)))
+}
+
+func (kv *KV) loadWeightRamp(storeID uint64, kind ResourceKind) (*WeightRamp, error) {
+	value, err := kv.Load(kv.storeWeightRampPath(storeID, kind))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if value == "" {
+		return nil, nil
+	}
+	ramp := &WeightRamp{}
+	if err := json.Unmarshal([]byte(value), ramp); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return ramp, nil
+}
+
+// SaveStoreRegionSizeLimit saves a store's soft and hard region size limit to KV.
+func (kv *KV) SaveStoreRegionSizeLimit(storeID uint64, soft, hard int64) error {
+	if err := kv.Save(kv.storeRegionSizeSoftLimitPath(storeID), strconv.FormatInt(soft, 10)); err != nil {
+		return errors.Trace(err)
+	}
+	if err := kv.Save(kv.storeRegionSizeHardLimitPath(storeID), strconv.FormatInt(hard, 10)); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// SaveStoreLimit saves a store's add-peer and remove-peer rates to KV.
+func (kv *KV) SaveStoreLimit(storeID uint64, addPeer, removePeer float64) error {
+	if err := kv.Save(kv.storeLimitPath(storeID, StoreAddPeer), strconv.FormatFloat(addPeer, 'f', -1, 64)); err != nil {
+		return errors.Trace(err)
+	}
+	if err := kv.Save(kv.storeLimitPath(storeID, StoreRemovePeer), strconv.FormatFloat(removePeer, 'f', -1, 64)); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+// SaveOperator saves the operator of a region to KV.
+func (kv *KV) SaveOperator(regionID uint64, op interface{}) error {
+	value, err := json.Marshal(op)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(kv.Save(kv.operatorPath(regionID), string(value)))
+}
+
+// DeleteOperator deletes the operator of a region from KV.
+func (kv *KV) DeleteOperator(regionID uint64) error {
+	return errors.Trace(kv.Delete(kv.operatorPath(regionID)))
+}
+
+// LoadOperators loads up to limit operators saved by SaveOperator, in JSON.
+func (kv *KV) LoadOperators(limit int) ([]string, error) {
+	values, err := kv.LoadRange(kv.operatorPath(0), kv.operatorPath(math.MaxUint64), limit)
+	return values, errors.Trace(err)
+}
+
+// SaveOperatorRecord saves a record of the operator history with its
+// sequence in JSON.
+func (kv *KV) SaveOperatorRecord(seq uint64, record interface{}) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(kv.Save(kv.operatorRecordPath(seq), string(value)))
+}
+
+// DeleteOperatorRecord deletes the record of the sequence from the operator
+// history.
+func (kv *KV) DeleteOperatorRecord(seq uint64) error {
+	return errors.Trace(kv.Delete(kv.operatorRecordPath(seq)))
+}
+
+// LoadOperatorRecords loads up to limit records of the operator history from
+// the sequence, in JSON and ordered by the sequences.
+func (kv *KV) LoadOperatorRecords(seq uint64, limit int) ([]string, error) {
+	values, err := kv.LoadRange(kv.operatorRecordPath(seq), kv.operatorRecordPath(math.MaxUint64), limit)
+	return values, errors.Trace(err)
+}
+
+func (kv *KV) loadFloat(path string) (float64, bool, error) {
+	res, err := kv.Load(path)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	if res == "" {
+		return 0, false, nil
+	}
+	val, err := strconv.ParseFloat(res, 64)
+	if err != nil {
+		return 0, false, errors.Trace(err)
+	}
+	return val, true, nil
+}
+
+func (kv *KV) loadIntWithDefaultValue(path string, def int64) (int64, error) {
 	res, err := kv.Load(path)
 	if err != nil {
 		return 0, errors.Trace(err)
@@ -182,7 +366,7 @@ func (kv *KV) loadFloatWithDefaultValue(path string, def float64) (float64, erro
 	if res == "" {
 		return def, nil
 	}
-	val, err := strconv.ParseFloat(res, 64)
+	val, err := strconv.ParseInt(res, 10, 64)
 	if err != nil {
 		return 0, errors.Trace(err)
 	}
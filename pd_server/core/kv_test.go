@@ -15,6 +15,7 @@ package core
 
 import (
 	"fmt"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -109,9 +110,61 @@ func (s *testKVSuite) TestStoreWeight(c *C) {
 	c.Assert(kv.LoadStores(cache, n), IsNil)
 	leaderWeights := []float64{1.0, 2.0, 0.2}
 	regionWeights := []float64{1.0, 3.0, 0.3}
+	sources := []WeightSource{WeightSourceDefault, WeightSourceAPI, WeightSourceAPI}
 	for i := 0; i < n; i++ {
 		c.Assert(cache.GetStore(uint64(i)).LeaderWeight, Equals, leaderWeights[i])
 		c.Assert(cache.GetStore(uint64(i)).RegionWeight, Equals, regionWeights[i])
+		c.Assert(cache.GetStore(uint64(i)).LeaderWeightSource, Equals, sources[i])
+		c.Assert(cache.GetStore(uint64(i)).RegionWeightSource, Equals, sources[i])
+	}
+
+	c.Assert(kv.DeleteStoreWeight(1), IsNil)
+	cache = NewStoresInfo()
+	c.Assert(kv.LoadStores(cache, n), IsNil)
+	c.Assert(cache.GetStore(1).LeaderWeight, Equals, DefaultWeight)
+	c.Assert(cache.GetStore(1).LeaderWeightSource, Equals, WeightSourceDefault)
+}
+
+func (s *testKVSuite) TestStoreWeightRamp(c *C) {
+	kv := NewKV(NewMemoryKV())
+	cache := NewStoresInfo()
+	const n = 3
+
+	mustSaveStores(c, kv, n)
+	ramp := &WeightRamp{From: 1, To: 2, StartTime: time.Now(), Duration: time.Hour}
+	c.Assert(kv.SaveStoreWeight(1, 1, 2), IsNil)
+	c.Assert(kv.SaveStoreWeightRamp(1, RegionKind, ramp), IsNil)
+	// The ramp of a changed weight is discarded.
+	c.Assert(kv.SaveStoreWeightRamp(2, RegionKind, ramp), IsNil)
+	c.Assert(kv.LoadStores(cache, n), IsNil)
+
+	store := cache.GetStore(1)
+	c.Assert(store.RegionWeightRamp.To, Equals, 2.0)
+	c.Assert(store.RegionWeight < 1.01, IsTrue)
+	c.Assert(store.LeaderWeightRamp, IsNil)
+	c.Assert(cache.GetStore(2).RegionWeightRamp, IsNil)
+
+	c.Assert(kv.SaveStoreWeightRamp(1, RegionKind, nil), IsNil)
+	cache = NewStoresInfo()
+	c.Assert(kv.LoadStores(cache, n), IsNil)
+	c.Assert(cache.GetStore(1).RegionWeightRamp, IsNil)
+	c.Assert(cache.GetStore(1).RegionWeight, Equals, 2.0)
+}
+
+func (s *testKVSuite) TestStoreRegionSizeLimit(c *C) {
+	kv := NewKV(NewMemoryKV())
+	cache := NewStoresInfo()
+	const n = 3
+
+	mustSaveStores(c, kv, n)
+	c.Assert(kv.SaveStoreRegionSizeLimit(1, 6144, 8192), IsNil)
+	c.Assert(kv.SaveStoreRegionSizeLimit(2, 0, 1024), IsNil)
+	c.Assert(kv.LoadStores(cache, n), IsNil)
+	softLimits := []int64{0, 6144, 0}
+	hardLimits := []int64{0, 8192, 1024}
+	for i := 0; i < n; i++ {
+		c.Assert(cache.GetStore(uint64(i)).RegionSizeSoftLimit, Equals, softLimits[i])
+		c.Assert(cache.GetStore(uint64(i)).RegionSizeHardLimit, Equals, hardLimits[i])
 	}
 }
 
@@ -218,6 +218,11 @@ type HotRegionsStat struct {
 	TotalFlowBytes uint64      `json:"total_flow_bytes"`
 	RegionsCount   int         `json:"regions_count"`
 	RegionsStat    RegionsStat `json:"statistics"`
+	// Weight is the store weight the flow bytes and regions count are
+	// normalized by.
+	Weight                 float64 `json:"weight"`
+	NormalizedFlowBytes    float64 `json:"normalized_flow_bytes"`
+	NormalizedRegionsCount float64 `json:"normalized_regions_count"`
 }
 
 // regionMap wraps a map[uint64]*core.RegionInfo and supports randomly pick a region.
@@ -17,7 +17,6 @@ import (
 	"math"
 	"strings"
 	"time"
-	"strconv"	// wyy add	
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/juju/errors"
@@ -40,14 +39,44 @@ type StoreInfo struct {
 	LastHeartbeatTS  time.Time
 	LeaderWeight     float64
 	RegionWeight     float64
+	// LeaderWeights and RegionWeights are the candidate weights of the store
+	// by source, LeaderWeight and RegionWeight are resolved from them.
+	LeaderWeights      WeightCandidates
+	RegionWeights      WeightCandidates
+	LeaderWeightSource WeightSource
+	RegionWeightSource WeightSource
+	// LeaderWeightRamp and RegionWeightRamp move the weights toward their
+	// resolved values gradually. Nil means no ramp in progress.
+	LeaderWeightRamp *WeightRamp
+	RegionWeightRamp *WeightRamp
+	// RegionSizeSoftLimit and RegionSizeHardLimit limit the store's region
+	// size in MB. Zero means no limit.
+	RegionSizeSoftLimit int64
+	RegionSizeHardLimit int64
+	// RegionWeightMode is where the region weight comes from.
+	RegionWeightMode string
+	// AdaptiveLeaderFactor is multiplied to LeaderWeight by the adaptive
+	// leader weight controller. Zero means 1.
+	AdaptiveLeaderFactor float64
+	// SpaceFactor is multiplied to the region score by the used space of the
+	// store. Zero means 1.
+	SpaceFactor float64
+	// AddPeerRate and RemovePeerRate override the default number of peers
+	// added to and removed from the store per minute. Zero means default.
+	AddPeerRate    float64
+	RemovePeerRate float64
+	// limits are the token buckets of the store limits, shared by the clones.
+	limits map[StoreLimitType]*StoreLimit
 }
 
 // NewStoreInfo creates StoreInfo with meta data.
 func NewStoreInfo(store *metapb.Store) *StoreInfo {
 	return &StoreInfo{
-		Store:        store,
-		LeaderWeight: 1.0,
-		RegionWeight: 1.0,
+		Store:              store,
+		LeaderWeight:       DefaultWeight,
+		RegionWeight:       DefaultWeight,
+		LeaderWeightSource: WeightSourceDefault,
+		RegionWeightSource: WeightSourceDefault,
 	}
 }
 
@@ -65,7 +94,57 @@ func (s *StoreInfo) Clone() *StoreInfo {
 		LastHeartbeatTS:  s.LastHeartbeatTS,
 		LeaderWeight:     s.LeaderWeight,
 		RegionWeight:     s.RegionWeight,
+
+		LeaderWeights:      s.LeaderWeights.clone(),
+		RegionWeights:      s.RegionWeights.clone(),
+		LeaderWeightSource: s.LeaderWeightSource,
+		RegionWeightSource: s.RegionWeightSource,
+		LeaderWeightRamp:   s.LeaderWeightRamp,
+		RegionWeightRamp:   s.RegionWeightRamp,
+
+		RegionSizeSoftLimit: s.RegionSizeSoftLimit,
+		RegionSizeHardLimit: s.RegionSizeHardLimit,
+		RegionWeightMode:    s.RegionWeightMode,
+
+		AdaptiveLeaderFactor: s.AdaptiveLeaderFactor,
+		SpaceFactor:          s.SpaceFactor,
+
+		AddPeerRate:    s.AddPeerRate,
+		RemovePeerRate: s.RemovePeerRate,
+		limits:         s.limits,
+	}
+}
+
+// GetStoreLimitRate returns the rate of the limit type overridden by the
+// store, or zero if not overridden.
+func (s *StoreInfo) GetStoreLimitRate(typ StoreLimitType) float64 {
+	if typ == StoreRemovePeer {
+		return s.RemovePeerRate
+	}
+	return s.AddPeerRate
+}
+
+// GetStoreLimit returns the token bucket of the limit type, or nil if the
+// store is not limited.
+func (s *StoreInfo) GetStoreLimit(typ StoreLimitType) *StoreLimit {
+	return s.limits[typ]
+}
+
+// SetStoreLimit sets the token bucket of the limit type.
+func (s *StoreInfo) SetStoreLimit(typ StoreLimitType, limit *StoreLimit) {
+	limits := make(map[StoreLimitType]*StoreLimit, len(s.limits)+1)
+	for t, l := range s.limits {
+		limits[t] = l
 	}
+	limits[typ] = limit
+	s.limits = limits
+}
+
+// IsAvailable checks if the store has the tokens for an operation of the limit
+// type.
+func (s *StoreInfo) IsAvailable(typ StoreLimitType) bool {
+	limit := s.GetStoreLimit(typ)
+	return limit == nil || limit.Available() >= 1
 }
 
 // Block stops balancer from selecting the store.
@@ -105,6 +184,12 @@ func (s *StoreInfo) DownTime() time.Duration {
 
 const minWeight = 1e-6
 
+// Label keys used to specify the region size limits of a store.
+const (
+	RegionSizeSoftLimitLabel = "MaxRegionSizeSoft"
+	RegionSizeHardLimitLabel = "MaxRegionSizeHard"
+)
+
 // LeaderScore returns the store's leader score: leaderCount / leaderWeight.
 func (s *StoreInfo) LeaderScore() float64 {
 	// wyy add
@@ -112,18 +197,13 @@ func (s *StoreInfo) LeaderScore() float64 {
 	//	s.LeaderWeight, _ = strconv.ParseFloat(s.Store.GetLabels()[0].GetValue(),32)
 	//} 
 	//log.Infof("StoreInfo.Store: %s length: %d, s.LeaderSize: %d, s.LeaderWeight: %f", s.Store.GetLabels(), len(s.Store.GetLabels()), s.LeaderSize, s.LeaderWeight)	// wyy add
-	return float64(s.LeaderSize) / math.Max(s.LeaderWeight, minWeight)
+	return float64(s.LeaderSize) / s.ResourceWeight(LeaderKind)
 }
 
-// RegionScore returns the store's region score: regionSize / regionWeight.
+// RegionScore returns the store's region score: regionSize / regionWeight,
+// where the weight is reduced by the space factor.
 func (s *StoreInfo) RegionScore() float64 {
-	//log.Infof("id: %v, LeaderSize: %v, LeaderCount: %v, RegionSize: %v, RegionCount: %v, LeaderWeight: %v, RegionWeight: %v", s.Store.GetId(), s.LeaderSize, s.LeaderCount, s.RegionSize, s.RegionCount, s.LeaderWeight, s.RegionWeight)	
-	//log.Infof("s.LeaderWeight: %v, s.RegionWeight: %v", s.LeaderWeight, s.RegionWeight)	
-	// wyy add. Region size max is : 8G=8192M
-	//if s.RegionSize >= 8192 {
-	//	return float64(s.RegionSize) / minWeight
-	//}
-	return float64(s.RegionSize) / math.Max(s.RegionWeight, minWeight)
+	return float64(s.RegionSize) / s.ResourceWeight(RegionKind)
 }
 
 // StorageSize returns store's used storage size reported from tikv.
@@ -141,6 +221,28 @@ func (s *StoreInfo) AvailableRatio() float64 {
 
 const storeLowSpaceThreshold = 0.01
 
+// minSpaceAvailableRatio bounds the space factor of a full store.
+const minSpaceAvailableRatio = 0.001
+
+// ComputeSpaceFactor returns the factor of the region score by the used
+// space ratio of the store. It is 1 below highSpaceRatio, grows linearly to
+// 2 at highWaterMark, and then grows inversely to the available ratio, so
+// the score rises steeply as the store is nearly full.
+func (s *StoreInfo) ComputeSpaceFactor(highSpaceRatio, highWaterMark float64) float64 {
+	if s.Stats.GetCapacity() == 0 || highWaterMark <= highSpaceRatio {
+		return 1
+	}
+	used := 1 - s.AvailableRatio()
+	switch {
+	case used <= highSpaceRatio:
+		return 1
+	case used <= highWaterMark:
+		return 1 + (used-highSpaceRatio)/(highWaterMark-highSpaceRatio)
+	default:
+		return 2 * (1 - highWaterMark) / math.Max(1-used, minSpaceAvailableRatio)
+	}
+}
+
 // IsLowSpace checks if the store is lack of space.
 func (s *StoreInfo) IsLowSpace() bool {
 	return s.Stats != nil && s.AvailableRatio() < storeLowSpaceThreshold //&& s.RegionSize >= 7680 // wyy add "&& s.RegionSize >= 7680"
@@ -186,20 +288,89 @@ func (s *StoreInfo) ResourceScore(kind ResourceKind) float64 {
 func (s *StoreInfo) ResourceWeight(kind ResourceKind) float64 {
 	switch kind {
 	case LeaderKind:
-		if s.LeaderWeight <= 0 {
-			return minWeight
+		weight := s.LeaderWeight
+		if s.AdaptiveLeaderFactor > 0 {
+			weight *= s.AdaptiveLeaderFactor
 		}
-		return s.LeaderWeight
+		return math.Max(weight, minWeight)
 	case RegionKind:
-		if s.RegionWeight <= 0 {
+		// Regions are moved out of a store which exceeds its soft limit.
+		if s.RegionWeight <= 0 || s.IsOverRegionSizeSoftLimit() {
 			return minWeight
 		}
+		if s.SpaceFactor > 1 {
+			return math.Max(s.RegionWeight/s.SpaceFactor, minWeight)
+		}
 		return s.RegionWeight
 	default:
 		return 0
 	}
 }
 
+// SetWeight sets the leader or region weight provided by the source. The
+// store's weight is resolved when it is put into StoresInfo.
+func (s *StoreInfo) SetWeight(kind ResourceKind, source WeightSource, weight float64) {
+	switch kind {
+	case LeaderKind:
+		if s.LeaderWeights == nil {
+			s.LeaderWeights = make(WeightCandidates)
+		}
+		s.LeaderWeights[source] = weight
+	case RegionKind:
+		if s.RegionWeights == nil {
+			s.RegionWeights = make(WeightCandidates)
+		}
+		s.RegionWeights[source] = weight
+	}
+}
+
+// ClearWeight removes the leader or region weight provided by the source.
+func (s *StoreInfo) ClearWeight(kind ResourceKind, source WeightSource) {
+	switch kind {
+	case LeaderKind:
+		delete(s.LeaderWeights, source)
+	case RegionKind:
+		delete(s.RegionWeights, source)
+	}
+}
+
+// CancelWeightRamps stops the weight ramps of the store, and sets its weights
+// to their targets at once.
+func (s *StoreInfo) CancelWeightRamps() {
+	s.LeaderWeight, s.LeaderWeightSource = s.LeaderWeights.Resolve()
+	s.RegionWeight, s.RegionWeightSource = s.RegionWeights.Resolve()
+	s.LeaderWeightRamp, s.RegionWeightRamp = nil, nil
+}
+
+// ResolveWeights resolves the store's leader and region weight at once. A
+// changed weight reaches its new value in rampDuration. It returns true if a
+// weight ramp is started or stopped.
+func (s *StoreInfo) ResolveWeights(rampDuration time.Duration) bool {
+	return s.resolveWeights(time.Now(), rampDuration)
+}
+
+// resolveWeights sets the store's leader and region weight toward the
+// candidates with the highest precedence. It returns true if a weight ramp is
+// started or stopped.
+func (s *StoreInfo) resolveWeights(now time.Time, rampDuration time.Duration) bool {
+	leaderRamp, regionRamp := s.LeaderWeightRamp, s.RegionWeightRamp
+	s.LeaderWeight, s.LeaderWeightSource, s.LeaderWeightRamp = resolveWeight(s.LeaderWeights, s.LeaderWeight, s.LeaderWeightRamp, now, rampDuration)
+	s.RegionWeight, s.RegionWeightSource, s.RegionWeightRamp = resolveWeight(s.RegionWeights, s.RegionWeight, s.RegionWeightRamp, now, rampDuration)
+	return s.LeaderWeightRamp != leaderRamp || s.RegionWeightRamp != regionRamp
+}
+
+// IsOverRegionSizeSoftLimit checks if the store's region size exceeds its
+// soft limit.
+func (s *StoreInfo) IsOverRegionSizeSoftLimit() bool {
+	return s.RegionSizeSoftLimit > 0 && s.RegionSize >= s.RegionSizeSoftLimit
+}
+
+// IsOverRegionSizeHardLimit checks if the store's region size exceeds its
+// hard limit.
+func (s *StoreInfo) IsOverRegionSizeHardLimit() bool {
+	return s.RegionSizeHardLimit > 0 && s.RegionSize >= s.RegionSizeHardLimit
+}
+
 // GetStartTS returns the start timestamp.
 func (s *StoreInfo) GetStartTS() time.Time {
 	return time.Unix(int64(s.Stats.GetStartTime()), 0)
@@ -295,13 +466,19 @@ var (
 
 // StoresInfo is a map of storeID to StoreInfo
 type StoresInfo struct {
-	stores map[uint64]*StoreInfo
+	stores       map[uint64]*StoreInfo
+	weightPolicy WeightPolicy
+	rampDuration time.Duration
+	// rampUpdates is the stores whose weight ramps are started or stopped.
+	rampUpdates map[uint64]struct{}
 }
 
 // NewStoresInfo create a StoresInfo with map of storeID to StoreInfo
 func NewStoresInfo() *StoresInfo {
 	return &StoresInfo{
-		stores: make(map[uint64]*StoreInfo),
+		stores:       make(map[uint64]*StoreInfo),
+		weightPolicy: rankExponentialWeightPolicy{},
+		rampUpdates:  make(map[uint64]struct{}),
 	}
 }
 
@@ -314,161 +491,63 @@ func (s *StoresInfo) GetStore(storeID uint64) *StoreInfo {
 	return store.Clone()
 }
 
-// wyy add
-func mi(i int) float64 {
-	var result float64
-	result = 1e-5
-	for j := 0; j < i; j++ {
-		result = result * 1e4
-	}
-	return result
-}
-
 // SetStore set a StoreInfo with storeID
 func (s *StoresInfo) SetStore(store *StoreInfo) {
+	old := s.stores[store.GetId()]
 	s.stores[store.GetId()] = store
-
-	/*
-	// wyy add
-	// balance leader weight : 1 + log(x/min)
-	min := 0.0
-	// find min exclude not LeaderWeight
-	for _, s1 := range s.stores {
-		LeaderWeight := s1.GetLabelValue("LeaderWeight")
-		if LeaderWeight != "" {
-			s1.LeaderWeight, _ = strconv.ParseFloat(LeaderWeight, 32)
-			if min < 1 {
-				min = s1.LeaderWeight
-			}
-			if min > s1.LeaderWeight {
-				min = s1.LeaderWeight
-			}
-		} else {
-			//s1.LeaderWeight = 1
-			continue
-		}
+	s.updateWeights()
+	if old == nil {
+		old = &StoreInfo{}
 	}
-	// balance LeaderWeight
-	for index, s1 := range s.stores {
-		LeaderWeight := s1.GetLabelValue("LeaderWeight")
-		if LeaderWeight != "" {
-			s1.LeaderWeight, _ = strconv.ParseFloat(LeaderWeight, 32)
-			//s.stores[index].LeaderWeight = 1 + math.Log10(s1.LeaderWeight/min) // method1. change LeaderWeight
-			//s.stores[index].LeaderWeight = 1 + ((s1.LeaderWeight-min)/min)/10 // method2. change LeaderWeight
-			s.stores[index].LeaderWeight = s.stores[index].LeaderWeight  // w8M_r64K and original
-			//s.stores[index].LeaderWeight = s.stores[index].RegionWeight // LeaderWeight = RegionWeight
-			//s.stores[index].LeaderWeight = 1  // original. All is 1
-			//log.Infof("id: %v, LeaderWeight: %v", s1.GetId(), s1.LeaderWeight)
-		} else {
-			//s1.LeaderWeight = 1
-			continue
-		}
-	}*/
-
-	// wyy add
-	// balance region weight : 1 + log(x/min)
-	min := 0.0
-	flag := 0
-	// find min exclude not RegionWeight
-	for _, s1 := range s.stores {
-		if(s1.RegionWeight == minWeight) {
-			flag = 1
-			break
-		}
-		RegionWeight := s1.GetLabelValue("RegionWeight")
-		if RegionWeight != "" {
-			s1.RegionWeight, _ = strconv.ParseFloat(RegionWeight, 32)
-			if min < 1 {
-				min = s1.RegionWeight
-			}
-			if min > s1.RegionWeight {
-				min = s1.RegionWeight
-			}
-		} else {
-			//s1.RegionWeight = 1
-			continue
-		}
+	if store.LeaderWeightRamp != old.LeaderWeightRamp || store.RegionWeightRamp != old.RegionWeightRamp {
+		s.rampUpdates[store.GetId()] = struct{}{}
 	}
-	
-	// wyy add.Sort RegionWeight to decide LeaderWeight
-	m := make(map[uint64]int)
-	if(flag == 0) {
-		for index, s1 := range s.stores {
-                	RegionWeight := s1.GetLabelValue("RegionWeight")
-                	if RegionWeight != "" {
-                	        z := 0
-                	        for _, s2 := range s.stores {
-                        	        if s1.RegionWeight > s2.RegionWeight {
-                                	        z++
-                               		}       
-                        	}       
-                       		m[index] = z
-                	} else {
-                       		continue
-                	}       
-       		}       
-        	for index, z := range m {
-                	s.stores[index].LeaderWeight = mi(z)
-			//s.stores[index].RegionWeight = mi(z)
-        	}
-	}
-  
-	
-	// balance RegionWeight
-	for index, s1 := range s.stores {
-		if(flag == 1) {
-			break
-		}
-		RegionWeight := s1.GetLabelValue("RegionWeight")
-		if RegionWeight != "" {
-			s1.RegionWeight, _ = strconv.ParseFloat(RegionWeight, 32)
-			//s.stores[index].RegionWeight = 1 + math.Log10(s1.RegionWeight/min) // method1. change RegionWeight
-			//s.stores[index].RegionWeight = 1 + ((s1.RegionWeight-min)/min)/10 // method2. change RegionWeight
-			//s.stores[index].RegionWeight = 1 + math.Log2(s1.RegionWeight/min) // method3. change RegionWeight
-			//s.stores[index].RegionWeight = 1 + math.Log(s1.RegionWeight/min) / math.Log(3) // method4. change RegionWeight
-			s.stores[index].RegionWeight = s.stores[index].RegionWeight // w8M_r64K
-			//s.stores[index].RegionWeight = s.stores[index].RegionWeight * 0.0127 + 7529.4 // fit
-			//s.stores[index].RegionWeight = 1 // original. All is 1
-			//log.Infof("id: %v, RegionWeight: %v", s1.GetId(), s1.RegionWeight)
-		} else {
-			//s1.RegionWeight = 1
-			continue
+}
+
+// GetWeightPolicy returns the policy used to derive the stores' weights.
+func (s *StoresInfo) GetWeightPolicy() WeightPolicy {
+	return s.weightPolicy
+}
+
+// SetWeightPolicy changes the policy used to derive the stores' weights, and
+// applies it to the existing stores.
+func (s *StoresInfo) SetWeightPolicy(policy WeightPolicy) {
+	s.weightPolicy = policy
+	s.updateWeights()
+}
+
+// SetWeightRampDuration sets the duration for the store weights to reach
+// their new values when they change. Zero means changing at once.
+func (s *StoresInfo) SetWeightRampDuration(d time.Duration) {
+	s.rampDuration = d
+}
+
+// TakeWeightRampUpdates returns the stores whose weight ramps are started or
+// stopped since the last call.
+func (s *StoresInfo) TakeWeightRampUpdates() []*StoreInfo {
+	stores := make([]*StoreInfo, 0, len(s.rampUpdates))
+	for id := range s.rampUpdates {
+		if store, ok := s.stores[id]; ok {
+			stores = append(stores, store.Clone())
 		}
+		delete(s.rampUpdates, id)
 	}
+	return stores
+}
 
-	/*// wyy add.Sort LeaderWeight
-	m := make(map[uint64]int)
-	for index, s1 := range s.stores {
-		LeaderWeight := s1.GetLabelValue("LeaderWeight")
-		if LeaderWeight != "" {
-			z := 0
-			for _, s2 := range s.stores {
-				if s1.LeaderWeight > s2.LeaderWeight {
-					z++
-				}
-			}
-			m[index] = z
-		} else {
-			continue
-		}
+// updateWeights derives the label candidates of the stores by the weight
+// policy, and resolves the stores' weights.
+func (s *StoresInfo) updateWeights() {
+	for _, store := range s.stores {
+		delete(store.LeaderWeights, WeightSourceLabel)
+		delete(store.RegionWeights, WeightSourceLabel)
 	}
-	for index, z := range m {
-		s.stores[index].LeaderWeight = mi(z)
-	}*/
-	
-
-	// wyy add. Region size max is : 8G=8192M
-	for index, s1 := range s.stores {
-		if s1.RegionSize >= 6144 {
-			s.stores[index].RegionWeight = minWeight
-		} //else {
-		//	RegionWeight := s1.GetLabelValue("RegionWeight")
-		//	if RegionWeight != "" {
-		//		s.stores[index].RegionWeight, _ = strconv.ParseFloat(RegionWeight, 32)
-		//	} else {
-		//		s.stores[index].RegionWeight = 1
-		//	}
-		//}
+	s.weightPolicy.Apply(s.stores)
+	now := time.Now()
+	for _, store := range s.stores {
+		if store.resolveWeights(now, s.rampDuration) {
+			s.rampUpdates[store.GetId()] = struct{}{}
+		}
 	}
 }
 
@@ -0,0 +1,112 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// StoreLimitType is the type of the operations limited on each store.
+type StoreLimitType int
+
+// Types of the store limits.
+const (
+	StoreAddPeer StoreLimitType = iota
+	StoreRemovePeer
+)
+
+var storeLimitTypeToName = map[StoreLimitType]string{
+	StoreAddPeer:    "add-peer",
+	StoreRemovePeer: "remove-peer",
+}
+
+func (t StoreLimitType) String() string {
+	if name, ok := storeLimitTypeToName[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseStoreLimitType converts the name to StoreLimitType.
+func ParseStoreLimitType(name string) (StoreLimitType, error) {
+	for t, n := range storeLimitTypeToName {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, errors.Errorf("unknown store limit type: %s", name)
+}
+
+// StoreLimit is a token bucket limiting the operations on a store per minute.
+// The bucket holds the tokens of one minute at most.
+type StoreLimit struct {
+	sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewStoreLimit creates a full StoreLimit of the rate per minute.
+func NewStoreLimit(rate float64) *StoreLimit {
+	return &StoreLimit{
+		rate:   rate,
+		tokens: math.Max(rate, 1),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens generated since the last time.
+func (l *StoreLimit) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(l.tokens+elapsed.Minutes()*l.rate, math.Max(l.rate, 1))
+		l.last = now
+	}
+}
+
+// Rate returns the number of operations allowed per minute.
+func (l *StoreLimit) Rate() float64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.rate
+}
+
+// SetRate changes the number of operations allowed per minute.
+func (l *StoreLimit) SetRate(rate float64) {
+	l.Lock()
+	defer l.Unlock()
+	l.refill(time.Now())
+	l.rate = rate
+	l.tokens = math.Min(l.tokens, math.Max(rate, 1))
+}
+
+// Available returns the tokens left.
+func (l *StoreLimit) Available() float64 {
+	l.Lock()
+	defer l.Unlock()
+	l.refill(time.Now())
+	return l.tokens
+}
+
+// Take takes the tokens for the operations. The tokens may become negative,
+// which are paid off before the next operation is allowed.
+func (l *StoreLimit) Take(count float64) {
+	l.Lock()
+	defer l.Unlock()
+	l.refill(time.Now())
+	l.tokens -= count
+}
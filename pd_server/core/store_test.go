@@ -0,0 +1,103 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"math"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testStoreSuite{})
+
+type testStoreSuite struct{}
+
+func newSpaceTestStore(capacity, available uint64) *StoreInfo {
+	store := NewStoreInfo(&metapb.Store{Id: 1})
+	store.Stats = &pdpb.StoreStats{Capacity: capacity, Available: available}
+	store.RegionSize = 100
+	store.RegionWeight = 1
+	return store
+}
+
+func (s *testStoreSuite) TestSpaceFactor(c *C) {
+	// No stats.
+	c.Assert(NewStoreInfo(&metapb.Store{Id: 1}).ComputeSpaceFactor(0.6, 0.8), Equals, 1.0)
+
+	cases := []struct {
+		available uint64
+		factor    float64
+	}{
+		{100, 1},
+		{40, 1},
+		{30, 1.5},
+		{20, 2},
+		{10, 4},
+		{0, 400},
+	}
+	for _, t := range cases {
+		store := newSpaceTestStore(100, t.available)
+		c.Assert(math.Abs(store.ComputeSpaceFactor(0.6, 0.8)-t.factor), Less, 1e-9)
+	}
+}
+
+func (s *testStoreSuite) TestSpaceRegionScore(c *C) {
+	store := newSpaceTestStore(100, 50)
+	c.Assert(store.RegionScore(), Equals, 100.0)
+	store.SpaceFactor = store.ComputeSpaceFactor(0.6, 0.8)
+	c.Assert(store.RegionScore(), Equals, 100.0)
+
+	store = newSpaceTestStore(100, 10)
+	store.SpaceFactor = store.ComputeSpaceFactor(0.6, 0.8)
+	c.Assert(math.Abs(store.RegionScore()-400), Less, 1e-9)
+	c.Assert(store.Clone().SpaceFactor, Equals, store.SpaceFactor)
+}
+
+func (s *testStoreSuite) TestStoreLimit(c *C) {
+	limit := NewStoreLimit(2)
+	c.Assert(limit.Available(), Equals, 2.0)
+	limit.Take(1)
+	limit.Take(1)
+	limit.Take(1)
+	c.Assert(limit.Available() < 0, IsTrue)
+
+	// Tokens of one minute are refilled, up to the rate.
+	limit.last = limit.last.Add(-time.Minute)
+	c.Assert(limit.Available() >= 1, IsTrue)
+	limit.last = limit.last.Add(-time.Hour)
+	c.Assert(limit.Available(), Equals, 2.0)
+
+	// Lowering the rate drops the extra tokens.
+	limit.SetRate(0.5)
+	c.Assert(limit.Rate(), Equals, 0.5)
+	c.Assert(limit.Available(), Equals, 1.0)
+
+	store := NewStoreInfo(&metapb.Store{Id: 1})
+	c.Assert(store.IsAvailable(StoreAddPeer), IsTrue)
+	store.SetStoreLimit(StoreAddPeer, limit)
+	c.Assert(store.IsAvailable(StoreAddPeer), IsTrue)
+	c.Assert(store.Clone().GetStoreLimit(StoreAddPeer), Equals, limit)
+	limit.Take(1)
+	c.Assert(store.IsAvailable(StoreAddPeer), IsFalse)
+	c.Assert(store.IsAvailable(StoreRemovePeer), IsTrue)
+
+	typ, err := ParseStoreLimitType("remove-peer")
+	c.Assert(err, IsNil)
+	c.Assert(typ, Equals, StoreRemovePeer)
+	_, err = ParseStoreLimitType("unknown")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,200 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/juju/errors"
+)
+
+// Label keys used to specify the weights of a store.
+const (
+	LeaderWeightLabel = "LeaderWeight"
+	RegionWeightLabel = "RegionWeight"
+)
+
+// WeightSource is where a weight of a store comes from.
+type WeightSource string
+
+// Sources of the store weights, in descending order of precedence.
+const (
+	// WeightSourceAPI is the weight set by the store weight API.
+	WeightSourceAPI WeightSource = "api"
+	// WeightSourceLabel is the weight derived from the store's weight labels
+	// by the weight policy.
+	WeightSourceLabel WeightSource = "label"
+	// WeightSourcePolicy is the weight derived by the cluster, such as from
+	// the store's capacity in capacity region weight mode.
+	WeightSourcePolicy WeightSource = "policy"
+	// WeightSourceDefault is used if no other source provides a weight.
+	WeightSourceDefault WeightSource = "default"
+)
+
+// DefaultWeight is the weight of a store if no source provides one.
+const DefaultWeight = 1.0
+
+var weightSources = []WeightSource{WeightSourceAPI, WeightSourceLabel, WeightSourcePolicy}
+
+// WeightCandidates are the weights of a store provided by each source.
+type WeightCandidates map[WeightSource]float64
+
+// Resolve returns the weight of the source with the highest precedence.
+func (c WeightCandidates) Resolve() (float64, WeightSource) {
+	for _, source := range weightSources {
+		if weight, ok := c[source]; ok {
+			return weight, source
+		}
+	}
+	return DefaultWeight, WeightSourceDefault
+}
+
+func (c WeightCandidates) clone() WeightCandidates {
+	if c == nil {
+		return nil
+	}
+	res := make(WeightCandidates, len(c))
+	for source, weight := range c {
+		res[source] = weight
+	}
+	return res
+}
+
+// Names of the built-in weight policies.
+const (
+	IdentityWeightPolicy        = "identity"
+	RankExponentialWeightPolicy = "rank-exponential"
+	LogRatioWeightPolicy        = "log-ratio"
+	LinearRatioWeightPolicy     = "linear-ratio"
+)
+
+// WeightPolicy derives the leader and region weights of stores from their
+// weight labels. The derived weights are the label candidates of the stores.
+type WeightPolicy interface {
+	GetName() string
+	// Apply updates the weights of the stores. It is called with all the
+	// stores of the cluster whenever one of them changes.
+	Apply(stores map[uint64]*StoreInfo)
+}
+
+// NewWeightPolicy creates a weight policy by name.
+func NewWeightPolicy(name string) (WeightPolicy, error) {
+	switch name {
+	case IdentityWeightPolicy:
+		return identityWeightPolicy{}, nil
+	case RankExponentialWeightPolicy:
+		return rankExponentialWeightPolicy{}, nil
+	case LogRatioWeightPolicy:
+		return ratioWeightPolicy{name: name, fn: func(ratio float64) float64 { return 1 + math.Log10(ratio) }}, nil
+	case LinearRatioWeightPolicy:
+		return ratioWeightPolicy{name: name, fn: func(ratio float64) float64 { return 1 + (ratio-1)/10 }}, nil
+	}
+	return nil, errors.Errorf("unknown weight policy %q", name)
+}
+
+// labelWeight returns the weight specified by the store's label. It returns
+// false if the label is missing or invalid.
+func labelWeight(store *StoreInfo, key string) (float64, bool) {
+	value := store.GetLabelValue(key)
+	if value == "" {
+		return 0, false
+	}
+	weight, err := strconv.ParseFloat(value, 64)
+	if err != nil || weight <= 0 {
+		return 0, false
+	}
+	return weight, true
+}
+
+// identityWeightPolicy uses the labeled weights as they are.
+type identityWeightPolicy struct{}
+
+func (p identityWeightPolicy) GetName() string {
+	return IdentityWeightPolicy
+}
+
+func (p identityWeightPolicy) Apply(stores map[uint64]*StoreInfo) {
+	for _, s := range stores {
+		if w, ok := labelWeight(s, LeaderWeightLabel); ok {
+			s.SetWeight(LeaderKind, WeightSourceLabel, w)
+		}
+		if w, ok := labelWeight(s, RegionWeightLabel); ok {
+			s.SetWeight(RegionKind, WeightSourceLabel, w)
+		}
+	}
+}
+
+// rankExponentialWeightPolicy uses the labeled region weights as they are,
+// and sets the leader weight of a store to 1e-5 * 1e4^rank, where rank is
+// the number of stores that have a smaller region weight.
+type rankExponentialWeightPolicy struct{}
+
+func (p rankExponentialWeightPolicy) GetName() string {
+	return RankExponentialWeightPolicy
+}
+
+func (p rankExponentialWeightPolicy) Apply(stores map[uint64]*StoreInfo) {
+	var labeled []*StoreInfo
+	weights := make(map[uint64]float64, len(stores))
+	for id, s := range stores {
+		weights[id] = DefaultWeight
+		if w, ok := labelWeight(s, RegionWeightLabel); ok {
+			s.SetWeight(RegionKind, WeightSourceLabel, w)
+			weights[id] = w
+			labeled = append(labeled, s)
+		}
+	}
+	ranks := make(map[uint64]int, len(labeled))
+	for _, s := range labeled {
+		for _, w := range weights {
+			if weights[s.GetId()] > w {
+				ranks[s.GetId()]++
+			}
+		}
+	}
+	for _, s := range labeled {
+		s.SetWeight(LeaderKind, WeightSourceLabel, 1e-5*math.Pow(1e4, float64(ranks[s.GetId()])))
+	}
+}
+
+// ratioWeightPolicy sets the weight of a store to fn(x/min), where x is the
+// labeled weight and min is the smallest labeled weight in the cluster.
+type ratioWeightPolicy struct {
+	name string
+	fn   func(ratio float64) float64
+}
+
+func (p ratioWeightPolicy) GetName() string {
+	return p.name
+}
+
+func (p ratioWeightPolicy) Apply(stores map[uint64]*StoreInfo) {
+	p.apply(stores, LeaderKind, LeaderWeightLabel)
+	p.apply(stores, RegionKind, RegionWeightLabel)
+}
+
+func (p ratioWeightPolicy) apply(stores map[uint64]*StoreInfo, kind ResourceKind, key string) {
+	weights := make(map[uint64]float64)
+	min := math.MaxFloat64
+	for id, s := range stores {
+		if w, ok := labelWeight(s, key); ok {
+			weights[id] = w
+			min = math.Min(min, w)
+		}
+	}
+	for id, w := range weights {
+		stores[id].SetWeight(kind, WeightSourceLabel, p.fn(w/min))
+	}
+}
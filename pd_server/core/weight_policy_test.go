@@ -0,0 +1,124 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"math"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testWeightPolicySuite{})
+
+type testWeightPolicySuite struct{}
+
+func newWeightedStores(weights ...float64) *StoresInfo {
+	stores := NewStoresInfo()
+	for i, w := range weights {
+		stores.SetStore(NewStoreInfo(&metapb.Store{
+			Id: uint64(i + 1),
+			Labels: []*metapb.StoreLabel{
+				{Key: LeaderWeightLabel, Value: fmt.Sprint(w)},
+				{Key: RegionWeightLabel, Value: fmt.Sprint(w)},
+			},
+		}))
+	}
+	return stores
+}
+
+func (s *testWeightPolicySuite) TestNewWeightPolicy(c *C) {
+	for _, name := range []string{IdentityWeightPolicy, RankExponentialWeightPolicy, LogRatioWeightPolicy, LinearRatioWeightPolicy} {
+		policy, err := NewWeightPolicy(name)
+		c.Assert(err, IsNil)
+		c.Assert(policy.GetName(), Equals, name)
+	}
+	_, err := NewWeightPolicy("unknown")
+	c.Assert(err, NotNil)
+}
+
+func (s *testWeightPolicySuite) TestApply(c *C) {
+	stores := newWeightedStores(10, 100, 1000)
+	c.Assert(stores.GetWeightPolicy().GetName(), Equals, RankExponentialWeightPolicy)
+
+	testCases := []struct {
+		name          string
+		leaderWeights []float64
+		regionWeights []float64
+	}{
+		{IdentityWeightPolicy, []float64{10, 100, 1000}, []float64{10, 100, 1000}},
+		{RankExponentialWeightPolicy, []float64{1e-5, 1e-1, 1e3}, []float64{10, 100, 1000}},
+		{LogRatioWeightPolicy, []float64{1, 2, 3}, []float64{1, 2, 3}},
+		{LinearRatioWeightPolicy, []float64{1, 1.9, 10.9}, []float64{1, 1.9, 10.9}},
+	}
+	for _, t := range testCases {
+		policy, err := NewWeightPolicy(t.name)
+		c.Assert(err, IsNil)
+		stores.SetWeightPolicy(policy)
+		for i := range t.leaderWeights {
+			store := stores.GetStore(uint64(i + 1))
+			c.Assert(math.Abs(store.LeaderWeight-t.leaderWeights[i]) < 1e-9, IsTrue)
+			c.Assert(math.Abs(store.RegionWeight-t.regionWeights[i]) < 1e-9, IsTrue)
+		}
+	}
+}
+
+func (s *testWeightPolicySuite) TestUnlabeledStore(c *C) {
+	stores := newWeightedStores(2, 4)
+	stores.SetStore(NewStoreInfo(&metapb.Store{Id: 3}))
+
+	policy, err := NewWeightPolicy(LogRatioWeightPolicy)
+	c.Assert(err, IsNil)
+	stores.SetWeightPolicy(policy)
+	store := stores.GetStore(3)
+	c.Assert(store.LeaderWeight, Equals, 1.0)
+	c.Assert(store.RegionWeight, Equals, 1.0)
+}
+
+func (s *testWeightPolicySuite) TestWeightPrecedence(c *C) {
+	stores := newWeightedStores(2)
+	store := stores.GetStore(1)
+	c.Assert(store.RegionWeight, Equals, 2.0)
+	c.Assert(store.RegionWeightSource, Equals, WeightSourceLabel)
+
+	// API overrides label.
+	store.SetWeight(RegionKind, WeightSourceAPI, 3)
+	stores.SetStore(store)
+	store = stores.GetStore(1)
+	c.Assert(store.RegionWeight, Equals, 3.0)
+	c.Assert(store.RegionWeightSource, Equals, WeightSourceAPI)
+	c.Assert(store.RegionWeights, DeepEquals, WeightCandidates{WeightSourceAPI: 3, WeightSourceLabel: 2})
+
+	// Label overrides policy.
+	store.ClearWeight(RegionKind, WeightSourceAPI)
+	store.SetWeight(RegionKind, WeightSourcePolicy, 4)
+	stores.SetStore(store)
+	store = stores.GetStore(1)
+	c.Assert(store.RegionWeight, Equals, 2.0)
+	c.Assert(store.RegionWeightSource, Equals, WeightSourceLabel)
+
+	// Policy overrides default.
+	store.Labels = nil
+	stores.SetStore(store)
+	store = stores.GetStore(1)
+	c.Assert(store.RegionWeight, Equals, 4.0)
+	c.Assert(store.RegionWeightSource, Equals, WeightSourcePolicy)
+
+	store.ClearWeight(RegionKind, WeightSourcePolicy)
+	stores.SetStore(store)
+	store = stores.GetStore(1)
+	c.Assert(store.RegionWeight, Equals, DefaultWeight)
+	c.Assert(store.RegionWeightSource, Equals, WeightSourceDefault)
+}
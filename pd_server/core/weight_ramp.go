@@ -0,0 +1,66 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+)
+
+// WeightRamp moves a weight of a store linearly from From to To within
+// Duration since StartTime, so that a weight change does not trigger a burst
+// of data migration.
+type WeightRamp struct {
+	From      float64       `json:"from"`
+	To        float64       `json:"to"`
+	StartTime time.Time     `json:"start_time"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Weight returns the weight at the time.
+func (r *WeightRamp) Weight(now time.Time) float64 {
+	if r.IsFinished(now) {
+		return r.To
+	}
+	elapsed := now.Sub(r.StartTime)
+	if elapsed <= 0 {
+		return r.From
+	}
+	return r.From + (r.To-r.From)*float64(elapsed)/float64(r.Duration)
+}
+
+// IsFinished checks if the weight has reached the target at the time.
+func (r *WeightRamp) IsFinished(now time.Time) bool {
+	return now.Sub(r.StartTime) >= r.Duration
+}
+
+// resolveWeight returns the effective weight, its source and the ramp toward
+// the candidate with the highest precedence. A new ramp from the current
+// weight is started if the target changes and rampDuration is positive.
+func resolveWeight(candidates WeightCandidates, weight float64, ramp *WeightRamp, now time.Time, rampDuration time.Duration) (float64, WeightSource, *WeightRamp) {
+	target, source := candidates.Resolve()
+	lastTarget := weight
+	if ramp != nil {
+		lastTarget = ramp.To
+	}
+	if target != lastTarget {
+		ramp = nil
+		if rampDuration > 0 {
+			ramp = &WeightRamp{From: weight, To: target, StartTime: now, Duration: rampDuration}
+		}
+	}
+	if ramp == nil || ramp.IsFinished(now) {
+		return target, source, nil
+	}
+	return ramp.Weight(now), source, ramp
+}
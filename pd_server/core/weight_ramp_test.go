@@ -0,0 +1,104 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testWeightRampSuite{})
+
+type testWeightRampSuite struct{}
+
+func (s *testWeightRampSuite) TestWeight(c *C) {
+	start := time.Now()
+	ramp := &WeightRamp{From: 1, To: 3, StartTime: start, Duration: time.Minute}
+	c.Assert(ramp.Weight(start.Add(-time.Second)), Equals, 1.0)
+	c.Assert(ramp.Weight(start.Add(30*time.Second)), Equals, 2.0)
+	c.Assert(ramp.IsFinished(start.Add(30*time.Second)), IsFalse)
+	c.Assert(ramp.Weight(start.Add(2*time.Minute)), Equals, 3.0)
+	c.Assert(ramp.IsFinished(start.Add(time.Minute)), IsTrue)
+}
+
+func (s *testWeightRampSuite) TestResolveWeight(c *C) {
+	now := time.Now()
+	candidates := WeightCandidates{WeightSourceAPI: 3}
+
+	// No ramp without a duration.
+	weight, source, ramp := resolveWeight(candidates, 1, nil, now, 0)
+	c.Assert(weight, Equals, 3.0)
+	c.Assert(source, Equals, WeightSourceAPI)
+	c.Assert(ramp, IsNil)
+
+	// A ramp starts from the current weight.
+	weight, _, ramp = resolveWeight(candidates, 1, nil, now, time.Minute)
+	c.Assert(weight, Equals, 1.0)
+	c.Assert(ramp, DeepEquals, &WeightRamp{From: 1, To: 3, StartTime: now, Duration: time.Minute})
+
+	// The ramp moves on while the target is unchanged.
+	later := now.Add(30 * time.Second)
+	weight, _, r := resolveWeight(candidates, weight, ramp, later, time.Minute)
+	c.Assert(weight, Equals, 2.0)
+	c.Assert(r, Equals, ramp)
+
+	// A new ramp starts from the middle if the target changes.
+	candidates[WeightSourceAPI] = 4
+	weight, _, r = resolveWeight(candidates, weight, ramp, later, time.Minute)
+	c.Assert(weight, Equals, 2.0)
+	c.Assert(r.From, Equals, 2.0)
+	c.Assert(r.To, Equals, 4.0)
+
+	// The ramp is removed after it finishes.
+	weight, _, r = resolveWeight(candidates, weight, r, later.Add(time.Minute), time.Minute)
+	c.Assert(weight, Equals, 4.0)
+	c.Assert(r, IsNil)
+}
+
+func (s *testWeightRampSuite) TestStoresInfo(c *C) {
+	stores := NewStoresInfo()
+	stores.SetWeightRampDuration(time.Hour)
+	stores.SetStore(NewStoreInfo(&metapb.Store{Id: 1}))
+	c.Assert(stores.TakeWeightRampUpdates(), HasLen, 0)
+
+	store := stores.GetStore(1)
+	store.SetWeight(RegionKind, WeightSourceAPI, 2)
+	stores.SetStore(store)
+	store = stores.GetStore(1)
+	c.Assert(store.RegionWeight, Equals, 1.0)
+	c.Assert(store.RegionWeightRamp.To, Equals, 2.0)
+	c.Assert(store.LeaderWeightRamp, IsNil)
+	updates := stores.TakeWeightRampUpdates()
+	c.Assert(updates, HasLen, 1)
+	c.Assert(updates[0].RegionWeightRamp, NotNil)
+	c.Assert(stores.TakeWeightRampUpdates(), HasLen, 0)
+
+	// A ramp with an explicit duration.
+	store.SetWeight(LeaderKind, WeightSourceAPI, 3)
+	store.ResolveWeights(0)
+	stores.SetStore(store)
+	store = stores.GetStore(1)
+	c.Assert(store.LeaderWeight, Equals, 3.0)
+	c.Assert(store.LeaderWeightRamp, IsNil)
+
+	// Cancel the ramp.
+	store.CancelWeightRamps()
+	stores.SetStore(store)
+	store = stores.GetStore(1)
+	c.Assert(store.RegionWeight, Equals, 2.0)
+	c.Assert(store.RegionWeightRamp, IsNil)
+	c.Assert(stores.TakeWeightRampUpdates(), HasLen, 1)
+}
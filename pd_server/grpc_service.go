@@ -24,10 +24,12 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 )
 
 // notLeaderError is returned when current server is not the leader and not possible to process request.
@@ -200,6 +202,7 @@ func (s *Server) PutStore(ctx context.Context, request *pdpb.PutStoreRequest) (*
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
+	s.recorder.record(RecordPutStore, request)
 
 	cluster := s.GetRaftCluster()
 	if cluster == nil {
@@ -246,6 +249,7 @@ func (s *Server) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHeartbea
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
+	s.recorder.record(RecordStoreHeartbeat, request)
 
 	if request.GetStats() == nil {
 		return nil, errors.Errorf("invalid store heartbeat command, but %v", request)
@@ -337,6 +341,7 @@ func (s *Server) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error {
 		if err = s.validateRequest(request.GetHeader()); err != nil {
 			return errors.Trace(err)
 		}
+		s.recorder.record(RecordRegionHeartbeat, request)
 
 		storeID := request.GetLeader().GetStoreId()
 		storeLabel := strconv.FormatUint(storeID, 10)
@@ -416,6 +421,7 @@ func (s *Server) AskSplit(ctx context.Context, request *pdpb.AskSplitRequest) (*
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
+	s.recorder.record(RecordAskSplit, request)
 
 	cluster := s.GetRaftCluster()
 	if cluster == nil {
@@ -444,6 +450,7 @@ func (s *Server) ReportSplit(ctx context.Context, request *pdpb.ReportSplitReque
 	if err := s.validateRequest(request.GetHeader()); err != nil {
 		return nil, errors.Trace(err)
 	}
+	s.recorder.record(RecordReportSplit, request)
 
 	cluster := s.GetRaftCluster()
 	if cluster == nil {
@@ -516,8 +523,13 @@ func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionR
 		region = core.NewRegionInfo(request.GetRegion(), request.GetLeader())
 	}
 
+	mode, err := scatterModeFromContext(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
 	co := cluster.coordinator
-	if op := co.regionScatterer.Scatter(region); op != nil {
+	if op := co.regionScatterer.Scatter(region, mode); op != nil {
 		co.addOperator(op)
 	}
 
@@ -526,6 +538,20 @@ func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionR
 	}, nil
 }
 
+// scatterModeMetadataKey is the gRPC metadata key to select the scatter mode
+// of a ScatterRegion request, since the request has no such field.
+const scatterModeMetadataKey = "pd-scatter-mode"
+
+func scatterModeFromContext(ctx context.Context) (schedule.ScatterMode, error) {
+	var mode string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md[scatterModeMetadataKey]; len(values) > 0 {
+			mode = values[0]
+		}
+	}
+	return schedule.ParseScatterMode(mode)
+}
+
 // validateRequest checks if Server is leader and clusterID is matched.
 // TODO: Call it in gRPC intercepter.
 func (s *Server) validateRequest(header *pdpb.RequestHeader) error {
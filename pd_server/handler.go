@@ -71,6 +71,15 @@ func (h *Handler) GetSchedulers() ([]string, error) {
 	return c.getSchedulers(), nil
 }
 
+// GetSchedulerStatuses returns the states of all schedulers.
+func (h *Handler) GetSchedulerStatuses() ([]*SchedulerStatus, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getSchedulerStatuses(), nil
+}
+
 // GetStores returns all stores in the cluster.
 func (h *Handler) GetStores() ([]*core.StoreInfo, error) {
 	cluster := h.s.GetRaftCluster()
@@ -89,6 +98,16 @@ func (h *Handler) GetStores() ([]*core.StoreInfo, error) {
 	return stores, nil
 }
 
+// GetBalancePlan returns the ideal distribution of leaders and regions under
+// the store weights, with the hypothetical weights of some stores.
+func (h *Handler) GetBalancePlan(weights map[uint64]*PlanWeights) (*BalancePlan, error) {
+	cluster := h.s.GetRaftCluster()
+	if cluster == nil {
+		return nil, errors.Trace(ErrNotBootstrapped)
+	}
+	return newBalancePlan(cluster.cachedCluster.GetStores(), h.s.classifier, weights)
+}
+
 // GetHotWriteRegions gets all hot write regions status
 func (h *Handler) GetHotWriteRegions() *core.StoreHotRegionInfos {
 	c, err := h.getCoordinator()
@@ -136,6 +155,35 @@ func (h *Handler) AddScheduler(name string, args ...string) error {
 	return errors.Trace(err)
 }
 
+// AddDryRunScheduler adds a scheduler in dry-run mode, whose operators can be
+// previewed but are not added.
+func (h *Handler) AddDryRunScheduler(name string, args ...string) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s, err := schedule.CreateScheduler(name, c.limiter, args...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("create dry-run scheduler %s", s.GetName())
+	if err = c.addDryRunScheduler(s, args...); err != nil {
+		log.Errorf("can not add scheduler %v: %v", s.GetName(), err)
+	} else if err = h.opt.persist(c.cluster.kv); err != nil {
+		log.Errorf("can not persist scheduler config: %v", err)
+	}
+	return errors.Trace(err)
+}
+
+// GetSchedulerPreviews returns the operator previews of a dry-run scheduler.
+func (h *Handler) GetSchedulerPreviews(name string) ([]*SchedulerPreview, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getSchedulerPreviews(name)
+}
+
 // RemoveScheduler removes a scheduler by name.
 func (h *Handler) RemoveScheduler(name string) error {
 	c, err := h.getCoordinator()
@@ -150,6 +198,88 @@ func (h *Handler) RemoveScheduler(name string) error {
 	return errors.Trace(err)
 }
 
+// GetSchedulerConfig returns the config of a scheduler by name.
+func (h *Handler) GetSchedulerConfig(name string) (map[string]interface{}, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getSchedulerConfig(name)
+}
+
+// UpdateSchedulerConfig updates the config of a scheduler by name, and returns
+// the name of the scheduler after the update.
+func (h *Handler) UpdateSchedulerConfig(name string, config map[string]interface{}) (string, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	newName, err := c.updateSchedulerConfig(name, config)
+	if err != nil {
+		log.Errorf("can not update scheduler %v config: %v", name, err)
+	}
+	return newName, errors.Trace(err)
+}
+
+// SetSchedulerTrace enables or disables the decision tracing of a scheduler
+// by name.
+func (h *Handler) SetSchedulerTrace(name string, enable bool) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tracer, err := c.getSchedulerTracer(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("set scheduler %s trace enabled: %v", name, enable)
+	tracer.Enable(enable)
+	return nil
+}
+
+// GetSchedulerTraces returns if the decision tracing of a scheduler is
+// enabled, and the recorded schedule attempts.
+func (h *Handler) GetSchedulerTraces(name string) (bool, []*schedule.ScheduleTrace, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return false, nil, errors.Trace(err)
+	}
+	tracer, err := c.getSchedulerTracer(name)
+	if err != nil {
+		return false, nil, errors.Trace(err)
+	}
+	return tracer.IsEnabled(), tracer.GetTraces(), nil
+}
+
+// PauseScheduler pauses a scheduler by name for the duration, or until it is
+// resumed if the duration is 0.
+func (h *Handler) PauseScheduler(name string, d time.Duration) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = c.pauseScheduler(name, d); err != nil {
+		log.Errorf("can not pause scheduler %v: %v", name, err)
+	} else if err = h.opt.persist(c.cluster.kv); err != nil {
+		log.Errorf("can not persist scheduler config: %v", err)
+	}
+	return errors.Trace(err)
+}
+
+// ResumeScheduler resumes a paused scheduler by name.
+func (h *Handler) ResumeScheduler(name string) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = c.resumeScheduler(name); err != nil {
+		log.Errorf("can not resume scheduler %v: %v", name, err)
+	} else if err = h.opt.persist(c.cluster.kv); err != nil {
+		log.Errorf("can not persist scheduler config: %v", err)
+	}
+	return errors.Trace(err)
+}
+
 // AddBalanceLeaderScheduler adds a balance-leader-scheduler.
 func (h *Handler) AddBalanceLeaderScheduler() error {
 	return h.AddScheduler("balance-leader")
@@ -227,7 +357,7 @@ func (h *Handler) RemoveOperator(regionID uint64) error {
 		return ErrOperatorNotFound
 	}
 
-	c.removeOperator(op)
+	c.removeOperator(op, schedule.OperatorCanceledByAdmin, "removed by admin")
 	return nil
 }
 
@@ -279,6 +409,16 @@ func (h *Handler) GetHistory(start time.Time) ([]schedule.OperatorHistory, error
 	return c.getHistory(start), nil
 }
 
+// GetOperatorRecords returns the records of the operator history matching the
+// filter.
+func (h *Handler) GetOperatorRecords(filter *OperatorRecordFilter) ([]*schedule.OperatorRecord, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.getOperatorRecords(filter), nil
+}
+
 // AddTransferLeaderOperator adds an operator to transfer leader to the store.
 func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) error {
 	c, err := h.getCoordinator()
@@ -296,7 +436,27 @@ func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) err
 	}
 
 	step := schedule.TransferLeader{FromStore: region.Leader.GetStoreId(), ToStore: newLeader.GetStoreId()}
-	op := schedule.NewOperator("adminTransferLeader", regionID, schedule.OpAdmin|schedule.OpLeader, step)
+	op := schedule.NewOperator("adminTransferLeader", regionID, region.GetRegionEpoch(), schedule.OpAdmin|schedule.OpLeader, step)
+	c.addOperator(op)
+	return nil
+}
+
+// AddScatterRegionOperator adds an operator to scatter the region.
+func (h *Handler) AddScatterRegionOperator(regionID uint64, mode schedule.ScatterMode) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	region := c.cluster.GetRegion(regionID)
+	if region == nil {
+		return ErrRegionNotFound(regionID)
+	}
+
+	op := c.regionScatterer.Scatter(region, mode)
+	if op == nil {
+		return errors.Errorf("region %v cannot be scattered", regionID)
+	}
 	c.addOperator(op)
 	return nil
 }
@@ -338,7 +498,7 @@ func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64
 		steps = append(steps, schedule.RemovePeer{FromStore: peer.GetStoreId()})
 	}
 
-	op := schedule.NewOperator("adminMoveRegion", regionID, schedule.OpAdmin|schedule.OpRegion, steps...)
+	op := schedule.NewOperator("adminMoveRegion", regionID, region.GetRegionEpoch(), schedule.OpAdmin|schedule.OpRegion, steps...)
 	c.addOperator(op)
 	return nil
 }
@@ -398,7 +558,7 @@ func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
 	}
 
 	step := schedule.AddPeer{ToStore: toStoreID, PeerID: newPeer.GetId()}
-	op := schedule.NewOperator("adminAddPeer", regionID, schedule.OpAdmin|schedule.OpRegion, step)
+	op := schedule.NewOperator("adminAddPeer", regionID, region.GetRegionEpoch(), schedule.OpAdmin|schedule.OpRegion, step)
 	c.addOperator(op)
 	return nil
 }
@@ -0,0 +1,176 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/pd/server/core"
+)
+
+// AdaptiveLeaderWeightLabel is the label key to switch off the adaptive
+// leader weight of a store, by setting it to "false".
+const AdaptiveLeaderWeightLabel = "AdaptiveLeaderWeight"
+
+// throughputSample is the read/write throughput reported in a store heartbeat.
+type throughputSample struct {
+	bytes  uint64
+	isBusy bool
+}
+
+// throughputWindow keeps the latest throughput samples of a store.
+type throughputWindow struct {
+	samples []throughputSample
+	next    int
+	full    bool
+}
+
+func newThroughputWindow(size int) *throughputWindow {
+	return &throughputWindow{samples: make([]throughputSample, size)}
+}
+
+func (w *throughputWindow) add(sample throughputSample) {
+	w.samples[w.next] = sample
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+func (w *throughputWindow) items() []throughputSample {
+	if w.full {
+		return w.samples
+	}
+	return w.samples[:w.next]
+}
+
+// estimate returns the estimated service capacity of the store and the ratio
+// of busy samples. The average throughput while busy is taken as the capacity
+// since the store is saturated, otherwise the capacity is at least the max
+// throughput observed.
+func (w *throughputWindow) estimate() (float64, float64) {
+	var busyCount int
+	var busyBytes, maxBytes uint64
+	for _, s := range w.items() {
+		if s.isBusy {
+			busyCount++
+			busyBytes += s.bytes
+		}
+		if s.bytes > maxBytes {
+			maxBytes = s.bytes
+		}
+	}
+	busyRatio := float64(busyCount) / float64(len(w.items()))
+	if busyCount > 0 {
+		return float64(busyBytes) / float64(busyCount), busyRatio
+	}
+	return float64(maxBytes), busyRatio
+}
+
+// leaderWeightController adjusts the adaptive leader factor of stores by
+// their service capacity relative to the average of the cluster. A busy
+// store's factor can only decrease and an idle store's factor can only
+// increase, so that the factor of a lightly loaded store is not driven down
+// by its own low throughput.
+type leaderWeightController struct {
+	opt        *scheduleOption
+	windows    map[uint64]*throughputWindow
+	capacities map[uint64]float64
+}
+
+func newLeaderWeightController(opt *scheduleOption) *leaderWeightController {
+	return &leaderWeightController{
+		opt:        opt,
+		windows:    make(map[uint64]*throughputWindow),
+		capacities: make(map[uint64]float64),
+	}
+}
+
+func isAdaptiveLeaderWeightDisabled(store *core.StoreInfo) bool {
+	return strings.EqualFold(store.GetLabelValue(AdaptiveLeaderWeightLabel), "false")
+}
+
+func (c *leaderWeightController) reset(storeID uint64) {
+	delete(c.windows, storeID)
+	delete(c.capacities, storeID)
+	id := strconv.FormatUint(storeID, 10)
+	for _, typ := range []string{"factor", "capacity", "busy_ratio"} {
+		adaptiveLeaderWeightGauge.DeleteLabelValues(id, typ)
+	}
+}
+
+// update records the throughput of the store heartbeat and adjusts the
+// store's adaptive leader factor.
+func (c *leaderWeightController) update(store *core.StoreInfo, stores []*core.StoreInfo) {
+	storeID := store.GetId()
+	// An offline store keeps heartbeating while it is drained, but it is not
+	// counted in the average capacity any more.
+	if !c.opt.IsAdaptiveLeaderWeightEnabled() || isAdaptiveLeaderWeightDisabled(store) || !store.IsUp() {
+		store.AdaptiveLeaderFactor = 0
+		c.reset(storeID)
+		return
+	}
+
+	size := c.opt.GetAdaptiveLeaderWeightWindow()
+	window, ok := c.windows[storeID]
+	if !ok || len(window.samples) != size {
+		window = newThroughputWindow(size)
+		c.windows[storeID] = window
+	}
+	window.add(throughputSample{
+		bytes:  store.Stats.GetBytesRead() + store.Stats.GetBytesWritten(),
+		isBusy: store.Stats.GetIsBusy(),
+	})
+	capacity, busyRatio := window.estimate()
+	c.capacities[storeID] = capacity
+
+	factor := store.AdaptiveLeaderFactor
+	if factor <= 0 {
+		factor = 1
+	}
+	if avg := c.averageCapacity(stores); window.full && avg > 0 {
+		minFactor, maxFactor := c.opt.GetAdaptiveLeaderFactorBounds()
+		target := math.Min(math.Max(capacity/avg, minFactor), maxFactor)
+		step := c.opt.GetAdaptiveLeaderWeightStep()
+		if busyRatio > 0 && target < factor {
+			factor = math.Max(target, factor*(1-step))
+		} else if busyRatio == 0 && target > factor {
+			factor = math.Min(target, factor*(1+step))
+		}
+	}
+	store.AdaptiveLeaderFactor = factor
+
+	id := strconv.FormatUint(storeID, 10)
+	adaptiveLeaderWeightGauge.WithLabelValues(id, "factor").Set(factor)
+	adaptiveLeaderWeightGauge.WithLabelValues(id, "capacity").Set(capacity)
+	adaptiveLeaderWeightGauge.WithLabelValues(id, "busy_ratio").Set(busyRatio)
+}
+
+// averageCapacity returns the average estimated capacity of the up stores.
+func (c *leaderWeightController) averageCapacity(stores []*core.StoreInfo) float64 {
+	var total float64
+	var count int
+	for _, s := range stores {
+		if capacity, ok := c.capacities[s.GetId()]; ok && s.IsUp() {
+			total += capacity
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
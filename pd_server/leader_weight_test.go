@@ -0,0 +1,106 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server/core"
+)
+
+var _ = Suite(&testLeaderWeightSuite{})
+
+type testLeaderWeightSuite struct{}
+
+func (s *testLeaderWeightSuite) heartbeat(c *C, tc *testClusterInfo, storeID uint64, bytes uint64, isBusy bool) {
+	c.Assert(tc.handleStoreHeartbeat(&pdpb.StoreStats{StoreId: storeID, BytesWritten: bytes, IsBusy: isBusy}), IsNil)
+}
+
+func (s *testLeaderWeightSuite) TestThroughputWindow(c *C) {
+	w := newThroughputWindow(3)
+	w.add(throughputSample{bytes: 100})
+	w.add(throughputSample{bytes: 300})
+	capacity, busyRatio := w.estimate()
+	c.Assert(capacity, Equals, 300.0)
+	c.Assert(busyRatio, Equals, 0.0)
+	c.Assert(w.full, IsFalse)
+
+	w.add(throughputSample{bytes: 200, isBusy: true})
+	w.add(throughputSample{bytes: 400, isBusy: true})
+	capacity, busyRatio = w.estimate()
+	c.Assert(capacity, Equals, 300.0)
+	c.Assert(busyRatio, Equals, 2.0/3)
+	c.Assert(w.full, IsTrue)
+}
+
+func (s *testLeaderWeightSuite) TestAdaptiveLeaderWeight(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	cfg.AdaptiveLeaderWeight = true
+	cfg.AdaptiveLeaderWeightWindow = 3
+	cfg.AdaptiveLeaderWeightStep = 0.5
+	cfg.MaxAdaptiveLeaderFactor = 1.8
+	tc := newTestClusterInfo(opt)
+	tc.leaderWeights = newLeaderWeightController(opt)
+
+	tc.addRegionStore(1, 0)
+	tc.addRegionStore(2, 0)
+	for i := 0; i < 3; i++ {
+		s.heartbeat(c, tc, 1, 100, true)
+		s.heartbeat(c, tc, 2, 300, false)
+	}
+	// The busy store serves less than the average, so its factor decreases.
+	c.Assert(tc.GetStore(1).AdaptiveLeaderFactor, Equals, 0.5)
+	c.Assert(tc.GetStore(1).ResourceWeight(core.LeaderKind), Equals, 0.5)
+	// The idle store serves more than the average, so its factor increases.
+	c.Assert(tc.GetStore(2).AdaptiveLeaderFactor, Equals, 1.5)
+
+	// The factor is bounded.
+	for i := 0; i < 3; i++ {
+		s.heartbeat(c, tc, 1, 10, true)
+		s.heartbeat(c, tc, 2, 3000, false)
+	}
+	c.Assert(tc.GetStore(1).AdaptiveLeaderFactor, Equals, 0.5)
+	c.Assert(tc.GetStore(2).AdaptiveLeaderFactor, Equals, 1.8)
+
+	// The states of a buried store are dropped.
+	c.Assert(tc.leaderWeights.windows, HasLen, 2)
+	tc.resetStoreLeaderWeight(1)
+	c.Assert(tc.leaderWeights.windows, HasLen, 1)
+	c.Assert(tc.leaderWeights.capacities, HasLen, 1)
+
+	// An offline store is not adjusted.
+	store := tc.GetStore(1)
+	store.State = metapb.StoreState_Offline
+	c.Assert(tc.putStore(store), IsNil)
+	s.heartbeat(c, tc, 1, 100, true)
+	c.Assert(tc.GetStore(1).AdaptiveLeaderFactor, Equals, 0.0)
+	c.Assert(tc.leaderWeights.windows, HasLen, 1)
+	store = tc.GetStore(1)
+	store.State = metapb.StoreState_Up
+	c.Assert(tc.putStore(store), IsNil)
+
+	// Switch off store 2 by label.
+	store = tc.GetStore(2)
+	store.Labels = append(store.Labels, &metapb.StoreLabel{Key: AdaptiveLeaderWeightLabel, Value: "false"})
+	c.Assert(tc.putStore(store), IsNil)
+	s.heartbeat(c, tc, 2, 3000, false)
+	c.Assert(tc.GetStore(2).AdaptiveLeaderFactor, Equals, 0.0)
+	c.Assert(tc.GetStore(2).ResourceWeight(core.LeaderKind), Equals, 1.0)
+
+	// Switch off all stores.
+	cfg.AdaptiveLeaderWeight = false
+	s.heartbeat(c, tc, 1, 100, true)
+	c.Assert(tc.GetStore(1).AdaptiveLeaderFactor, Equals, 0.0)
+}
@@ -139,6 +139,14 @@ var (
 			Help:      "Counter of tso events",
 		}, []string{"type"})
 
+	adaptiveLeaderWeightGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "adaptive_leader_weight",
+			Help:      "Status of the adaptive leader weight controller.",
+		}, []string{"store", "type"})
+
 	metadataGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -164,4 +172,5 @@ func init() {
 	prometheus.MustRegister(regionStatusGauge)
 	prometheus.MustRegister(regionLabelLevelGauge)
 	prometheus.MustRegister(metadataGauge)
+	prometheus.MustRegister(adaptiveLeaderWeightGauge)
 }
@@ -0,0 +1,160 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+)
+
+// OperatorRecordFilter selects the records of the operator history. The zero
+// value of a field matches all records.
+type OperatorRecordFilter struct {
+	RegionID uint64
+	StoreID  uint64
+	Kind     schedule.OperatorKind
+	Start    time.Time
+	End      time.Time
+	// Limit is the max number of the records returned, which are the latest
+	// ones matching.
+	Limit int
+}
+
+func (f *OperatorRecordFilter) match(record *schedule.OperatorRecord) bool {
+	if f.RegionID != 0 && record.RegionID != f.RegionID {
+		return false
+	}
+	if f.StoreID != 0 && !containsStore(record.Stores, f.StoreID) {
+		return false
+	}
+	if f.Kind != 0 {
+		kind, err := schedule.ParseOperatorKind(record.Kind)
+		if err != nil || kind&f.Kind == 0 {
+			return false
+		}
+	}
+	if !f.Start.IsZero() && record.FinishTime.Before(f.Start) {
+		return false
+	}
+	if !f.End.IsZero() && record.FinishTime.After(f.End) {
+		return false
+	}
+	return true
+}
+
+func containsStore(stores []uint64, storeID uint64) bool {
+	for _, id := range stores {
+		if id == storeID {
+			return true
+		}
+	}
+	return false
+}
+
+// operatorHistory is a bounded log of the finished operators. The records
+// are loaded from the KV once when the leader starts, and then served from
+// memory. They are keyed by their sequences in the KV, where the records
+// beyond the limit are deleted as new ones are appended.
+type operatorHistory struct {
+	sync.RWMutex
+	kv        *core.KV
+	persister *operatorPersister
+	nextSeq   uint64
+	// records are the kept records ordered by their sequences.
+	records []*schedule.OperatorRecord
+}
+
+func newOperatorHistory(kv *core.KV, persister *operatorPersister) *operatorHistory {
+	return &operatorHistory{kv: kv, persister: persister}
+}
+
+// load loads the records kept by the previous leader, and deletes the ones
+// beyond the limit.
+func (h *operatorHistory) load(limit uint64) error {
+	var records []*schedule.OperatorRecord
+	for nextSeq := uint64(0); ; {
+		values, err := h.kv.LoadOperatorRecords(nextSeq, kvRangeLimit)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for _, value := range values {
+			record := &schedule.OperatorRecord{}
+			if err = json.Unmarshal([]byte(value), record); err != nil {
+				return errors.Trace(err)
+			}
+			records = append(records, record)
+			nextSeq = record.Seq + 1
+		}
+		if len(values) < kvRangeLimit {
+			break
+		}
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	h.records = records
+	if len(records) > 0 {
+		h.nextSeq = records[len(records)-1].Seq + 1
+	}
+	h.trim(limit)
+	return nil
+}
+
+// append assigns the next sequence to the record and keeps it.
+func (h *operatorHistory) append(record *schedule.OperatorRecord, limit uint64) {
+	h.Lock()
+	defer h.Unlock()
+	record.Seq = h.nextSeq
+	h.nextSeq++
+	h.records = append(h.records, record)
+	h.persister.saveRecord(record)
+	h.trim(limit)
+}
+
+// trim drops the oldest records beyond the limit.
+func (h *operatorHistory) trim(limit uint64) {
+	n := 0
+	for uint64(len(h.records)-n) > limit {
+		h.persister.deleteRecord(h.records[n].Seq)
+		h.records[n] = nil
+		n++
+	}
+	h.records = h.records[n:]
+}
+
+// query returns the records matching the filter, from the oldest.
+func (h *operatorHistory) query(filter *OperatorRecordFilter, limit uint64) []*schedule.OperatorRecord {
+	h.RLock()
+	defer h.RUnlock()
+	records := h.records
+	// The limit may have been reduced since the last append.
+	if uint64(len(records)) > limit {
+		records = records[uint64(len(records))-limit:]
+	}
+	var results []*schedule.OperatorRecord
+	for _, record := range records {
+		if filter.match(record) {
+			results = append(results, record)
+		}
+	}
+	if filter.Limit > 0 && len(results) > filter.Limit {
+		results = results[len(results)-filter.Limit:]
+	}
+	return results
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/pd/pkg/logutil"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+	log "github.com/sirupsen/logrus"
+)
+
+// operatorPersister saves the in-flight operators and the operator history to
+// the KV out of the coordinator lock. The changes of a region or a record are
+// merged until they are flushed, and the flushes are serialized to keep the
+// order of the changes.
+type operatorPersister struct {
+	kv *core.KV
+
+	flushMu sync.Mutex
+	mu      sync.Mutex
+	// dirty maps the regions to the operators to save, where nil means the
+	// saved operator is to be deleted.
+	dirty map[uint64]*schedule.OperatorMeta
+	// dirtyRecords maps the sequences to the records of the operator
+	// history to save, where nil means the record is to be deleted.
+	dirtyRecords map[uint64]*schedule.OperatorRecord
+	notify       chan struct{}
+}
+
+func newOperatorPersister(kv *core.KV) *operatorPersister {
+	return &operatorPersister{
+		kv:           kv,
+		dirty:        make(map[uint64]*schedule.OperatorMeta),
+		dirtyRecords: make(map[uint64]*schedule.OperatorRecord),
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+func (p *operatorPersister) save(meta *schedule.OperatorMeta) {
+	p.mark(meta.RegionID, meta)
+}
+
+func (p *operatorPersister) delete(regionID uint64) {
+	p.mark(regionID, nil)
+}
+
+func (p *operatorPersister) mark(regionID uint64, meta *schedule.OperatorMeta) {
+	p.mu.Lock()
+	p.dirty[regionID] = meta
+	p.mu.Unlock()
+	p.wakeup()
+}
+
+func (p *operatorPersister) saveRecord(record *schedule.OperatorRecord) {
+	p.markRecord(record.Seq, record)
+}
+
+func (p *operatorPersister) deleteRecord(seq uint64) {
+	p.markRecord(seq, nil)
+}
+
+func (p *operatorPersister) markRecord(seq uint64, record *schedule.OperatorRecord) {
+	p.mu.Lock()
+	p.dirtyRecords[seq] = record
+	p.mu.Unlock()
+	p.wakeup()
+}
+
+func (p *operatorPersister) wakeup() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// flush writes the pending changes to the KV.
+func (p *operatorPersister) flush() {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	dirty, dirtyRecords := p.dirty, p.dirtyRecords
+	p.dirty = make(map[uint64]*schedule.OperatorMeta)
+	p.dirtyRecords = make(map[uint64]*schedule.OperatorRecord)
+	p.mu.Unlock()
+
+	for regionID, meta := range dirty {
+		if meta == nil {
+			if err := p.kv.DeleteOperator(regionID); err != nil {
+				log.Errorf("[region %v] delete saved operator error: %v", regionID, err)
+			}
+			continue
+		}
+		if err := p.kv.SaveOperator(regionID, meta); err != nil {
+			log.Errorf("[region %v] save operator error: %v", regionID, err)
+		}
+	}
+	for seq, record := range dirtyRecords {
+		if record == nil {
+			if err := p.kv.DeleteOperatorRecord(seq); err != nil {
+				log.Errorf("delete operator record %v error: %v", seq, err)
+			}
+			continue
+		}
+		if err := p.kv.SaveOperatorRecord(seq, record); err != nil {
+			log.Errorf("[region %v] record operator error: %v", record.RegionID, err)
+		}
+	}
+}
+
+// run flushes the changes as they come until the context is done, and
+// flushes the rest before it returns.
+func (p *operatorPersister) run(ctx context.Context) {
+	defer logutil.LogPanic()
+
+	for {
+		select {
+		case <-p.notify:
+			p.flush()
+		case <-ctx.Done():
+			p.flush()
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server/core"
+	"github.com/pingcap/pd/server/schedule"
+)
+
+var _ = Suite(&testOperatorPersisterSuite{})
+
+type testOperatorPersisterSuite struct{}
+
+func (s *testOperatorPersisterSuite) TestFlush(c *C) {
+	kv := core.NewKV(core.NewMemoryKV())
+	p := newOperatorPersister(kv)
+	meta := func(regionID uint64, desc string) *schedule.OperatorMeta {
+		return schedule.NewOperator(desc, regionID, nil, schedule.OpLeader, schedule.TransferLeader{FromStore: 1, ToStore: 2}).Meta()
+	}
+	load := func() map[uint64]string {
+		values, err := kv.LoadOperators(kvRangeLimit)
+		c.Assert(err, IsNil)
+		descs := make(map[uint64]string)
+		for _, value := range values {
+			m := &schedule.OperatorMeta{}
+			c.Assert(json.Unmarshal([]byte(value), m), IsNil)
+			descs[m.RegionID] = m.Desc
+		}
+		return descs
+	}
+
+	// The changes of a region are merged, and the last one is saved.
+	p.save(meta(1, "a"))
+	p.save(meta(2, "a"))
+	p.save(meta(1, "b"))
+	p.delete(2)
+	p.save(meta(3, "a"))
+	c.Assert(load(), HasLen, 0)
+	p.flush()
+	c.Assert(load(), DeepEquals, map[uint64]string{1: "b", 3: "a"})
+
+	// The pending changes are flushed when the loop is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.run(ctx)
+		close(done)
+	}()
+	p.delete(1)
+	cancel()
+	<-done
+	c.Assert(load(), DeepEquals, map[uint64]string{3: "a"})
+}
@@ -116,10 +116,99 @@ func (o *scheduleOption) GetMergeScheduleLimit(name string) uint64 {
 	return o.load().MergeScheduleLimit
 }
 
+func (o *scheduleOption) GetRegionWeightMode(name string) string {
+	if n, ok := o.ns[name]; ok && n.GetRegionWeightMode() != "" {
+		return n.GetRegionWeightMode()
+	}
+	return o.load().RegionWeightMode
+}
+
+func (o *scheduleOption) GetCapacityWeightSmoothing() float64 {
+	return o.load().CapacityWeightSmoothing
+}
+
+func (o *scheduleOption) GetCapacityWeightMinChange() float64 {
+	return o.load().CapacityWeightMinChange
+}
+
+func (o *scheduleOption) IsAdaptiveLeaderWeightEnabled() bool {
+	return o.load().AdaptiveLeaderWeight
+}
+
+func (o *scheduleOption) GetAdaptiveLeaderWeightWindow() int {
+	return int(o.load().AdaptiveLeaderWeightWindow)
+}
+
+func (o *scheduleOption) GetAdaptiveLeaderWeightStep() float64 {
+	return o.load().AdaptiveLeaderWeightStep
+}
+
+func (o *scheduleOption) GetAdaptiveLeaderFactorBounds() (float64, float64) {
+	cfg := o.load()
+	return cfg.MinAdaptiveLeaderFactor, cfg.MaxAdaptiveLeaderFactor
+}
+
 func (o *scheduleOption) GetTolerantSizeRatio() float64 {
 	return o.load().TolerantSizeRatio
 }
 
+func (o *scheduleOption) GetWeightPolicy() string {
+	return o.load().WeightPolicy
+}
+
+func (o *scheduleOption) GetWeightRampDuration() time.Duration {
+	return o.load().WeightRampDuration.Duration
+}
+
+func (o *scheduleOption) GetReplicaWeightTolerance() float64 {
+	return o.load().ReplicaWeightTolerance
+}
+
+func (o *scheduleOption) GetBalanceCandidateCount() int {
+	return int(o.load().BalanceCandidateCount)
+}
+
+func (o *scheduleOption) GetBalanceBatchSize() uint64 {
+	return o.load().BalanceBatchSize
+}
+
+func (o *scheduleOption) GetStoreBalanceLimit() uint64 {
+	return o.load().StoreBalanceLimit
+}
+
+func (o *scheduleOption) GetSpaceRatios() (float64, float64) {
+	cfg := o.load()
+	return cfg.HighSpaceRatio, cfg.SpaceHighWaterMark
+}
+
+func (o *scheduleOption) GetStoreLimitRate(typ core.StoreLimitType) float64 {
+	if typ == core.StoreRemovePeer {
+		return o.load().StoreRemovePeerRate
+	}
+	return o.load().StoreAddPeerRate
+}
+
+func (o *scheduleOption) GetOperatorHistoryLimit() uint64 {
+	return o.load().OperatorHistoryLimit
+}
+
+func (o *scheduleOption) GetSchedulePolicy(name string) *SchedulePolicy {
+	if p := o.load().SchedulePolicies.find(name); p != nil {
+		policy := p.clone()
+		return &policy
+	}
+	return nil
+}
+
+// GetSchedulePolicyLimit returns the cap on the schedule limits of the
+// scheduler or checker at the time, and false if it is not limited.
+func (o *scheduleOption) GetSchedulePolicyLimit(name string, t time.Time) (uint64, bool) {
+	if p := o.load().SchedulePolicies.find(name); p != nil {
+		return p.limitAt(t)
+	}
+	return 0, false
+}
+
 func (o *scheduleOption) GetSchedulers() SchedulerConfigs {
 	return o.load().Schedulers
 }
@@ -131,7 +220,7 @@ func (o *scheduleOption) AddSchedulerCfg(tp string, args []string) error {
 		// comparing args is to cover the case that there are schedulers in same type but not with same name
 		// such as two schedulers of type "evict-leader",
 		// one name is "evict-leader-scheduler-1" and the other is "evict-leader-scheduler-2"
-		if reflect.DeepEqual(schedulerCfg, SchedulerConfig{tp, args}) {
+		if schedulerCfg.Type == tp && reflect.DeepEqual(schedulerCfg.Args, args) {
 			return nil
 		}
 	}
@@ -143,19 +232,87 @@ func (o *scheduleOption) AddSchedulerCfg(tp string, args []string) error {
 func (o *scheduleOption) RemoveSchedulerCfg(name string) error {
 	c := o.load()
 	v := c.clone()
-	for i, schedulerCfg := range v.Schedulers {
+	i, err := findSchedulerCfg(v.Schedulers, name)
+	if err != nil || i < 0 {
+		return errors.Trace(err)
+	}
+	v.Schedulers = append(v.Schedulers[:i], v.Schedulers[i+1:]...)
+	o.store(v)
+	return nil
+}
+
+// SetSchedulerArgs sets the args of the scheduler config. If the args rename
+// the scheduler, its schedule policy is renamed with it, in place of the one
+// of the new name.
+func (o *scheduleOption) SetSchedulerArgs(name, newName string, args []string) error {
+	c := o.load()
+	v := c.clone()
+	i, err := findSchedulerCfg(v.Schedulers, name)
+	if err != nil || i < 0 {
+		return errors.Trace(err)
+	}
+	v.Schedulers[i].Args = args
+	if newName != name && v.SchedulePolicies.find(name) != nil {
+		policies := v.SchedulePolicies[:0]
+		for _, p := range v.SchedulePolicies {
+			if p.Name == newName {
+				continue
+			}
+			if p.Name == name {
+				p.Name = newName
+			}
+			policies = append(policies, p)
+		}
+		v.SchedulePolicies = policies
+	}
+	o.store(v)
+	return nil
+}
+
+// SetSchedulerDryRun sets the dry-run mode of the scheduler config.
+func (o *scheduleOption) SetSchedulerDryRun(name string, dryRun bool) error {
+	c := o.load()
+	v := c.clone()
+	i, err := findSchedulerCfg(v.Schedulers, name)
+	if err != nil || i < 0 {
+		return errors.Trace(err)
+	}
+	v.Schedulers[i].DryRun = dryRun
+	o.store(v)
+	return nil
+}
+
+// SetSchedulerPaused sets the paused state of the scheduler config. A zero
+// until means the scheduler is paused until it is resumed.
+func (o *scheduleOption) SetSchedulerPaused(name string, paused bool, until time.Time) error {
+	c := o.load()
+	v := c.clone()
+	i, err := findSchedulerCfg(v.Schedulers, name)
+	if err != nil || i < 0 {
+		return errors.Trace(err)
+	}
+	v.Schedulers[i].Paused, v.Schedulers[i].PausedUntil = paused, 0
+	if paused && !until.IsZero() {
+		v.Schedulers[i].PausedUntil = until.Unix()
+	}
+	o.store(v)
+	return nil
+}
+
+// findSchedulerCfg returns the index of the config of the scheduler, or -1 if
+// it is not found.
+func findSchedulerCfg(cfgs SchedulerConfigs, name string) (int, error) {
+	for i, schedulerCfg := range cfgs {
 		// To create a temporary scheduler is just used to get scheduler's name
 		tmp, err := schedule.CreateScheduler(schedulerCfg.Type, schedule.NewLimiter(), schedulerCfg.Args...)
 		if err != nil {
-			return errors.Trace(err)
+			return -1, errors.Trace(err)
 		}
 		if tmp.GetName() == name {
-			v.Schedulers = append(v.Schedulers[:i], v.Schedulers[i+1:]...)
-			o.store(v)
-			return nil
+			return i, nil
 		}
 	}
-	return nil
+	return -1, nil
 }
 
 func (o *scheduleOption) SetLabelProperty(typ, labelKey, labelValue string) {
@@ -327,3 +484,8 @@ func (n *namespaceOption) GetReplicaScheduleLimit() uint64 {
 func (n *namespaceOption) GetMergeScheduleLimit() uint64 {
 	return n.load().MergeScheduleLimit
 }
+
+// GetRegionWeightMode returns where the store region weights come from.
+func (n *namespaceOption) GetRegionWeightMode() string {
+	return n.load().RegionWeightMode
+}
@@ -71,6 +71,16 @@ func (m OpInfluence) GetStoreInfluence(id uint64) *StoreInfluence {
 	return storeInfluence
 }
 
+// Clone returns a copy of the OpInfluence.
+func (m OpInfluence) Clone() OpInfluence {
+	c := make(OpInfluence, len(m))
+	for id, influence := range m {
+		storeInfluence := *influence
+		c[id] = &storeInfluence
+	}
+	return c
+}
+
 // StoreInfluence records influences that pending operators will make.
 type StoreInfluence struct {
 	RegionSize  int
@@ -200,6 +200,38 @@ func (f *storageThresholdFilter) FilterTarget(opt Options, store *core.StoreInfo
 	return store.IsLowSpace()
 }
 
+type storeLimitFilter struct{}
+
+// NewStoreLimitFilter creates a Filter that filters all stores out of the
+// tokens to remove peers from as sources, or to add peers to as targets.
+func NewStoreLimitFilter() Filter {
+	return &storeLimitFilter{}
+}
+
+func (f *storeLimitFilter) FilterSource(opt Options, store *core.StoreInfo) bool {
+	return !store.IsAvailable(core.StoreRemovePeer)
+}
+
+func (f *storeLimitFilter) FilterTarget(opt Options, store *core.StoreInfo) bool {
+	return !store.IsAvailable(core.StoreAddPeer)
+}
+
+type regionSizeLimitFilter struct{}
+
+// NewRegionSizeLimitFilter creates a Filter that filters all stores whose
+// region size reaches the hard limit.
+func NewRegionSizeLimitFilter() Filter {
+	return &regionSizeLimitFilter{}
+}
+
+func (f *regionSizeLimitFilter) FilterSource(opt Options, store *core.StoreInfo) bool {
+	return false
+}
+
+func (f *regionSizeLimitFilter) FilterTarget(opt Options, store *core.StoreInfo) bool {
+	return store.IsOverRegionSizeHardLimit()
+}
+
 // distinctScoreFilter ensures that distinct score will not decrease.
 type distinctScoreFilter struct {
 	labels    []string
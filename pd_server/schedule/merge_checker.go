@@ -86,15 +86,38 @@ func (m *MergeChecker) Check(region *core.RegionInfo) (*Operator, *Operator) {
 		return nil, nil
 	}
 
-	checkerCounter.WithLabelValues("merge_checker", "new_operator").Inc()
 	log.Debugf("try to merge region {%v} into region {%v}", region, target)
 	op1, op2, err := CreateMergeRegionOperator("merge-region", m.cluster, region, target, OpMerge)
 	if err != nil {
 		return nil, nil
 	}
+	if !m.checkStoreLimit(op1) {
+		checkerCounter.WithLabelValues("merge_checker", "store_limit").Inc()
+		return nil, nil
+	}
+	checkerCounter.WithLabelValues("merge_checker", "new_operator").Inc()
 	return op1, op2
 }
 
+// checkStoreLimit checks if the stores have the tokens for the peers added
+// and removed by the operator.
+func (m *MergeChecker) checkStoreLimit(op *Operator) bool {
+	filter := NewStoreLimitFilter()
+	for _, step := range op.steps {
+		switch s := step.(type) {
+		case AddPeer:
+			if store := m.cluster.GetStore(s.ToStore); store != nil && filter.FilterTarget(m.cluster, store) {
+				return false
+			}
+		case RemovePeer:
+			if store := m.cluster.GetStore(s.FromStore); store != nil && filter.FilterSource(m.cluster, store) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (m *MergeChecker) checkTarget(region, adjacent, target *core.RegionInfo) *core.RegionInfo {
 	// if is not hot region and under same namesapce
 	if adjacent != nil && !m.cluster.IsRegionHot(adjacent.GetId()) &&
@@ -32,6 +32,7 @@ func NewNamespaceChecker(cluster Cluster, classifier namespace.Classifier) *Name
 	filters := []Filter{
 		NewHealthFilter(),
 		NewSnapshotCountFilter(),
+		NewStoreLimitFilter(),
 	}
 
 	return &NamespaceChecker{
@@ -95,6 +96,7 @@ func (n *NamespaceChecker) SelectBestStoreToRelocate(region *core.RegionInfo, ta
 	newFilters := []Filter{
 		NewStateFilter(),
 		NewStorageThresholdFilter(),
+		NewRegionSizeLimitFilter(),
 		NewExcludedFilter(nil, region.GetStoreIds()),
 	}
 	filters = append(filters, newFilters...)
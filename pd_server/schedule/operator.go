@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -149,29 +150,39 @@ func (mr MergeRegion) Influence(opInfluence OpInfluence, region *core.RegionInfo
 type Operator struct {
 	desc        string
 	regionID    uint64
+	regionEpoch *metapb.RegionEpoch
 	kind        OperatorKind
 	steps       []OperatorStep
 	currentStep int32
 	createTime  time.Time
 	stepTime    int64
 	level       core.PriorityLevel
+	statusMu    sync.Mutex
+	transitions []OperatorTransition
 }
 
-// NewOperator creates a new operator.
-func NewOperator(desc string, regionID uint64, kind OperatorKind, steps ...OperatorStep) *Operator {
+// NewOperator creates a new operator. The regionEpoch is the epoch of the
+// region when the operator is created, which is nil if it's unknown.
+func NewOperator(desc string, regionID uint64, regionEpoch *metapb.RegionEpoch, kind OperatorKind, steps ...OperatorStep) *Operator {
+	if regionEpoch != nil {
+		regionEpoch = &metapb.RegionEpoch{ConfVer: regionEpoch.GetConfVer(), Version: regionEpoch.GetVersion()}
+	}
+	now := time.Now()
 	return &Operator{
-		desc:       desc,
-		regionID:   regionID,
-		kind:       kind,
-		steps:      steps,
-		createTime: time.Now(),
-		stepTime:   time.Now().UnixNano(),
-		level:      core.NormalPriority,
+		desc:        desc,
+		regionID:    regionID,
+		regionEpoch: regionEpoch,
+		kind:        kind,
+		steps:       steps,
+		createTime:  now,
+		stepTime:    now.UnixNano(),
+		level:       core.NormalPriority,
+		transitions: []OperatorTransition{{Status: OperatorCreated, Time: now}},
 	}
 }
 
 func (o *Operator) String() string {
-	s := fmt.Sprintf("%s (kind:%s, region:%v, createAt:%s, currentStep:%v, steps:%+v) ", o.desc, o.kind, o.regionID, o.createTime, atomic.LoadInt32(&o.currentStep), o.steps)
+	s := fmt.Sprintf("%s (kind:%s, region:%v, createAt:%s, currentStep:%v, steps:%+v, status:%s) ", o.desc, o.kind, o.regionID, o.createTime, atomic.LoadInt32(&o.currentStep), o.steps, o.Status())
 	if o.IsTimeout() {
 		s = s + "timeout"
 	}
@@ -196,6 +207,11 @@ func (o *Operator) RegionID() uint64 {
 	return o.regionID
 }
 
+// RegionEpoch returns the epoch of the region when the operator is created.
+func (o *Operator) RegionEpoch() *metapb.RegionEpoch {
+	return o.regionEpoch
+}
+
 // Kind returns operator's kind.
 func (o *Operator) Kind() OperatorKind {
 	return o.kind
@@ -219,6 +235,11 @@ func (o *Operator) Step(i int) OperatorStep {
 	return nil
 }
 
+// CurrentStep returns the index of the current step.
+func (o *Operator) CurrentStep() int {
+	return int(atomic.LoadInt32(&o.currentStep))
+}
+
 // Check checks if current step is finished, returns next step to take action.
 // It's safe to be called by multiple goroutine concurrently.
 func (o *Operator) Check(region *core.RegionInfo) OperatorStep {
@@ -235,6 +256,36 @@ func (o *Operator) Check(region *core.RegionInfo) OperatorStep {
 	return nil
 }
 
+// CheckEpoch checks if the epoch of the region is changed only by the steps
+// of the operator, which are finished or in progress. It returns an error if
+// the region has changed unexpectedly, such as split or changed peers by
+// others. It should be called after Check.
+func (o *Operator) CheckEpoch(region *core.RegionInfo) error {
+	if o.regionEpoch == nil {
+		return nil
+	}
+	// The steps finished and the one in progress may change the epoch.
+	var confChanges uint64
+	current := int(atomic.LoadInt32(&o.currentStep))
+	for i := 0; i <= current && i < len(o.steps); i++ {
+		switch o.steps[i].(type) {
+		case AddPeer, RemovePeer:
+			confChanges++
+		case MergeRegion:
+			// Merge changes both the conf version and the version.
+			return nil
+		}
+	}
+	epoch := region.GetRegionEpoch()
+	if epoch.GetVersion() != o.regionEpoch.GetVersion() {
+		return errors.Errorf("region version changed from %d to %d", o.regionEpoch.GetVersion(), epoch.GetVersion())
+	}
+	if confVer := epoch.GetConfVer(); confVer < o.regionEpoch.GetConfVer() || confVer > o.regionEpoch.GetConfVer()+confChanges {
+		return errors.Errorf("region conf version changed from %d to %d, expect at most %d changes by the operator", o.regionEpoch.GetConfVer(), confVer, confChanges)
+	}
+	return nil
+}
+
 // SetPriorityLevel set the priority level for operator
 func (o *Operator) SetPriorityLevel(level core.PriorityLevel) {
 	o.level = level
@@ -267,6 +318,46 @@ func (o *Operator) Influence(opInfluence OpInfluence, region *core.RegionInfo) {
 	}
 }
 
+// Stores returns the stores that the steps of the operator change.
+func (o *Operator) Stores() []uint64 {
+	var stores []uint64
+	add := func(id uint64) {
+		for _, s := range stores {
+			if s == id {
+				return
+			}
+		}
+		stores = append(stores, id)
+	}
+	for _, step := range o.steps {
+		switch s := step.(type) {
+		case TransferLeader:
+			add(s.FromStore)
+			add(s.ToStore)
+		case AddPeer:
+			add(s.ToStore)
+		case RemovePeer:
+			add(s.FromStore)
+		}
+	}
+	return stores
+}
+
+// TargetStores returns the stores that the unfinished steps of the operator
+// add peers or transfer leaders to.
+func (o *Operator) TargetStores() []uint64 {
+	var stores []uint64
+	for step := atomic.LoadInt32(&o.currentStep); int(step) < len(o.steps); step++ {
+		switch s := o.steps[int(step)].(type) {
+		case TransferLeader:
+			stores = append(stores, s.ToStore)
+		case AddPeer:
+			stores = append(stores, s.ToStore)
+		}
+	}
+	return stores
+}
+
 // OperatorHistory is used to log and visualize completed operators.
 type OperatorHistory struct {
 	FinishTime time.Time
@@ -310,7 +401,7 @@ func (o *Operator) History() []OperatorHistory {
 // CreateRemovePeerOperator creates an Operator that removes a peer from region.
 func CreateRemovePeerOperator(desc string, cluster Cluster, kind OperatorKind, region *core.RegionInfo, storeID uint64) *Operator {
 	removeKind, steps := removePeerSteps(cluster, region, storeID)
-	return NewOperator(desc, region.GetId(), removeKind|kind, steps...)
+	return NewOperator(desc, region.GetId(), region.GetRegionEpoch(), removeKind|kind, steps...)
 }
 
 // CreateMovePeerOperator creates an Operator that replaces an old peer with a
@@ -318,7 +409,7 @@ func CreateRemovePeerOperator(desc string, cluster Cluster, kind OperatorKind, r
 func CreateMovePeerOperator(desc string, cluster Cluster, region *core.RegionInfo, kind OperatorKind, oldStore, newStore uint64, peerID uint64) *Operator {
 	removeKind, steps := removePeerSteps(cluster, region, oldStore)
 	steps = append([]OperatorStep{AddPeer{ToStore: newStore, PeerID: peerID}}, steps...)
-	return NewOperator(desc, region.GetId(), removeKind|kind|OpRegion, steps...)
+	return NewOperator(desc, region.GetId(), region.GetRegionEpoch(), removeKind|kind|OpRegion, steps...)
 }
 
 // removePeerSteps returns the steps to safely remove a peer. It prevents removing leader by transfer its leadership first.
@@ -351,8 +442,8 @@ func CreateMergeRegionOperator(desc string, cluster Cluster, source *core.Region
 		IsPassive:  false,
 	})
 
-	op1 := NewOperator(desc, source.GetId(), kinds|kind, steps...)
-	op2 := NewOperator(desc, target.GetId(), kind, MergeRegion{
+	op1 := NewOperator(desc, source.GetId(), source.GetRegionEpoch(), kinds|kind, steps...)
+	op2 := NewOperator(desc, target.GetId(), target.GetRegionEpoch(), kind, MergeRegion{
 		FromRegion: source.Region,
 		ToRegion:   target.Region,
 		IsPassive:  true,
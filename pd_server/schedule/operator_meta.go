@@ -0,0 +1,185 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
+)
+
+// Types of the persisted operator steps.
+const (
+	transferLeaderStepType = "transfer-leader"
+	addPeerStepType        = "add-peer"
+	removePeerStepType     = "remove-peer"
+	mergeRegionStepType    = "merge-region"
+)
+
+// OperatorMeta is the persisted form of an operator, which is used to resume
+// the operator after the PD leader changes.
+type OperatorMeta struct {
+	Desc        string               `json:"desc"`
+	RegionID    uint64               `json:"region_id"`
+	RegionEpoch *metapb.RegionEpoch  `json:"region_epoch,omitempty"`
+	Kind        OperatorKind         `json:"kind"`
+	Steps       []*OperatorStepMeta  `json:"steps"`
+	CurrentStep int32                `json:"current_step"`
+	CreateTime  time.Time            `json:"create_time"`
+	Level       core.PriorityLevel   `json:"level"`
+	Transitions []OperatorTransition `json:"transitions,omitempty"`
+}
+
+// OperatorStepMeta is the persisted form of an operator step.
+type OperatorStepMeta struct {
+	Type       string         `json:"type"`
+	FromStore  uint64         `json:"from_store,omitempty"`
+	ToStore    uint64         `json:"to_store,omitempty"`
+	PeerID     uint64         `json:"peer_id,omitempty"`
+	FromRegion *metapb.Region `json:"from_region,omitempty"`
+	ToRegion   *metapb.Region `json:"to_region,omitempty"`
+	IsPassive  bool           `json:"is_passive,omitempty"`
+}
+
+// Meta returns the persisted form of the operator.
+func (o *Operator) Meta() *OperatorMeta {
+	meta := &OperatorMeta{
+		Desc:        o.desc,
+		RegionID:    o.regionID,
+		RegionEpoch: o.regionEpoch,
+		Kind:        o.kind,
+		Steps:       make([]*OperatorStepMeta, 0, len(o.steps)),
+		CurrentStep: atomic.LoadInt32(&o.currentStep),
+		CreateTime:  o.createTime,
+		Level:       o.level,
+		Transitions: o.Transitions(),
+	}
+	for _, step := range o.steps {
+		switch s := step.(type) {
+		case TransferLeader:
+			meta.Steps = append(meta.Steps, &OperatorStepMeta{Type: transferLeaderStepType, FromStore: s.FromStore, ToStore: s.ToStore})
+		case AddPeer:
+			meta.Steps = append(meta.Steps, &OperatorStepMeta{Type: addPeerStepType, ToStore: s.ToStore, PeerID: s.PeerID})
+		case RemovePeer:
+			meta.Steps = append(meta.Steps, &OperatorStepMeta{Type: removePeerStepType, FromStore: s.FromStore})
+		case MergeRegion:
+			meta.Steps = append(meta.Steps, &OperatorStepMeta{Type: mergeRegionStepType, FromRegion: s.FromRegion, ToRegion: s.ToRegion, IsPassive: s.IsPassive})
+		}
+	}
+	return meta
+}
+
+// OperatorRecord is the record of an operator which has left the
+// coordinator, kept in the operator history.
+type OperatorRecord struct {
+	Seq           uint64               `json:"seq"`
+	Desc          string               `json:"desc"`
+	RegionID      uint64               `json:"region_id"`
+	Kind          string               `json:"kind"`
+	Stores        []uint64             `json:"stores"`
+	Steps         []*OperatorStepMeta  `json:"steps"`
+	FinishedSteps int                  `json:"finished_steps"`
+	CreateTime    time.Time            `json:"create_time"`
+	FinishTime    time.Time            `json:"finish_time"`
+	Status        OperatorStatus       `json:"status"`
+	Transitions   []OperatorTransition `json:"transitions"`
+}
+
+// Record returns the record of the operator, which is finished at the time of
+// its last status change.
+func (o *Operator) Record() *OperatorRecord {
+	meta := o.Meta()
+	last := meta.Transitions[len(meta.Transitions)-1]
+	return &OperatorRecord{
+		Desc:          meta.Desc,
+		RegionID:      meta.RegionID,
+		Kind:          meta.Kind.String(),
+		Stores:        o.Stores(),
+		Steps:         meta.Steps,
+		FinishedSteps: int(meta.CurrentStep),
+		CreateTime:    meta.CreateTime,
+		FinishTime:    last.Time,
+		Status:        last.Status,
+		Transitions:   meta.Transitions,
+	}
+}
+
+// NewOperatorFromMeta restores an operator from its persisted form, with the
+// current step and the create time.
+func NewOperatorFromMeta(meta *OperatorMeta) (*Operator, error) {
+	steps := make([]OperatorStep, 0, len(meta.Steps))
+	for _, s := range meta.Steps {
+		switch s.Type {
+		case transferLeaderStepType:
+			steps = append(steps, TransferLeader{FromStore: s.FromStore, ToStore: s.ToStore})
+		case addPeerStepType:
+			steps = append(steps, AddPeer{ToStore: s.ToStore, PeerID: s.PeerID})
+		case removePeerStepType:
+			steps = append(steps, RemovePeer{FromStore: s.FromStore})
+		case mergeRegionStepType:
+			steps = append(steps, MergeRegion{FromRegion: s.FromRegion, ToRegion: s.ToRegion, IsPassive: s.IsPassive})
+		default:
+			return nil, errors.Errorf("unknown operator step type %q", s.Type)
+		}
+	}
+	if meta.CurrentStep < 0 || int(meta.CurrentStep) > len(steps) {
+		return nil, errors.Errorf("invalid current step %d of %d steps", meta.CurrentStep, len(steps))
+	}
+	op := NewOperator(meta.Desc, meta.RegionID, meta.RegionEpoch, meta.Kind, steps...)
+	op.currentStep = meta.CurrentStep
+	op.createTime = meta.CreateTime
+	op.level = meta.Level
+	if len(meta.Transitions) > 0 {
+		op.transitions = meta.Transitions
+	}
+	return op, nil
+}
+
+// CreateRollbackOperator creates an Operator that removes the peers added by
+// the unfinished operator, so that the region doesn't keep extra replicas.
+// It returns nil if there is nothing to roll back.
+func CreateRollbackOperator(cluster Cluster, op *Operator, region *core.RegionInfo) *Operator {
+	current := int(atomic.LoadInt32(&op.currentStep))
+	var addedStores []uint64
+	var unfinished bool
+	for i, step := range op.steps {
+		switch s := step.(type) {
+		case AddPeer:
+			if i <= current && region.GetStorePeer(s.ToStore) != nil {
+				addedStores = append(addedStores, s.ToStore)
+			}
+		case RemovePeer, MergeRegion:
+			if i >= current {
+				unfinished = true
+			}
+		}
+	}
+	if len(addedStores) == 0 || !unfinished {
+		return nil
+	}
+
+	var kind OperatorKind
+	var steps []OperatorStep
+	for _, storeID := range addedStores {
+		k, s := removePeerSteps(cluster, region, storeID)
+		kind |= k
+		steps = append(steps, s...)
+	}
+	rollback := NewOperator("rollback-"+op.desc, region.GetId(), region.GetRegionEpoch(), kind, steps...)
+	rollback.SetPriorityLevel(core.HighPriority)
+	return rollback
+}
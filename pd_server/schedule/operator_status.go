@@ -0,0 +1,128 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// OperatorStatus is the status of an operator.
+type OperatorStatus int32
+
+// Status list of operators.
+const (
+	OperatorCreated              OperatorStatus = iota // Created by a scheduler, checker or admin.
+	OperatorStarted                                    // The first step is sent to the region.
+	OperatorSuccess                                    // All steps are finished.
+	OperatorTimeout                                    // The steps are not finished in time.
+	OperatorReplaced                                   // Replaced by an operator of higher priority.
+	OperatorCanceledByAdmin                            // Removed by admin.
+	OperatorCanceledEpochChanged                       // The region has changed unexpectedly.
+	OperatorCanceledStoreDown                          // A target store is down.
+	operatorStatusMax
+)
+
+var operatorStatusToName = map[OperatorStatus]string{
+	OperatorCreated:              "created",
+	OperatorStarted:              "started",
+	OperatorSuccess:              "success",
+	OperatorTimeout:              "timeout",
+	OperatorReplaced:             "replaced",
+	OperatorCanceledByAdmin:      "canceled-by-admin",
+	OperatorCanceledEpochChanged: "canceled-epoch-changed",
+	OperatorCanceledStoreDown:    "canceled-store-down",
+}
+
+func (s OperatorStatus) String() string {
+	if name, ok := operatorStatusToName[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// IsEnd checks if the status is final, which means the operator has left the
+// coordinator.
+func (s OperatorStatus) IsEnd() bool {
+	return s >= OperatorSuccess && s < operatorStatusMax
+}
+
+// MarshalJSON returns the name of the status.
+func (s OperatorStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON parses the name of the status.
+func (s *OperatorStatus) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return errors.Trace(err)
+	}
+	for status, n := range operatorStatusToName {
+		if n == name {
+			*s = status
+			return nil
+		}
+	}
+	return errors.Errorf("unknown operator status: %s", name)
+}
+
+// canTransit checks if an operator can change from the status to the next.
+// An operator ends from either status, and is started only once.
+func (s OperatorStatus) canTransit(next OperatorStatus) bool {
+	switch s {
+	case OperatorCreated:
+		return next == OperatorStarted || next.IsEnd()
+	case OperatorStarted:
+		return next.IsEnd()
+	}
+	return false
+}
+
+// OperatorTransition is a status change of an operator with the reason.
+type OperatorTransition struct {
+	Status OperatorStatus `json:"status"`
+	Reason string         `json:"reason,omitempty"`
+	Time   time.Time      `json:"time"`
+}
+
+// Status returns the current status of the operator.
+func (o *Operator) Status() OperatorStatus {
+	o.statusMu.Lock()
+	defer o.statusMu.Unlock()
+	return o.transitions[len(o.transitions)-1].Status
+}
+
+// Transitions returns the status changes of the operator, from the creation.
+func (o *Operator) Transitions() []OperatorTransition {
+	o.statusMu.Lock()
+	defer o.statusMu.Unlock()
+	transitions := make([]OperatorTransition, len(o.transitions))
+	copy(transitions, o.transitions)
+	return transitions
+}
+
+// SetStatus changes the status of the operator with the reason. It returns
+// false if the operator can't change to the status, such as it has ended.
+func (o *Operator) SetStatus(status OperatorStatus, reason string) bool {
+	o.statusMu.Lock()
+	defer o.statusMu.Unlock()
+	if !o.transitions[len(o.transitions)-1].Status.canTransit(status) {
+		return false
+	}
+	o.transitions = append(o.transitions, OperatorTransition{Status: status, Reason: reason, Time: time.Now()})
+	return true
+}
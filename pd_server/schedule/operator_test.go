@@ -15,6 +15,7 @@ package schedule
 
 import (
 	"encoding/json"
+	"strings"
 	"sync/atomic"
 
 	. "github.com/pingcap/check"
@@ -58,7 +59,7 @@ func (s *testOperatorSuite) TestOperatorStep(c *C) {
 }
 
 func (s *testOperatorSuite) newTestOperator(regionID uint64, steps ...OperatorStep) *Operator {
-	return NewOperator("testOperator", regionID, OpAdmin, steps...)
+	return NewOperator("testOperator", regionID, nil, OpAdmin, steps...)
 }
 
 func (s *testOperatorSuite) checkSteps(c *C, op *Operator, steps []OperatorStep) {
@@ -183,3 +184,142 @@ func (s *testOperatorSuite) TestOperatorKind(c *C) {
 	_, err = ParseOperatorKind("foobar")
 	c.Assert(err, NotNil)
 }
+
+func (s *testOperatorSuite) TestStores(c *C) {
+	op := s.newTestOperator(1, AddPeer{ToStore: 3, PeerID: 3}, TransferLeader{FromStore: 1, ToStore: 3}, RemovePeer{FromStore: 1})
+	c.Assert(op.Stores(), DeepEquals, []uint64{3, 1})
+	c.Assert(op.TargetStores(), DeepEquals, []uint64{3, 3})
+	atomic.StoreInt32(&op.currentStep, 2)
+	c.Assert(op.TargetStores(), HasLen, 0)
+
+	limiter := NewLimiter()
+	limiter.UpdateCounts(map[uint64]*Operator{
+		1: op,
+		2: s.newTestOperator(2, TransferLeader{FromStore: 1, ToStore: 2}),
+	})
+	c.Assert(limiter.StoreOperatorCount(1), Equals, uint64(2))
+	c.Assert(limiter.StoreOperatorCount(2), Equals, uint64(1))
+	c.Assert(limiter.StoreOperatorCount(3), Equals, uint64(1))
+	c.Assert(limiter.StoreOperatorCount(4), Equals, uint64(0))
+	c.Assert(limiter.HasOperator(2), IsTrue)
+	c.Assert(limiter.HasOperator(3), IsFalse)
+}
+
+func (s *testOperatorSuite) TestMeta(c *C) {
+	steps := []OperatorStep{
+		AddPeer{ToStore: 3, PeerID: 3},
+		TransferLeader{FromStore: 1, ToStore: 3},
+		RemovePeer{FromStore: 1},
+		MergeRegion{FromRegion: &metapb.Region{Id: 1}, ToRegion: &metapb.Region{Id: 2}, IsPassive: true},
+	}
+	op := NewOperator("test", 1, &metapb.RegionEpoch{ConfVer: 1, Version: 2}, OpAdmin|OpLeader|OpRegion, steps...)
+	op.SetPriorityLevel(core.HighPriority)
+	atomic.StoreInt32(&op.currentStep, 2)
+
+	data, err := json.Marshal(op.Meta())
+	c.Assert(err, IsNil)
+	meta := &OperatorMeta{}
+	c.Assert(json.Unmarshal(data, meta), IsNil)
+	restored, err := NewOperatorFromMeta(meta)
+	c.Assert(err, IsNil)
+	c.Assert(restored.Desc(), Equals, op.Desc())
+	c.Assert(restored.Kind(), Equals, op.Kind())
+	c.Assert(restored.CurrentStep(), Equals, 2)
+	c.Assert(restored.GetPriorityLevel(), Equals, core.HighPriority)
+	c.Assert(restored.createTime.Equal(op.createTime), IsTrue)
+	c.Assert(restored.steps, DeepEquals, steps)
+	c.Assert(restored.RegionEpoch(), DeepEquals, op.RegionEpoch())
+	c.Assert(restored.Status(), Equals, OperatorCreated)
+	c.Assert(restored.Transitions()[0].Time.Equal(op.Transitions()[0].Time), IsTrue)
+
+	meta.Steps[0].Type = "foo"
+	_, err = NewOperatorFromMeta(meta)
+	c.Assert(err, NotNil)
+}
+
+func (s *testOperatorSuite) TestStatus(c *C) {
+	op := s.newTestOperator(1, TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(op.Status(), Equals, OperatorCreated)
+	c.Assert(op.SetStatus(OperatorSuccess, "finished"), IsTrue)
+
+	op = s.newTestOperator(1, TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(op.SetStatus(OperatorStarted, "send step"), IsTrue)
+	c.Assert(op.SetStatus(OperatorStarted, "send step"), IsFalse)
+	c.Assert(op.SetStatus(OperatorTimeout, "slow"), IsTrue)
+	// The operator can't change after it ends.
+	c.Assert(op.SetStatus(OperatorCanceledByAdmin, "removed"), IsFalse)
+	c.Assert(op.Status(), Equals, OperatorTimeout)
+	c.Assert(strings.Contains(op.String(), "status:timeout"), IsTrue)
+
+	transitions := op.Transitions()
+	c.Assert(transitions, HasLen, 3)
+	for i, status := range []OperatorStatus{OperatorCreated, OperatorStarted, OperatorTimeout} {
+		c.Assert(transitions[i].Status, Equals, status)
+	}
+	c.Assert(transitions[2].Reason, Equals, "slow")
+
+	record := op.Record()
+	c.Assert(record.Status, Equals, OperatorTimeout)
+	c.Assert(record.FinishTime, Equals, transitions[2].Time)
+	data, err := json.Marshal(record)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), `"status":"timeout"`), IsTrue)
+	restored := &OperatorRecord{}
+	c.Assert(json.Unmarshal(data, restored), IsNil)
+	c.Assert(restored.Status, Equals, OperatorTimeout)
+	c.Assert(restored.Transitions[1].Status, Equals, OperatorStarted)
+
+	var status OperatorStatus
+	c.Assert(json.Unmarshal([]byte(`"foo"`), &status), NotNil)
+}
+
+func (s *testOperatorSuite) TestCheckEpoch(c *C) {
+	region := s.newTestRegion(1, 1, [2]uint64{1, 1}, [2]uint64{2, 2})
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 1, Version: 1}
+	op := NewOperator("test", 1, region.GetRegionEpoch(), OpRegion, AddPeer{ToStore: 3, PeerID: 3}, RemovePeer{FromStore: 2})
+	c.Assert(op.CheckEpoch(region), IsNil)
+
+	// The epoch is copied.
+	region.RegionEpoch.Version = 2
+	c.Assert(op.RegionEpoch().GetVersion(), Equals, uint64(1))
+	// The region is split.
+	c.Assert(op.CheckEpoch(region), NotNil)
+
+	// The peer on store 3 is being added.
+	region = s.newTestRegion(1, 1, [2]uint64{1, 1}, [2]uint64{2, 2}, [2]uint64{3, 3})
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 2, Version: 1}
+	region.PendingPeers = []*metapb.Peer{region.GetStorePeer(3)}
+	c.Assert(op.Check(region), Equals, AddPeer{ToStore: 3, PeerID: 3})
+	c.Assert(op.CheckEpoch(region), IsNil)
+	// Another peer is changed by others before the peer is added.
+	region.RegionEpoch.ConfVer = 3
+	c.Assert(op.CheckEpoch(region), NotNil)
+
+	// The peer on store 3 is added, and the one on store 2 is being removed.
+	region.PendingPeers = nil
+	c.Assert(op.Check(region), Equals, RemovePeer{FromStore: 2})
+	c.Assert(op.CheckEpoch(region), IsNil)
+	region.RegionEpoch.ConfVer = 4
+	c.Assert(op.CheckEpoch(region), NotNil)
+
+	// The operators without epochs are not checked.
+	op = NewOperator("test", 1, nil, OpRegion, AddPeer{ToStore: 3, PeerID: 3})
+	c.Assert(op.CheckEpoch(region), IsNil)
+}
+
+func (s *testOperatorSuite) TestRollback(c *C) {
+	region := s.newTestRegion(1, 1, [2]uint64{1, 1}, [2]uint64{2, 2}, [2]uint64{3, 3})
+	op := s.newTestOperator(1, AddPeer{ToStore: 3, PeerID: 3}, RemovePeer{FromStore: 2})
+
+	// The peer on store 3 is added, and the peer on store 2 is not removed.
+	op.Check(region)
+	c.Assert(op.CurrentStep(), Equals, 1)
+	rollback := CreateRollbackOperator(nil, op, region)
+	c.Assert(rollback, NotNil)
+	s.checkSteps(c, rollback, []OperatorStep{RemovePeer{FromStore: 3}})
+
+	// Nothing to roll back after the peer on store 2 is removed.
+	region.RemoveStorePeer(2)
+	op.Check(region)
+	c.Assert(CreateRollbackOperator(nil, op, region), IsNil)
+}
@@ -40,6 +40,10 @@ type Options interface {
 
 	GetHotRegionLowThreshold() int
 	GetTolerantSizeRatio() float64
+	GetReplicaWeightTolerance() float64
+	GetBalanceCandidateCount() int
+	GetBalanceBatchSize() uint64
+	GetStoreBalanceLimit() uint64
 
 	CheckLabelProperty(typ string, labels []*metapb.StoreLabel) bool
 }
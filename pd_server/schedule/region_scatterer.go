@@ -14,14 +14,39 @@
 package schedule
 
 import (
+	"math"
 	"math/rand"
 	"sync"
 
+	"github.com/juju/errors"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/namespace"
 )
 
+// ScatterMode decides how the scatterer selects the stores for the peers.
+type ScatterMode string
+
+// Scatter modes.
+const (
+	// ScatterModeRandom places the peers evenly across the stores.
+	ScatterModeRandom ScatterMode = "random"
+	// ScatterModeWeighted places the peers in proportion to the region
+	// weights of the stores.
+	ScatterModeWeighted ScatterMode = "weighted"
+)
+
+// ParseScatterMode parses the scatter mode. Empty means ScatterModeRandom.
+func ParseScatterMode(mode string) (ScatterMode, error) {
+	switch ScatterMode(mode) {
+	case "", ScatterModeRandom:
+		return ScatterModeRandom, nil
+	case ScatterModeWeighted:
+		return ScatterModeWeighted, nil
+	}
+	return "", errors.Errorf("unknown scatter mode %q", mode)
+}
+
 type selectedStores struct {
 	mu     sync.Mutex
 	stores map[uint64]struct{}
@@ -59,12 +84,82 @@ func (s *selectedStores) newFilter() Filter {
 	return NewExcludedFilter(nil, cloned)
 }
 
+// placedCount is the number of peers placed on a store and the region weight
+// of the store the count is based on.
+type placedCount struct {
+	count  float64
+	weight float64
+}
+
+// placedPeers counts the peers placed on the stores by weighted scattering.
+type placedPeers struct {
+	mu     sync.Mutex
+	counts map[uint64]*placedCount
+}
+
+func newPlacedPeers() *placedPeers {
+	return &placedPeers{
+		counts: make(map[uint64]*placedCount),
+	}
+}
+
+// score returns the number of peers per weight after placing one more peer on
+// the store. A store never seen before starts from the lowest score, and the
+// count of a store is rescaled when its weight changes, so that it does not
+// take all the peers to catch up.
+func (p *placedPeers) score(store *core.StoreInfo, stores []*core.StoreInfo) float64 {
+	weight := store.ResourceWeight(core.RegionKind)
+	c, ok := p.counts[store.GetId()]
+	if !ok {
+		c = &placedCount{count: p.minScore(stores) * weight, weight: weight}
+		p.counts[store.GetId()] = c
+	} else if c.weight != weight {
+		c.count, c.weight = c.count/c.weight*weight, weight
+	}
+	return (c.count + 1) / weight
+}
+
+// minScore returns the lowest number of peers per weight of the counted
+// stores, or 0 if there is none.
+func (p *placedPeers) minScore(stores []*core.StoreInfo) float64 {
+	minScore := math.MaxFloat64
+	for _, s := range stores {
+		if c, ok := p.counts[s.GetId()]; ok {
+			minScore = math.Min(minScore, c.count/c.weight)
+		}
+	}
+	if minScore == math.MaxFloat64 {
+		return 0
+	}
+	return minScore
+}
+
+func (p *placedPeers) put(storeID uint64) {
+	if c, ok := p.counts[storeID]; ok {
+		c.count++
+	}
+}
+
+// prune drops the counts of the stores which are removed from the cluster.
+func (p *placedPeers) prune(stores []*core.StoreInfo) {
+	ids := make(map[uint64]struct{}, len(stores))
+	for _, s := range stores {
+		ids[s.GetId()] = struct{}{}
+	}
+	for id := range p.counts {
+		if _, ok := ids[id]; !ok {
+			delete(p.counts, id)
+		}
+	}
+}
+
 // RegionScatterer scatters regions.
 type RegionScatterer struct {
 	cluster    Cluster
 	classifier namespace.Classifier
 	filters    []Filter
 	selected   *selectedStores
+	placed     *placedPeers
 }
 
 // NewRegionScatterer creates a region scatterer.
@@ -73,6 +168,8 @@ func NewRegionScatterer(cluster Cluster, classifier namespace.Classifier) *Regio
 		NewStateFilter(),
 		NewHealthFilter(),
 		NewStorageThresholdFilter(),
+		NewRegionSizeLimitFilter(),
+		NewStoreLimitFilter(),
 	}
 
 	return &RegionScatterer{
@@ -80,11 +177,12 @@ func NewRegionScatterer(cluster Cluster, classifier namespace.Classifier) *Regio
 		classifier: classifier,
 		filters:    filters,
 		selected:   newSelectedStores(),
+		placed:     newPlacedPeers(),
 	}
 }
 
 // Scatter relocates the region.
-func (r *RegionScatterer) Scatter(region *core.RegionInfo) *Operator {
+func (r *RegionScatterer) Scatter(region *core.RegionInfo, mode ScatterMode) *Operator {
 	if r.cluster.IsRegionHot(region.GetId()) {
 		return nil
 	}
@@ -93,18 +191,21 @@ func (r *RegionScatterer) Scatter(region *core.RegionInfo) *Operator {
 		return nil
 	}
 
+	if mode == ScatterModeWeighted {
+		return r.scatterRegionWeighted(region)
+	}
 	return r.scatterRegion(region)
 }
 
 func (r *RegionScatterer) scatterRegion(region *core.RegionInfo) *Operator {
 	steps := make([]OperatorStep, 0, len(region.GetPeers()))
 
-	stores := r.collectAvailableStores(region)
+	stores := r.collectAvailableStores(region, r.selected.newFilter())
 	for _, peer := range region.GetPeers() {
 		if len(stores) == 0 {
 			// Reset selected stores if we have no available stores.
 			r.selected.reset()
-			stores = r.collectAvailableStores(region)
+			stores = r.collectAvailableStores(region, r.selected.newFilter())
 		}
 
 		if r.selected.put(peer.GetStoreId()) {
@@ -129,7 +230,71 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo) *Operator {
 	if len(steps) == 0 {
 		return nil
 	}
-	return NewOperator("scatter-region", region.GetId(), OpAdmin, steps...)
+	return NewOperator("scatter-region", region.GetId(), region.GetRegionEpoch(), OpAdmin, steps...)
+}
+
+// scatterRegionWeighted keeps or replaces each peer of the region with the
+// store which has the least peers placed per region weight.
+func (r *RegionScatterer) scatterRegionWeighted(region *core.RegionInfo) *Operator {
+	r.placed.mu.Lock()
+	defer r.placed.mu.Unlock()
+
+	steps := make([]OperatorStep, 0, len(region.GetPeers()))
+	allStores := r.cluster.GetStores()
+	r.placed.prune(allStores)
+	chosen := make(map[uint64]struct{})
+	for _, peer := range region.GetPeers() {
+		stores := r.collectAvailableStores(region, NewExcludedFilter(nil, chosen))
+		target := r.selectWeightedStore(stores, allStores, region, peer)
+		if target == nil {
+			continue
+		}
+		chosen[target.GetId()] = struct{}{}
+		r.placed.put(target.GetId())
+		if target.GetId() == peer.GetStoreId() {
+			continue
+		}
+
+		newPeer, err := r.cluster.AllocPeer(target.GetId())
+		if err != nil {
+			continue
+		}
+		op := CreateMovePeerOperator("scatter-peer", r.cluster, region, OpAdmin,
+			peer.GetStoreId(), newPeer.GetStoreId(), newPeer.GetId())
+		steps = append(steps, op.steps...)
+		steps = append(steps, TransferLeader{ToStore: newPeer.GetStoreId()})
+	}
+
+	if len(steps) == 0 {
+		return nil
+	}
+	return NewOperator("scatter-region", region.GetId(), region.GetRegionEpoch(), OpAdmin, steps...)
+}
+
+// selectWeightedStore selects the store with the lowest placed peer score
+// among the store of the old peer and the candidate stores which can hold
+// the region without exceeding their hard size limits.
+func (r *RegionScatterer) selectWeightedStore(stores map[uint64]*core.StoreInfo, allStores []*core.StoreInfo, region *core.RegionInfo, oldPeer *metapb.Peer) *core.StoreInfo {
+	sourceStore := r.cluster.GetStore(oldPeer.GetStoreId())
+	if sourceStore == nil {
+		return nil
+	}
+	regionStores := r.cluster.GetRegionStores(region)
+	scoreGuard := NewDistinctScoreFilter(r.cluster.GetLocationLabels(), regionStores, sourceStore)
+
+	best, bestScore := sourceStore, r.placed.score(sourceStore, allStores)
+	for _, store := range stores {
+		if scoreGuard.FilterTarget(r.cluster, store) {
+			continue
+		}
+		if limit := store.RegionSizeHardLimit; limit > 0 && store.RegionSize+region.ApproximateSize >= limit {
+			continue
+		}
+		if score := r.placed.score(store, allStores); score < bestScore {
+			best, bestScore = store, score
+		}
+	}
+	return best
 }
 
 func (r *RegionScatterer) selectPeerToReplace(stores map[uint64]*core.StoreInfo, region *core.RegionInfo, oldPeer *metapb.Peer) *metapb.Peer {
@@ -158,13 +323,13 @@ func (r *RegionScatterer) selectPeerToReplace(stores map[uint64]*core.StoreInfo,
 	return newPeer
 }
 
-func (r *RegionScatterer) collectAvailableStores(region *core.RegionInfo) map[uint64]*core.StoreInfo {
+func (r *RegionScatterer) collectAvailableStores(region *core.RegionInfo, extraFilters ...Filter) map[uint64]*core.StoreInfo {
 	namespace := r.classifier.GetRegionNamespace(region)
 	filters := []Filter{
-		r.selected.newFilter(),
 		NewExcludedFilter(nil, region.GetStoreIds()),
 		NewNamespaceFilter(r.classifier, namespace),
 	}
+	filters = append(filters, extraFilters...)
 	filters = append(filters, r.filters...)
 
 	stores := r.cluster.GetStores()
@@ -0,0 +1,62 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
+)
+
+var _ = Suite(&testPlacedPeersSuite{})
+
+type testPlacedPeersSuite struct{}
+
+func (s *testPlacedPeersSuite) TestWeightChange(c *C) {
+	stores := make([]*core.StoreInfo, 0, 3)
+	for i := uint64(1); i <= 3; i++ {
+		store := core.NewStoreInfo(&metapb.Store{Id: i})
+		store.RegionWeight = 1
+		stores = append(stores, store)
+	}
+	p := newPlacedPeers()
+	place := func() uint64 {
+		best, bestScore := stores[0], p.score(stores[0], stores)
+		for _, store := range stores[1:] {
+			if score := p.score(store, stores); score < bestScore {
+				best, bestScore = store, score
+			}
+		}
+		p.put(best.GetId())
+		return best.GetId()
+	}
+	for i := 0; i < 30; i++ {
+		place()
+	}
+
+	// Store 1 doubles its weight, so it takes half of the new peers instead
+	// of all of them until its lifetime count catches up.
+	stores[0].RegionWeight = 2
+	placed := make(map[uint64]int)
+	for i := 0; i < 8; i++ {
+		placed[place()]++
+	}
+	c.Assert(placed[1], Equals, 4)
+	c.Assert(placed[2], Equals, 2)
+	c.Assert(placed[3], Equals, 2)
+
+	// The counts of removed stores are dropped.
+	p.prune(stores[:2])
+	c.Assert(p.counts, HasLen, 2)
+}
@@ -48,7 +48,8 @@ func compareStoreScore(storeA *core.StoreInfo, scoreA float64, storeB *core.Stor
 	if scoreA < scoreB {
 		return -1
 	}
-	// The store with lower region score is better.
+	// The store with lower region score, which is normalized by the region
+	// weight, is further below its weighted share and is better.
 	if storeA.RegionScore() < storeB.RegionScore() {
 		return 1
 	}
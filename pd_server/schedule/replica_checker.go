@@ -32,6 +32,7 @@ func NewReplicaChecker(cluster Cluster, classifier namespace.Classifier) *Replic
 	filters := []Filter{
 		NewHealthFilter(),
 		NewSnapshotCountFilter(),
+		NewStoreLimitFilter(),
 	}
 
 	return &ReplicaChecker{
@@ -64,7 +65,7 @@ func (r *ReplicaChecker) Check(region *core.RegionInfo) *Operator {
 		}
 		step := AddPeer{ToStore: newPeer.GetStoreId(), PeerID: newPeer.GetId()}
 		checkerCounter.WithLabelValues("replica_checker", "new_operator").Inc()
-		return NewOperator("makeUpReplica", region.GetId(), OpReplica|OpRegion, step)
+		return NewOperator("makeUpReplica", region.GetId(), region.GetRegionEpoch(), OpReplica|OpRegion, step)
 	}
 
 	if len(region.GetPeers()) > r.cluster.GetMaxReplicas() {
@@ -122,6 +123,7 @@ func (r *ReplicaChecker) selectBestStoreToAddReplica(region *core.RegionInfo, fi
 	newFilters := []Filter{
 		NewStateFilter(),
 		NewStorageThresholdFilter(),
+		NewRegionSizeLimitFilter(),
 		NewPendingPeerCountFilter(),
 		NewExcludedFilter(nil, region.GetStoreIds()),
 	}
@@ -219,8 +221,9 @@ func (r *ReplicaChecker) checkBestReplacement(region *core.RegionInfo) *Operator
 		checkerCounter.WithLabelValues("replica_checker", "no_replacement_store")
 		return nil
 	}
-	// Make sure the new peer is better than the old peer.
-	if newScore <= oldScore {
+	// Make sure the new peer is better than the old peer. If they are equally
+	// isolated, the peer is moved only to shed the overweight of the old store.
+	if newScore < oldScore || (newScore == oldScore && !r.shouldRelocateByWeight(region, oldPeer.GetStoreId(), storeID)) {
 		log.Debugf("[region %d] newScore %f is not better than oldScore %f", region.GetId(), newScore, oldScore)
 		checkerCounter.WithLabelValues("replica_checker", "not_better")
 		return nil
@@ -232,3 +235,29 @@ func (r *ReplicaChecker) checkBestReplacement(region *core.RegionInfo) *Operator
 	checkerCounter.WithLabelValues("replica_checker", "new_operator").Inc()
 	return CreateMovePeerOperator("moveToBetterLocation", r.cluster, region, OpReplica, oldPeer.GetStoreId(), newPeer.GetStoreId(), newPeer.GetId())
 }
+
+// shouldRelocateByWeight checks if a peer should be moved from the source
+// store, whose region score exceeds the weighted average by more than the
+// tolerance, to the target store below the average. The move must not make
+// the target store's score higher than the source store's.
+func (r *ReplicaChecker) shouldRelocateByWeight(region *core.RegionInfo, sourceID, targetID uint64) bool {
+	source, target := r.cluster.GetStore(sourceID), r.cluster.GetStore(targetID)
+	if source == nil || target == nil {
+		return false
+	}
+	var filters []Filter
+	if r.classifier != nil {
+		filters = append(filters, NewNamespaceFilter(r.classifier, r.classifier.GetRegionNamespace(region)))
+	}
+	avgScore := r.cluster.GetStoresAverageScore(core.RegionKind, filters...)
+	if avgScore <= 0 {
+		return false
+	}
+	if (source.RegionScore()-avgScore)/avgScore <= r.cluster.GetReplicaWeightTolerance() || target.RegionScore() >= avgScore {
+		return false
+	}
+	size := float64(region.ApproximateSize)
+	sourceScore := (float64(source.RegionSize) - size) / source.ResourceWeight(core.RegionKind)
+	targetScore := (float64(target.RegionSize) + size) / target.ResourceWeight(core.RegionKind)
+	return sourceScore >= targetScore
+}
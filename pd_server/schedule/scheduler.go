@@ -55,6 +55,13 @@ type Cluster interface {
 	AllocPeer(storeID uint64) (*metapb.Peer, error)
 }
 
+// PolicyCluster is a cluster whose schedule limits are capped by a schedule
+// policy. Schedulers with limits of their own should respect the cap too.
+type PolicyCluster interface {
+	Cluster
+	GetPolicyLimit() uint64
+}
+
 // Scheduler is an interface to schedule resources.
 type Scheduler interface {
 	GetName() string
@@ -68,6 +75,22 @@ type Scheduler interface {
 	IsScheduleAllowed(cluster Cluster) bool
 }
 
+// ConfigurableScheduler is a scheduler whose config can be changed at runtime.
+type ConfigurableScheduler interface {
+	Scheduler
+	// GetConfig returns the config items of the scheduler by names.
+	GetConfig() map[string]interface{}
+	// ParseConfig validates the config items in the input, and returns the
+	// args to create the scheduler with them applied, without applying them.
+	ParseConfig(config map[string]interface{}) ([]string, error)
+	// UpdateConfig validates and applies the config items in the input, and
+	// the items not in the input are kept.
+	UpdateConfig(cluster Cluster, config map[string]interface{}) error
+	// GetArgs returns the args to create the scheduler with the current
+	// config.
+	GetArgs() []string
+}
+
 // CreateSchedulerFunc is for creating scheudler.
 type CreateSchedulerFunc func(limiter *Limiter, args []string) (Scheduler, error)
 
@@ -95,12 +118,18 @@ func CreateScheduler(name string, limiter *Limiter, args ...string) (Scheduler,
 type Limiter struct {
 	sync.RWMutex
 	counts map[OperatorKind]uint64
+	// regions are the regions with pending operators.
+	regions map[uint64]struct{}
+	// stores are the counts of pending operators by the stores they change.
+	stores map[uint64]uint64
 }
 
 // NewLimiter create a schedule limiter
 func NewLimiter() *Limiter {
 	return &Limiter{
-		counts: make(map[OperatorKind]uint64),
+		counts:  make(map[OperatorKind]uint64),
+		regions: make(map[uint64]struct{}),
+		stores:  make(map[uint64]uint64),
 	}
 }
 
@@ -111,11 +140,33 @@ func (l *Limiter) UpdateCounts(operators map[uint64]*Operator) {
 	for k := range l.counts {
 		delete(l.counts, k)
 	}
+	l.regions = make(map[uint64]struct{}, len(operators))
+	l.stores = make(map[uint64]uint64)
 	for _, op := range operators {
 		l.counts[op.Kind()]++
+		l.regions[op.RegionID()] = struct{}{}
+		for _, id := range op.Stores() {
+			l.stores[id]++
+		}
 	}
 }
 
+// HasOperator checks if the region has a pending operator.
+func (l *Limiter) HasOperator(regionID uint64) bool {
+	l.RLock()
+	defer l.RUnlock()
+	_, ok := l.regions[regionID]
+	return ok
+}
+
+// StoreOperatorCount gets the count of pending operators which change the
+// store.
+func (l *Limiter) StoreOperatorCount(storeID uint64) uint64 {
+	l.RLock()
+	defer l.RUnlock()
+	return l.stores[storeID]
+}
+
 // OperatorCount gets the count of operators filtered by mask.
 func (l *Limiter) OperatorCount(mask OperatorKind) uint64 {
 	l.RLock()
@@ -0,0 +1,192 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/pd/server/core"
+)
+
+const (
+	// traceLimit is the max number of schedule attempts kept by a Tracer.
+	traceLimit = 100
+	// traceEventLimit is the max number of events kept for each attempt.
+	traceEventLimit = 1000
+)
+
+// TraceEvent is a decision made by a scheduler in a schedule attempt, such as
+// a store selected or rejected, or a region skipped.
+type TraceEvent struct {
+	Event    string `json:"event"`
+	StoreID  uint64 `json:"store_id,omitempty"`
+	RegionID uint64 `json:"region_id,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ScheduleTrace is the decisions made in a schedule attempt.
+type ScheduleTrace struct {
+	Time      time.Time     `json:"time"`
+	Events    []*TraceEvent `json:"events"`
+	Dropped   int           `json:"dropped,omitempty"`
+	Operators int           `json:"operators"`
+}
+
+// TraceableScheduler is a scheduler which records its decisions in a Tracer.
+type TraceableScheduler interface {
+	Scheduler
+	GetTracer() *Tracer
+}
+
+// Tracer records the decisions of the latest schedule attempts of a scheduler
+// when it is enabled. All methods are no-op on a nil Tracer.
+type Tracer struct {
+	enabled int32
+
+	mu      sync.Mutex
+	current *ScheduleTrace
+	traces  []*ScheduleTrace
+	next    int
+}
+
+// NewTracer creates a disabled Tracer.
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+// Enable enables or disables the Tracer. The traces are cleared when it is
+// disabled.
+func (t *Tracer) Enable(enable bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if enable {
+		atomic.StoreInt32(&t.enabled, 1)
+		return
+	}
+	atomic.StoreInt32(&t.enabled, 0)
+	t.current, t.traces, t.next = nil, nil, 0
+}
+
+// IsEnabled checks if the Tracer is enabled.
+func (t *Tracer) IsEnabled() bool {
+	return t != nil && atomic.LoadInt32(&t.enabled) != 0
+}
+
+// Begin starts to record a schedule attempt.
+func (t *Tracer) Begin() {
+	if !t.IsEnabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = &ScheduleTrace{Time: time.Now()}
+}
+
+// End finishes the schedule attempt with the number of operators created.
+func (t *Tracer) End(operators int) {
+	if !t.IsEnabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return
+	}
+	t.current.Operators = operators
+	if len(t.traces) < traceLimit {
+		t.traces = append(t.traces, t.current)
+	} else {
+		t.traces[t.next] = t.current
+		t.next = (t.next + 1) % traceLimit
+	}
+	t.current = nil
+}
+
+// Record records an event of the schedule attempt. The store and region are
+// 0 if the event is not about them.
+func (t *Tracer) Record(event string, storeID, regionID uint64, format string, args ...interface{}) {
+	if !t.IsEnabled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil {
+		return
+	}
+	if len(t.current.Events) >= traceEventLimit {
+		t.current.Dropped++
+		return
+	}
+	t.current.Events = append(t.current.Events, &TraceEvent{
+		Event:    event,
+		StoreID:  storeID,
+		RegionID: regionID,
+		Detail:   fmt.Sprintf(format, args...),
+	})
+}
+
+// GetTraces returns the recorded attempts from the oldest to the latest.
+func (t *Tracer) GetTraces() []*ScheduleTrace {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	traces := make([]*ScheduleTrace, 0, len(t.traces))
+	traces = append(traces, t.traces[t.next:]...)
+	return append(traces, t.traces[:t.next]...)
+}
+
+// WrapFilters wraps the filters to record the stores they reject.
+func (t *Tracer) WrapFilters(filters []Filter) []Filter {
+	wrapped := make([]Filter, 0, len(filters))
+	for _, f := range filters {
+		wrapped = append(wrapped, &traceFilter{
+			Filter: f,
+			name:   strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", f), "*schedule."), "Filter"),
+			tracer: t,
+		})
+	}
+	return wrapped
+}
+
+// traceFilter records the stores rejected by the filter.
+type traceFilter struct {
+	Filter
+	name   string
+	tracer *Tracer
+}
+
+func (f *traceFilter) FilterSource(opt Options, store *core.StoreInfo) bool {
+	if f.Filter.FilterSource(opt, store) {
+		f.tracer.Record("filter-source", store.GetId(), 0, "%s", f.name)
+		return true
+	}
+	return false
+}
+
+func (f *traceFilter) FilterTarget(opt Options, store *core.StoreInfo) bool {
+	if f.Filter.FilterTarget(opt, store) {
+		f.tracer.Record("filter-target", store.GetId(), 0, "%s", f.name)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testTracerSuite{})
+
+type testTracerSuite struct{}
+
+func (s *testTracerSuite) TestTracer(c *C) {
+	var nilTracer *Tracer
+	nilTracer.Begin()
+	nilTracer.Record("test", 1, 0, "")
+	nilTracer.End(0)
+	c.Assert(nilTracer.IsEnabled(), IsFalse)
+	c.Assert(nilTracer.GetTraces(), HasLen, 0)
+
+	tracer := NewTracer()
+	tracer.Enable(true)
+	for i := 0; i < traceLimit+10; i++ {
+		tracer.Begin()
+		tracer.Record("test", uint64(i), 0, "attempt %d", i)
+		tracer.End(i)
+	}
+	traces := tracer.GetTraces()
+	c.Assert(traces, HasLen, traceLimit)
+	c.Assert(traces[0].Operators, Equals, 10)
+	c.Assert(traces[0].Events[0].Detail, Equals, "attempt 10")
+	c.Assert(traces[traceLimit-1].Operators, Equals, traceLimit+9)
+
+	// The events of an attempt are bounded.
+	tracer.Begin()
+	for i := 0; i < traceEventLimit+5; i++ {
+		tracer.Record("test", 0, uint64(i), "")
+	}
+	tracer.End(0)
+	traces = tracer.GetTraces()
+	c.Assert(traces[traceLimit-1].Events, HasLen, traceEventLimit)
+	c.Assert(traces[traceLimit-1].Dropped, Equals, 5)
+
+	// The traces are cleared when disabled.
+	tracer.Enable(false)
+	c.Assert(tracer.GetTraces(), HasLen, 0)
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server/schedule"
+)
+
+// Names of the checkers that schedule policies can apply to, besides the
+// schedulers.
+const (
+	mergeCheckerName   = "merge-checker"
+	replicaCheckerName = "replica-checker"
+)
+
+// SchedulePolicy limits a scheduler or a checker outside its time windows.
+type SchedulePolicy struct {
+	// Name is the name of a scheduler, or "merge-checker" or "replica-checker".
+	Name string `toml:"name" json:"name"`
+	// Windows are the ranges of local time like "01:00-06:00" in which
+	// scheduling is allowed as usual. A window may cross midnight.
+	Windows []string `toml:"windows,omitempty" json:"windows"`
+	// OutsideLimit caps the schedule limits outside the windows, including
+	// the limits of schedulers like adjacent-region and hot-region. 0 means
+	// no scheduling at all.
+	OutsideLimit uint64 `toml:"outside-limit,omitempty" json:"outside-limit"`
+}
+
+func (p SchedulePolicy) clone() SchedulePolicy {
+	windows := make([]string, len(p.Windows))
+	copy(windows, p.Windows)
+	p.Windows = windows
+	return p
+}
+
+func (p SchedulePolicy) validate() error {
+	if p.Name == "" {
+		return errors.New("schedule policy needs a name")
+	}
+	for _, w := range p.Windows {
+		if _, err := parseTimeWindow(w); err != nil {
+			return errors.Annotatef(err, "schedule policy %s", p.Name)
+		}
+	}
+	return nil
+}
+
+// InWindow checks if the time is in one of the windows of the policy.
+func (p SchedulePolicy) InWindow(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range p.Windows {
+		window, err := parseTimeWindow(w)
+		if err == nil && window.contains(minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitAt returns the cap on the schedule limit at the time, and false if the
+// time is in a window thus not limited.
+func (p SchedulePolicy) limitAt(t time.Time) (uint64, bool) {
+	if p.InWindow(t) {
+		return 0, false
+	}
+	return p.OutsideLimit, true
+}
+
+// SchedulePolicies is a slice of schedule policies.
+type SchedulePolicies []SchedulePolicy
+
+func (ps SchedulePolicies) clone() SchedulePolicies {
+	if ps == nil {
+		return nil
+	}
+	policies := make(SchedulePolicies, 0, len(ps))
+	for _, p := range ps {
+		policies = append(policies, p.clone())
+	}
+	return policies
+}
+
+func (ps SchedulePolicies) validate() error {
+	names := make(map[string]struct{}, len(ps))
+	for _, p := range ps {
+		if err := p.validate(); err != nil {
+			return errors.Trace(err)
+		}
+		if _, ok := names[p.Name]; ok {
+			return errors.Errorf("duplicated schedule policy %s", p.Name)
+		}
+		names[p.Name] = struct{}{}
+	}
+	return nil
+}
+
+func (ps SchedulePolicies) find(name string) *SchedulePolicy {
+	for i := range ps {
+		if ps[i].Name == name {
+			return &ps[i]
+		}
+	}
+	return nil
+}
+
+// timeWindow is a range of minutes in a day, from start (inclusive) to end
+// (exclusive). It crosses midnight if start is greater than end.
+type timeWindow struct {
+	start int
+	end   int
+}
+
+func parseTimeWindow(s string) (timeWindow, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return timeWindow{}, errors.Errorf("invalid time window %q, should be like 01:00-06:00", s)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return timeWindow{}, errors.Trace(err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return timeWindow{}, errors.Trace(err)
+	}
+	if start == end {
+		return timeWindow{}, errors.Errorf("empty time window %q", s)
+	}
+	return timeWindow{start: start, end: end}, nil
+}
+
+// parseClock parses a time of day like "06:30" to the minutes since midnight.
+func parseClock(s string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, errors.Errorf("invalid time %q, should be like 06:30", s)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, errors.Errorf("invalid hour in time %q", s)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, errors.Errorf("invalid minute in time %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+func (w timeWindow) contains(minute int) bool {
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// policyCluster caps the schedule limits of the cluster outside the time
+// windows of a schedule policy.
+type policyCluster struct {
+	schedule.Cluster
+	limit uint64
+}
+
+func (c policyCluster) GetPolicyLimit() uint64 {
+	return c.limit
+}
+
+func (c policyCluster) GetLeaderScheduleLimit() uint64 {
+	return minUint64(c.Cluster.GetLeaderScheduleLimit(), c.limit)
+}
+
+func (c policyCluster) GetRegionScheduleLimit() uint64 {
+	return minUint64(c.Cluster.GetRegionScheduleLimit(), c.limit)
+}
+
+func (c policyCluster) GetReplicaScheduleLimit() uint64 {
+	return minUint64(c.Cluster.GetReplicaScheduleLimit(), c.limit)
+}
+
+func (c policyCluster) GetMergeScheduleLimit() uint64 {
+	return minUint64(c.Cluster.GetMergeScheduleLimit(), c.limit)
+}
+
+// GetOpt caps the limits of namespaces as well, as namespace clusters get
+// their limits from it.
+func (c policyCluster) GetOpt() schedule.NamespaceOptions {
+	return policyOptions{NamespaceOptions: c.Cluster.GetOpt(), limit: c.limit}
+}
+
+type policyOptions struct {
+	schedule.NamespaceOptions
+	limit uint64
+}
+
+func (o policyOptions) GetLeaderScheduleLimit(name string) uint64 {
+	return minUint64(o.NamespaceOptions.GetLeaderScheduleLimit(name), o.limit)
+}
+
+func (o policyOptions) GetRegionScheduleLimit(name string) uint64 {
+	return minUint64(o.NamespaceOptions.GetRegionScheduleLimit(name), o.limit)
+}
+
+func (o policyOptions) GetReplicaScheduleLimit(name string) uint64 {
+	return minUint64(o.NamespaceOptions.GetReplicaScheduleLimit(name), o.limit)
+}
+
+func (o policyOptions) GetMergeScheduleLimit(name string) uint64 {
+	return minUint64(o.NamespaceOptions.GetMergeScheduleLimit(name), o.limit)
+}
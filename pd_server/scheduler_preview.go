@@ -0,0 +1,81 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/server/schedule"
+)
+
+// schedulerPreviewLimit is the max number of previews kept for each dry-run
+// scheduler.
+const schedulerPreviewLimit = 100
+
+// SchedulerPreview is the operators a dry-run scheduler creates in one round,
+// with the influence of the pending operators it assumes.
+type SchedulerPreview struct {
+	Time        time.Time            `json:"time"`
+	Operators   []*schedule.Operator `json:"operators"`
+	OpInfluence schedule.OpInfluence `json:"op-influence"`
+}
+
+// schedulerPreviews is a bounded buffer of the latest previews.
+type schedulerPreviews struct {
+	sync.Mutex
+	previews []*SchedulerPreview
+	next     int
+}
+
+func newSchedulerPreviews() *schedulerPreviews {
+	return &schedulerPreviews{
+		previews: make([]*SchedulerPreview, 0, schedulerPreviewLimit),
+	}
+}
+
+func (p *schedulerPreviews) add(ops []*schedule.Operator, opInfluence schedule.OpInfluence) {
+	p.Lock()
+	defer p.Unlock()
+	preview := &SchedulerPreview{
+		Time:        time.Now(),
+		Operators:   ops,
+		OpInfluence: opInfluence,
+	}
+	if len(p.previews) < schedulerPreviewLimit {
+		p.previews = append(p.previews, preview)
+		return
+	}
+	p.previews[p.next] = preview
+	p.next = (p.next + 1) % schedulerPreviewLimit
+}
+
+// list returns the previews from the oldest to the latest.
+func (p *schedulerPreviews) list() []*SchedulerPreview {
+	p.Lock()
+	defer p.Unlock()
+	previews := make([]*SchedulerPreview, 0, len(p.previews))
+	previews = append(previews, p.previews[p.next:]...)
+	return append(previews, p.previews[:p.next]...)
+}
+
+// dryRunCluster ignores the changes made to the cluster by dry-run
+// schedulers, such as blocking stores.
+type dryRunCluster struct {
+	schedule.Cluster
+}
+
+func (c dryRunCluster) BlockStore(id uint64) error { return nil }
+
+func (c dryRunCluster) UnblockStore(id uint64) {}
@@ -16,6 +16,7 @@ package schedulers
 import (
 	"bytes"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -39,7 +40,7 @@ func init() {
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
-			peerLimit, err := strconv.ParseUint(args[0], 10, 64)
+			peerLimit, err := strconv.ParseUint(args[1], 10, 64)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
@@ -57,8 +58,8 @@ func init() {
 type balanceAdjacentRegionScheduler struct {
 	*baseScheduler
 	selector             schedule.Selector
-	leaderLimit          uint64
-	peerLimit            uint64
+	leaderLimit          uint64 // accessed atomically, as it can be changed by config.
+	peerLimit            uint64 // accessed atomically, as it can be changed by config.
 	lastKey              []byte
 	cacheRegions         *adjacentState
 	adjacentRegionsCount int
@@ -89,6 +90,7 @@ func newBalanceAdjacentRegionScheduler(limiter *schedule.Limiter, args ...uint64
 		schedule.NewHealthFilter(),
 		schedule.NewSnapshotCountFilter(),
 		schedule.NewStorageThresholdFilter(),
+		schedule.NewRegionSizeLimitFilter(),
 		schedule.NewPendingPeerCountFilter(),
 		schedule.NewRejectLeaderFilter(),
 	}
@@ -124,15 +126,74 @@ func (l *balanceAdjacentRegionScheduler) GetNextInterval(interval time.Duration)
 }
 
 func (l *balanceAdjacentRegionScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
+	if reachPolicyLimit(cluster, l.limiter, schedule.OpAdjacent) {
+		return false
+	}
 	return l.allowBalanceLeader() || l.allowBalanceLeader()
 }
 
 func (l *balanceAdjacentRegionScheduler) allowBalanceLeader() bool {
-	return l.limiter.OperatorCount(schedule.OpAdjacent|schedule.OpLeader) < l.leaderLimit
+	return l.limiter.OperatorCount(schedule.OpAdjacent|schedule.OpLeader) < atomic.LoadUint64(&l.leaderLimit)
 }
 
 func (l *balanceAdjacentRegionScheduler) allowBalancePeer() bool {
-	return l.limiter.OperatorCount(schedule.OpAdjacent|schedule.OpRegion) < l.peerLimit
+	return l.limiter.OperatorCount(schedule.OpAdjacent|schedule.OpRegion) < atomic.LoadUint64(&l.peerLimit)
+}
+
+func (l *balanceAdjacentRegionScheduler) GetConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"leader_limit": atomic.LoadUint64(&l.leaderLimit),
+		"peer_limit":   atomic.LoadUint64(&l.peerLimit),
+	}
+}
+
+// parseConfig returns the limits in the config, or the current ones if they
+// are not in the config.
+func (l *balanceAdjacentRegionScheduler) parseConfig(config map[string]interface{}) (uint64, uint64, error) {
+	if err := checkConfigKeys(config, "leader_limit", "peer_limit"); err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	leaderLimit, hasLeaderLimit, err := parseConfigUint64(config, "leader_limit")
+	if err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	peerLimit, hasPeerLimit, err := parseConfigUint64(config, "peer_limit")
+	if err != nil {
+		return 0, 0, errors.Trace(err)
+	}
+	if !hasLeaderLimit {
+		leaderLimit = atomic.LoadUint64(&l.leaderLimit)
+	}
+	if !hasPeerLimit {
+		peerLimit = atomic.LoadUint64(&l.peerLimit)
+	}
+	return leaderLimit, peerLimit, nil
+}
+
+func (l *balanceAdjacentRegionScheduler) ParseConfig(config map[string]interface{}) ([]string, error) {
+	leaderLimit, peerLimit, err := l.parseConfig(config)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return adjacentRegionArgs(leaderLimit, peerLimit), nil
+}
+
+func (l *balanceAdjacentRegionScheduler) UpdateConfig(cluster schedule.Cluster, config map[string]interface{}) error {
+	leaderLimit, peerLimit, err := l.parseConfig(config)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	atomic.StoreUint64(&l.leaderLimit, leaderLimit)
+	atomic.StoreUint64(&l.peerLimit, peerLimit)
+	return nil
+}
+
+func (l *balanceAdjacentRegionScheduler) GetArgs() []string {
+	return adjacentRegionArgs(atomic.LoadUint64(&l.leaderLimit), atomic.LoadUint64(&l.peerLimit))
+}
+
+func adjacentRegionArgs(leaderLimit, peerLimit uint64) []string {
+	return []string{strconv.FormatUint(leaderLimit, 10), strconv.FormatUint(peerLimit, 10)}
 }
 
 func (l *balanceAdjacentRegionScheduler) Schedule(cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
@@ -219,6 +280,7 @@ func (l *balanceAdjacentRegionScheduler) process(cluster schedule.Cluster) []*sc
 	if op == nil {
 		schedulerCounter.WithLabelValues(l.GetName(), "no_peer").Inc()
 		l.cacheRegions.assignedStoreIds = l.cacheRegions.assignedStoreIds[:0]
+		return nil
 	}
 	return []*schedule.Operator{op}
 }
@@ -257,7 +319,7 @@ func (l *balanceAdjacentRegionScheduler) disperseLeader(cluster schedule.Cluster
 		return nil
 	}
 	step := schedule.TransferLeader{FromStore: before.Leader.GetStoreId(), ToStore: target.GetId()}
-	op := schedule.NewOperator("balance-adjacent-leader", before.GetId(), schedule.OpAdjacent|schedule.OpLeader, step)
+	op := schedule.NewOperator("balance-adjacent-leader", before.GetId(), before.GetRegionEpoch(), schedule.OpAdjacent|schedule.OpLeader, step)
 	op.SetPriorityLevel(core.LowPriority)
 	schedulerCounter.WithLabelValues(l.GetName(), "adjacent_leader").Inc()
 	return op
@@ -271,6 +333,13 @@ func (l *balanceAdjacentRegionScheduler) dispersePeer(cluster schedule.Cluster,
 	leaderStoreID := region.Leader.GetStoreId()
 	stores := cluster.GetRegionStores(region)
 	source := cluster.GetStore(leaderStoreID)
+	// The store limit only applies to peers, so it is not in the selector
+	// shared with leader transfers.
+	storeLimitFilter := schedule.NewStoreLimitFilter()
+	if source != nil && storeLimitFilter.FilterSource(cluster, source) {
+		schedulerCounter.WithLabelValues(l.GetName(), "store_limit").Inc()
+		return nil
+	}
 	scoreGuard := schedule.NewDistinctScoreFilter(cluster.GetLocationLabels(), stores, source)
 	excludeStores := region.GetStoreIds()
 	for _, storeID := range l.cacheRegions.assignedStoreIds {
@@ -282,6 +351,7 @@ func (l *balanceAdjacentRegionScheduler) dispersePeer(cluster schedule.Cluster,
 	filters := []schedule.Filter{
 		schedule.NewExcludedFilter(nil, excludeStores),
 		scoreGuard,
+		storeLimitFilter,
 	}
 	target := l.selector.SelectTarget(cluster, cluster.GetStores(), filters...)
 	if target == nil {
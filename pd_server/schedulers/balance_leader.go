@@ -35,6 +35,7 @@ type balanceLeaderScheduler struct {
 	*baseScheduler
 	selector    schedule.Selector
 	taintStores *cache.TTLUint64
+	tracer      *schedule.Tracer
 }
 
 // newBalanceLeaderScheduler creates a scheduler that tends to keep leaders on
@@ -48,11 +49,13 @@ func newBalanceLeaderScheduler(limiter *schedule.Limiter) schedule.Scheduler {
 		schedule.NewRejectLeaderFilter(),
 		schedule.NewCacheFilter(taintStores),
 	}
+	tracer := schedule.NewTracer()
 	base := newBaseScheduler(limiter)
 	return &balanceLeaderScheduler{
 		baseScheduler: base,
-		selector:      schedule.NewBalanceSelector(core.LeaderKind, filters),
+		selector:      schedule.NewBalanceSelector(core.LeaderKind, tracer.WrapFilters(filters)),
 		taintStores:   taintStores,
+		tracer:        tracer,
 	}
 }
 
@@ -64,13 +67,22 @@ func (l *balanceLeaderScheduler) GetType() string {
 	return "balance-leader"
 }
 
+func (l *balanceLeaderScheduler) GetTracer() *schedule.Tracer {
+	return l.tracer
+}
+
 func (l *balanceLeaderScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
 	return l.limiter.OperatorCount(schedule.OpLeader) < cluster.GetLeaderScheduleLimit()
 }
 
 func (l *balanceLeaderScheduler) Schedule(cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
 	schedulerCounter.WithLabelValues(l.GetName(), "schedule").Inc()
+	return scheduleBatch(cluster, l.limiter, l.GetName(), schedule.OpLeader, cluster.GetLeaderScheduleLimit(), opInfluence, l.scheduleOnce)
+}
 
+// scheduleOnce creates an operator to transfer a leader out of the store
+// with the highest leader score or into the store with the lowest one.
+func (l *balanceLeaderScheduler) scheduleOnce(cluster schedule.Cluster, opInfluence schedule.OpInfluence, batchRegions map[uint64]struct{}) []*schedule.Operator {
 	stores := cluster.GetStores()
 
 	// source/target is the store with highest/lowest leader score in the list that
@@ -80,6 +92,7 @@ func (l *balanceLeaderScheduler) Schedule(cluster schedule.Cluster, opInfluence
 
 	// No store can be selected as source or target.
 	if source == nil || target == nil {
+		l.tracer.Record("no-store", 0, 0, "source selected: %v, target selected: %v", source != nil, target != nil)
 		schedulerCounter.WithLabelValues(l.GetName(), "no_store").Inc()
 		// When the cluster is balanced, all stores will be added to the cache once
 		// all of them have been selected. This will cause the scheduler to not adapt
@@ -91,49 +104,112 @@ func (l *balanceLeaderScheduler) Schedule(cluster schedule.Cluster, opInfluence
 	
 	//log.Infof("[%s] store%d has the max leader score: ResourceScore %f, store%d has the min leader score: ResourceScore %f", l.GetName(), source.GetId(), source.ResourceScore(0), target.GetId(), target.ResourceScore(0)) // wyy add
 	log.Debugf("[%s] store%d has the max leader score, store%d has the min leader score", l.GetName(), source.GetId(), target.GetId())
+	l.tracer.Record("select-source", source.GetId(), 0, "leader score %v", source.ResourceScore(core.LeaderKind))
+	l.tracer.Record("select-target", target.GetId(), 0, "leader score %v", target.ResourceScore(core.LeaderKind))
 	sourceStoreLabel := strconv.FormatUint(source.GetId(), 10)
 	targetStoreLabel := strconv.FormatUint(target.GetId(), 10)
 	balanceLeaderCounter.WithLabelValues("high_score", sourceStoreLabel).Inc()
 	balanceLeaderCounter.WithLabelValues("low_score", targetStoreLabel).Inc()
 
+	var op []*schedule.Operator
+	if count := cluster.GetBalanceCandidateCount(); count > 0 {
+		op = l.transferBestCandidate(source, target, cluster, count, opInfluence, batchRegions)
+	} else {
+		op = l.transferRandomRegion(source, target, cluster, opInfluence, batchRegions)
+	}
+	if op != nil {
+		return op
+	}
+
+	// If no operator can be created for the selected stores, ignore them for a while.
+	//log.Infof("[%s] no operator created for selected store%d and store%d", l.GetName(), source.GetId(), target.GetId())
+	log.Debugf("[%s] no operator created for selected store%d and store%d", l.GetName(), source.GetId(), target.GetId())
+	// The operators of the batch may have balanced the stores for now, which
+	// don't need to be ignored.
+	if len(batchRegions) > 0 {
+		return nil
+	}
+	l.tracer.Record("add-taint", source.GetId(), 0, "")
+	l.tracer.Record("add-taint", target.GetId(), 0, "")
+	balanceLeaderCounter.WithLabelValues("add_taint", strconv.FormatUint(source.GetId(), 10)).Inc()
+	l.taintStores.Put(source.GetId())
+	balanceLeaderCounter.WithLabelValues("add_taint", strconv.FormatUint(target.GetId(), 10)).Inc()
+	l.taintStores.Put(target.GetId())
+	return nil
+}
+
+// transferRandomRegion transfers the leader of a random region out of the
+// source store or into the target store, skipping the regions in the batch.
+func (l *balanceLeaderScheduler) transferRandomRegion(source, target *core.StoreInfo, cluster schedule.Cluster, opInfluence schedule.OpInfluence, batchRegions map[uint64]struct{}) []*schedule.Operator {
+	sourceStoreLabel := strconv.FormatUint(source.GetId(), 10)
+	targetStoreLabel := strconv.FormatUint(target.GetId(), 10)
 	for i := 0; i < balanceLeaderRetryLimit; i++ {
 		//log.Infof("balanceLeaderRetry: %d", i)
-		if op := l.transferLeaderOut(source, cluster, opInfluence); op != nil {
+		if op := l.transferLeaderOut(source, cluster, opInfluence); op != nil && !inBatch(op, batchRegions) {
 			//log.Infof("transferLeaderOut op: %s", op)	// wyy 
 			balanceLeaderCounter.WithLabelValues("transfer_out", sourceStoreLabel).Inc()
 			return op
 		}
-		if op := l.transferLeaderIn(target, cluster, opInfluence); op != nil {
+		if op := l.transferLeaderIn(target, cluster, opInfluence); op != nil && !inBatch(op, batchRegions) {
 			//log.Infof("transferLeaderIn op: %s", op)	// wyy add
 			balanceLeaderCounter.WithLabelValues("transfer_in", targetStoreLabel).Inc()
 			return op
 		}
 	}
-
-	// If no operator can be created for the selected stores, ignore them for a while.
-	//log.Infof("[%s] no operator created for selected store%d and store%d", l.GetName(), source.GetId(), target.GetId())
-	log.Debugf("[%s] no operator created for selected store%d and store%d", l.GetName(), source.GetId(), target.GetId())
-	balanceLeaderCounter.WithLabelValues("add_taint", strconv.FormatUint(source.GetId(), 10)).Inc()
-	l.taintStores.Put(source.GetId())
-	balanceLeaderCounter.WithLabelValues("add_taint", strconv.FormatUint(target.GetId(), 10)).Inc()
-	l.taintStores.Put(target.GetId())
 	return nil
 }
 
+// transferBestCandidate samples the leader regions of the source store and
+// the follower regions of the target store, and transfers the leader of the
+// one reducing the imbalance most.
+func (l *balanceLeaderScheduler) transferBestCandidate(source, target *core.StoreInfo, cluster schedule.Cluster, count int, opInfluence schedule.OpInfluence, batchRegions map[uint64]struct{}) []*schedule.Operator {
+	var best []*schedule.Operator
+	var bestGain float64
+	var bestAction string
+	var bestStore uint64
+	for _, region := range sampleRegions(cluster, l.limiter, l.tracer, l.GetName(), count, batchRegions, func() *core.RegionInfo {
+		return cluster.RandLeaderRegion(source.GetId())
+	}) {
+		if op, gain := l.transferLeaderOutOf(region, source, cluster, opInfluence); op != nil && gain > bestGain {
+			best, bestGain, bestAction, bestStore = op, gain, "transfer_out", source.GetId()
+		}
+	}
+	for _, region := range sampleRegions(cluster, l.limiter, l.tracer, l.GetName(), count, batchRegions, func() *core.RegionInfo {
+		return cluster.RandFollowerRegion(target.GetId())
+	}) {
+		if op, gain := l.transferLeaderInto(region, target, cluster, opInfluence); op != nil && gain > bestGain {
+			best, bestGain, bestAction, bestStore = op, gain, "transfer_in", target.GetId()
+		}
+	}
+	if best != nil {
+		balanceLeaderCounter.WithLabelValues(bestAction, strconv.FormatUint(bestStore, 10)).Inc()
+	}
+	return best
+}
+
 func (l *balanceLeaderScheduler) transferLeaderOut(source *core.StoreInfo, cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
 	region := cluster.RandLeaderRegion(source.GetId())
 	if region == nil {
 		//log.Infof("[%s] store%d has no leader", l.GetName(), source.GetId())
 		log.Debugf("[%s] store%d has no leader", l.GetName(), source.GetId())
+		l.tracer.Record("no-leader-region", source.GetId(), 0, "")
 		schedulerCounter.WithLabelValues(l.GetName(), "no_leader_region").Inc()
 		return nil
 	}
+	op, _ := l.transferLeaderOutOf(region, source, cluster, opInfluence)
+	return op
+}
+
+// transferLeaderOutOf transfers the leader of the region on the source store
+// to the follower store with the lowest leader score.
+func (l *balanceLeaderScheduler) transferLeaderOutOf(region *core.RegionInfo, source *core.StoreInfo, cluster schedule.Cluster, opInfluence schedule.OpInfluence) ([]*schedule.Operator, float64) {
 	target := l.selector.SelectTarget(cluster, cluster.GetFollowerStores(region))
 	if target == nil {
 		//log.Infof("[%s] region %d has no target store", l.GetName(), region.GetId())
 		log.Debugf("[%s] region %d has no target store", l.GetName(), region.GetId())
+		l.tracer.Record("no-target-store", 0, region.GetId(), "")
 		schedulerCounter.WithLabelValues(l.GetName(), "no_target_store").Inc()
-		return nil
+		return nil, 0
 	}
 	return l.createOperator(region, source, target, cluster, opInfluence)
 }
@@ -143,20 +219,32 @@ func (l *balanceLeaderScheduler) transferLeaderIn(target *core.StoreInfo, cluste
 	if region == nil {
 		//log.Infof("[%s] store%d has no follower", l.GetName(), target.GetId())
 		log.Debugf("[%s] store%d has no follower", l.GetName(), target.GetId())
+		l.tracer.Record("no-follower-region", target.GetId(), 0, "")
 		schedulerCounter.WithLabelValues(l.GetName(), "no_follower_region").Inc()
 		return nil
 	}
+	op, _ := l.transferLeaderInto(region, target, cluster, opInfluence)
+	return op
+}
+
+// transferLeaderInto transfers the leader of the region to its follower on
+// the target store.
+func (l *balanceLeaderScheduler) transferLeaderInto(region *core.RegionInfo, target *core.StoreInfo, cluster schedule.Cluster, opInfluence schedule.OpInfluence) ([]*schedule.Operator, float64) {
 	source := cluster.GetStore(region.Leader.GetStoreId())
 	if source == nil {
 		//log.Infof("[%s] region %d has no target store", l.GetName(), region.GetId())
 		log.Debugf("[%s] region %d has no leader", l.GetName(), region.GetId())
+		l.tracer.Record("no-leader", 0, region.GetId(), "")
 		schedulerCounter.WithLabelValues(l.GetName(), "no_leader").Inc()
-		return nil
+		return nil, 0
 	}
 	return l.createOperator(region, source, target, cluster, opInfluence)
 }
 
-func (l *balanceLeaderScheduler) createOperator(region *core.RegionInfo, source, target *core.StoreInfo, cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
+// createOperator creates the operator to transfer the leader of the region
+// from the source to the target. It returns the operator and how much it
+// reduces the imbalance.
+func (l *balanceLeaderScheduler) createOperator(region *core.RegionInfo, source, target *core.StoreInfo, cluster schedule.Cluster, opInfluence schedule.OpInfluence) ([]*schedule.Operator, float64) {
 	log.Debugf("[%s] verify balance region %d, from: %d, to: %d", l.GetName(), region.GetId(), source.GetId(), target.GetId())
 	/*if cluster.IsRegionHot(region.GetId()) {
 		//log.Infof("[%s] region %d is hot region, ignore it", l.GetName(), region.GetId())
@@ -167,15 +255,19 @@ func (l *balanceLeaderScheduler) createOperator(region *core.RegionInfo, source,
 	sourceSize := source.LeaderSize + int64(opInfluence.GetStoreInfluence(source.GetId()).LeaderSize)
 	targetSize := target.LeaderSize + int64(opInfluence.GetStoreInfluence(target.GetId()).LeaderSize)
 	regionSize := float64(region.ApproximateSize) * cluster.GetTolerantSizeRatio()
-	if !shouldBalance(sourceSize, source.LeaderWeight, targetSize, target.LeaderWeight, regionSize) {
+	sourceWeight, targetWeight := source.ResourceWeight(core.LeaderKind), target.ResourceWeight(core.LeaderKind)
+	if !shouldBalance(sourceSize, sourceWeight, targetSize, targetWeight, regionSize) {
 		//log.Infof("[%s] skip balance region%d, source size: %v, source weight: %v, target size: %v, target weight: %v, region size: %v", l.GetName(), region.GetId(), sourceSize, source.LeaderWeight, targetSize, target.LeaderWeight, region.ApproximateSize)
-		log.Debugf("[%s] skip balance region%d, source size: %v, source weight: %v, target size: %v, target weight: %v, region size: %v", l.GetName(), region.GetId(), sourceSize, source.LeaderWeight, targetSize, target.LeaderWeight, region.ApproximateSize)
+		log.Debugf("[%s] skip balance region%d, source size: %v, source weight: %v, target size: %v, target weight: %v, region size: %v", l.GetName(), region.GetId(), sourceSize, sourceWeight, targetSize, targetWeight, region.ApproximateSize)
+		l.tracer.Record("skip", 0, region.GetId(), "source store%d size: %v, weight: %v, target store%d size: %v, weight: %v, tolerant region size: %v",
+			source.GetId(), sourceSize, sourceWeight, target.GetId(), targetSize, targetWeight, regionSize)
 		schedulerCounter.WithLabelValues(l.GetName(), "skip").Inc()
-		return nil
+		return nil, 0
 	}
+	l.tracer.Record("new-operator", 0, region.GetId(), "transfer leader from store%d to store%d", source.GetId(), target.GetId())
 	schedulerCounter.WithLabelValues(l.GetName(), "new_operator").Inc()
 	step := schedule.TransferLeader{FromStore: region.Leader.GetStoreId(), ToStore: target.GetId()}
 	log.Debugf("[%s] start balance region %d, from: %d, to: %d", l.GetName(), region.GetId(), source.GetId(), target.GetId())
-	op := schedule.NewOperator("balanceLeader", region.GetId(), schedule.OpBalance|schedule.OpLeader, step)
-	return []*schedule.Operator{op}
+	op := schedule.NewOperator("balanceLeader", region.GetId(), region.GetRegionEpoch(), schedule.OpBalance|schedule.OpLeader, step)
+	return []*schedule.Operator{op}, balanceGain(sourceSize, sourceWeight, targetSize, targetWeight, float64(region.ApproximateSize))
 }
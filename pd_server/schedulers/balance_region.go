@@ -36,6 +36,7 @@ type balanceRegionScheduler struct {
 	*baseScheduler
 	selector    schedule.Selector
 	taintStores *cache.TTLUint64
+	tracer      *schedule.Tracer
 }
 
 // newBalanceRegionScheduler creates a scheduler that tends to keep regions on
@@ -48,13 +49,17 @@ func newBalanceRegionScheduler(limiter *schedule.Limiter) schedule.Scheduler {
 		schedule.NewHealthFilter(),
 		schedule.NewSnapshotCountFilter(),
 		schedule.NewStorageThresholdFilter(),
+		schedule.NewRegionSizeLimitFilter(),
 		schedule.NewPendingPeerCountFilter(),
+		schedule.NewStoreLimitFilter(),
 	}
+	tracer := schedule.NewTracer()
 	base := newBaseScheduler(limiter)
 	return &balanceRegionScheduler{
 		baseScheduler: base,
-		selector:      schedule.NewBalanceSelector(core.RegionKind, filters),
+		selector:      schedule.NewBalanceSelector(core.RegionKind, tracer.WrapFilters(filters)),
 		taintStores:   taintStores,
+		tracer:        tracer,
 	}
 }
 
@@ -66,18 +71,28 @@ func (s *balanceRegionScheduler) GetType() string {
 	return "balance-region"
 }
 
+func (s *balanceRegionScheduler) GetTracer() *schedule.Tracer {
+	return s.tracer
+}
+
 func (s *balanceRegionScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
 	return s.limiter.OperatorCount(schedule.OpRegion) < cluster.GetRegionScheduleLimit()
 }
 
 func (s *balanceRegionScheduler) Schedule(cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	return scheduleBatch(cluster, s.limiter, s.GetName(), schedule.OpRegion, cluster.GetRegionScheduleLimit(), opInfluence, s.scheduleOnce)
+}
 
+// scheduleOnce creates an operator to move a region out of the store with
+// the highest region score.
+func (s *balanceRegionScheduler) scheduleOnce(cluster schedule.Cluster, opInfluence schedule.OpInfluence, batchRegions map[uint64]struct{}) []*schedule.Operator {
 	stores := cluster.GetStores()
 
 	// source is the store with highest leade score in the list that can be selected as balance source.
 	source := s.selector.SelectSource(cluster, stores)
 	if source == nil {
+		s.tracer.Record("no-store", 0, 0, "")
 		schedulerCounter.WithLabelValues(s.GetName(), "no_store").Inc()
 		// When the cluster is balanced, all stores will be added to the cache once
 		// all of them have been selected. This will cause the scheduler to not adapt
@@ -88,23 +103,59 @@ func (s *balanceRegionScheduler) Schedule(cluster schedule.Cluster, opInfluence
 	}
 
 	log.Debugf("[%s] store%d has the max region score", s.GetName(), source.GetId())
+	s.tracer.Record("select-source", source.GetId(), 0, "region score %v", source.ResourceScore(core.RegionKind))
 	sourceLabel := strconv.FormatUint(source.GetId(), 10)
 	balanceRegionCounter.WithLabelValues("source_store", sourceLabel).Inc()
 
+	var op *schedule.Operator
+	if count := cluster.GetBalanceCandidateCount(); count > 0 {
+		op = s.transferBestCandidate(cluster, source, count, opInfluence, batchRegions)
+	} else {
+		op = s.transferRandomRegion(cluster, source, opInfluence, batchRegions)
+	}
+	if op != nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "new_operator").Inc()
+		return []*schedule.Operator{op}
+	}
+
+	// If no operator can be created for the selected store, ignore it for a while.
+	log.Debugf("[%s] no operator created for selected store%d", s.GetName(), source.GetId())
+	log.Debugf("[%s] no operator created for selected store%d", s.GetName(), source.GetId())
+	// The operators of the batch may have balanced the store for now, which
+	// doesn't need to be ignored.
+	if len(batchRegions) > 0 {
+		return nil
+	}
+	s.tracer.Record("add-taint", source.GetId(), 0, "")
+	balanceRegionCounter.WithLabelValues("add_taint", sourceLabel).Inc()
+	s.taintStores.Put(source.GetId())
+	return nil
+}
+
+// transferRandomRegion moves a random region out of the source store.
+func (s *balanceRegionScheduler) transferRandomRegion(cluster schedule.Cluster, source *core.StoreInfo, opInfluence schedule.OpInfluence, batchRegions map[uint64]struct{}) *schedule.Operator {
 	for i := 0; i < balanceRegionRetryLimit; i++ {
 		region := cluster.RandFollowerRegion(source.GetId())
 		if region == nil {
 			region = cluster.RandLeaderRegion(source.GetId())
 		}
 		if region == nil {
+			s.tracer.Record("no-region", source.GetId(), 0, "")
 			schedulerCounter.WithLabelValues(s.GetName(), "no_region").Inc()
 			continue
 		}
 		log.Debugf("[%s] select region%d", s.GetName(), region.GetId())
 
+		// Skip regions already in the batch or with pending operators.
+		if _, ok := batchRegions[region.GetId()]; ok || s.limiter.HasOperator(region.GetId()) {
+			s.tracer.Record("has-operator", 0, region.GetId(), "")
+			continue
+		}
+
 		// We don't schedule region with abnormal number of replicas.
 		if len(region.GetPeers()) != cluster.GetMaxReplicas() {
 			log.Debugf("[%s] region%d has abnormal replica count", s.GetName(), region.GetId())
+			s.tracer.Record("abnormal-replica", 0, region.GetId(), "%d replicas", len(region.GetPeers()))
 			schedulerCounter.WithLabelValues(s.GetName(), "abnormal_replica").Inc()
 			continue
 		}
@@ -113,26 +164,50 @@ func (s *balanceRegionScheduler) Schedule(cluster schedule.Cluster, opInfluence
 		if cluster.IsRegionHot(region.GetId()) {
 			//log.Infof("[%s] region%d is hot", s.GetName(), region.GetId())
 			log.Debugf("[%s] region%d is hot", s.GetName(), region.GetId())
+			s.tracer.Record("region-hot", 0, region.GetId(), "")
 			schedulerCounter.WithLabelValues(s.GetName(), "region_hot").Inc()
 			continue
 		}
 
 		oldPeer := region.GetStorePeer(source.GetId())
-		if op := s.transferPeer(cluster, region, oldPeer, opInfluence); op != nil {
-			schedulerCounter.WithLabelValues(s.GetName(), "new_operator").Inc()
-			return []*schedule.Operator{op}
+		if op, _ := s.transferPeer(cluster, region, oldPeer, opInfluence); op != nil {
+			return op
 		}
 	}
-
-	// If no operator can be created for the selected store, ignore it for a while.
-	log.Debugf("[%s] no operator created for selected store%d", s.GetName(), source.GetId())
-	log.Debugf("[%s] no operator created for selected store%d", s.GetName(), source.GetId())
-	balanceRegionCounter.WithLabelValues("add_taint", sourceLabel).Inc()
-	s.taintStores.Put(source.GetId())
 	return nil
 }
 
-func (s *balanceRegionScheduler) transferPeer(cluster schedule.Cluster, region *core.RegionInfo, oldPeer *metapb.Peer, opInfluence schedule.OpInfluence) *schedule.Operator {
+// transferBestCandidate samples candidate regions from the source store, and
+// moves the one reducing the imbalance between the source and the target
+// most.
+func (s *balanceRegionScheduler) transferBestCandidate(cluster schedule.Cluster, source *core.StoreInfo, count int, opInfluence schedule.OpInfluence, batchRegions map[uint64]struct{}) *schedule.Operator {
+	candidates := sampleRegions(cluster, s.limiter, s.tracer, s.GetName(), count, batchRegions, func() *core.RegionInfo {
+		region := cluster.RandFollowerRegion(source.GetId())
+		if region == nil {
+			region = cluster.RandLeaderRegion(source.GetId())
+		}
+		return region
+	})
+	var best *schedule.Operator
+	var bestGain float64
+	for _, region := range candidates {
+		if len(region.GetPeers()) != cluster.GetMaxReplicas() {
+			log.Debugf("[%s] region%d has abnormal replica count", s.GetName(), region.GetId())
+			s.tracer.Record("abnormal-replica", 0, region.GetId(), "%d replicas", len(region.GetPeers()))
+			schedulerCounter.WithLabelValues(s.GetName(), "abnormal_replica").Inc()
+			continue
+		}
+		op, gain := s.transferPeer(cluster, region, region.GetStorePeer(source.GetId()), opInfluence)
+		if op != nil && gain > bestGain {
+			best, bestGain = op, gain
+		}
+	}
+	return best
+}
+
+// transferPeer moves the peer of the region to the best store. It returns
+// the operator and how much it reduces the imbalance.
+func (s *balanceRegionScheduler) transferPeer(cluster schedule.Cluster, region *core.RegionInfo, oldPeer *metapb.Peer, opInfluence schedule.OpInfluence) (*schedule.Operator, float64) {
 	// scoreGuard guarantees that the distinct score will not decrease.
 	stores := cluster.GetRegionStores(region)
 	source := cluster.GetStore(oldPeer.GetStoreId())
@@ -143,8 +218,9 @@ func (s *balanceRegionScheduler) transferPeer(cluster schedule.Cluster, region *
 	checker := schedule.NewReplicaChecker(cluster, nil)
 	newPeer := checker.SelectBestReplacedPeerToAddReplica(region, oldPeer, scoreGuard)
 	if newPeer == nil {
+		s.tracer.Record("no-peer", 0, region.GetId(), "no store to replace the peer on store%d", source.GetId())
 		schedulerCounter.WithLabelValues(s.GetName(), "no_peer").Inc()
-		return nil
+		return nil, 0
 	}
 
 	target := cluster.GetStore(newPeer.GetStoreId())
@@ -154,17 +230,25 @@ func (s *balanceRegionScheduler) transferPeer(cluster schedule.Cluster, region *
 	sourceSize := source.RegionSize + int64(opInfluence.GetStoreInfluence(source.GetId()).RegionSize)
 	targetSize := target.RegionSize + int64(opInfluence.GetStoreInfluence(target.GetId()).RegionSize)
 	regionSize := float64(region.ApproximateSize) * cluster.GetTolerantSizeRatio()
-	if !shouldBalance(sourceSize, source.RegionWeight, targetSize, target.RegionWeight, regionSize) {
-		log.Debugf("[%s] skip balance region%d, source size: %v, source weight: %v, target size: %v, target weight: %v, region size: %v", s.GetName(), region.GetId(), sourceSize, source.RegionWeight, targetSize, target.RegionWeight, region.ApproximateSize)
+	sourceWeight, targetWeight := source.ResourceWeight(core.RegionKind), target.ResourceWeight(core.RegionKind)
+	if !shouldBalance(sourceSize, sourceWeight, targetSize, targetWeight, regionSize) {
+		log.Debugf("[%s] skip balance region%d, source size: %v, source weight: %v, target size: %v, target weight: %v, region size: %v", s.GetName(), region.GetId(), sourceSize, sourceWeight, targetSize, targetWeight, region.ApproximateSize)
+		s.tracer.Record("skip", 0, region.GetId(), "source store%d size: %v, weight: %v, target store%d size: %v, weight: %v, tolerant region size: %v",
+			source.GetId(), sourceSize, sourceWeight, target.GetId(), targetSize, targetWeight, regionSize)
 		schedulerCounter.WithLabelValues(s.GetName(), "skip").Inc()
-		return nil
+		return nil, 0
 	}
-	
-	// wyy add
-	if (sourceSize < 6144 && source.RegionWeight == 1e-6) {
+
+	// Stop moving regions out of a store over its soft limit once the pending
+	// operators are enough to bring it back under the limit.
+	if source.IsOverRegionSizeSoftLimit() && sourceSize < source.RegionSizeSoftLimit {
+		log.Debugf("[%s] skip balance region%d, source size: %v, source soft limit: %v", s.GetName(), region.GetId(), sourceSize, source.RegionSizeSoftLimit)
+		s.tracer.Record("skip", 0, region.GetId(), "source store%d size: %v, soft limit: %v", source.GetId(), sourceSize, source.RegionSizeSoftLimit)
 		schedulerCounter.WithLabelValues(s.GetName(), "skip").Inc()
-		return nil
+		return nil, 0
 	}
 
-	return schedule.CreateMovePeerOperator("balance-region", cluster, region, schedule.OpBalance, oldPeer.GetStoreId(), newPeer.GetStoreId(), newPeer.GetId())
+	s.tracer.Record("new-operator", 0, region.GetId(), "move peer from store%d to store%d", source.GetId(), target.GetId())
+	op := schedule.CreateMovePeerOperator("balance-region", cluster, region, schedule.OpBalance, oldPeer.GetStoreId(), newPeer.GetStoreId(), newPeer.GetId())
+	return op, balanceGain(sourceSize, sourceWeight, targetSize, targetWeight, float64(region.ApproximateSize))
 }
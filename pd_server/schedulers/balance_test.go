@@ -15,6 +15,7 @@ package schedulers
 
 import (
 	"math"
+	"strings"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -195,6 +196,44 @@ func (s *testBalanceLeaderSchedulerSuite) TestBalanceFilter(c *C) {
 	CheckTransferLeader(c, s.schedule(nil)[0], schedule.OpBalance, 4, 3)
 }
 
+func (s *testBalanceLeaderSchedulerSuite) TestTrace(c *C) {
+	// Stores:     1    2    3    4
+	// Leaders:    7    8    9   10
+	// Region1:    F    F    F    L
+	s.tc.addLeaderStore(1, 7)
+	s.tc.addLeaderStore(2, 8)
+	s.tc.addLeaderStore(3, 9)
+	s.tc.addLeaderStore(4, 10)
+	s.tc.addLeaderRegion(1, 4, 1, 2, 3)
+	s.tc.setStoreDown(1)
+
+	// Nothing is recorded until the tracer is enabled.
+	tracer := s.lb.(schedule.TraceableScheduler).GetTracer()
+	tracer.Begin()
+	tracer.Record("test", 0, 0, "")
+	tracer.End(0)
+	c.Assert(tracer.GetTraces(), HasLen, 0)
+
+	tracer.Enable(true)
+	tracer.Begin()
+	c.Assert(s.schedule(nil), IsNil)
+	tracer.End(0)
+	traces := tracer.GetTraces()
+	c.Assert(traces, HasLen, 1)
+	events := make(map[string]*schedule.TraceEvent)
+	for _, e := range traces[0].Events {
+		if _, ok := events[e.Event]; !ok {
+			events[e.Event] = e
+		}
+	}
+	c.Assert(events["filter-target"].StoreID, Equals, uint64(1))
+	c.Assert(events["filter-target"].Detail, Equals, "health")
+	c.Assert(events["select-source"].StoreID, Equals, uint64(4))
+	c.Assert(events["select-target"].StoreID, Equals, uint64(2))
+	c.Assert(events["skip"].RegionID, Equals, uint64(1))
+	c.Assert(strings.Contains(events["skip"].Detail, "source store4 size: 10"), IsTrue)
+}
+
 func (s *testBalanceLeaderSchedulerSuite) TestLeaderWeight(c *C) {
 	// Stores:	1	2	3	4
 	// Leaders:    10      10      10      10
@@ -215,6 +254,64 @@ func (s *testBalanceLeaderSchedulerSuite) TestLeaderWeight(c *C) {
 	CheckTransferLeader(c, s.schedule(nil)[0], schedule.OpBalance, 1, 3)
 }
 
+func (s *testBalanceLeaderSchedulerSuite) TestBatch(c *C) {
+	// Stores:     1    2    3    4
+	// Leaders:   20    0    0    0
+	// Region1-8:  L    F    F    F
+	s.tc.BalanceBatchSize = 3
+	s.tc.addLeaderStore(1, 20)
+	s.tc.addLeaderStore(2, 0)
+	s.tc.addLeaderStore(3, 0)
+	s.tc.addLeaderStore(4, 0)
+	for i := uint64(1); i <= 8; i++ {
+		s.tc.addLeaderRegion(i, 1, 2, 3, 4)
+	}
+
+	ops := s.schedule(nil)
+	c.Assert(ops, HasLen, 3)
+	regions := make(map[uint64]struct{})
+	for _, op := range ops {
+		c.Assert(op.Kind()&schedule.OpLeader, Not(Equals), schedule.OperatorKind(0))
+		regions[op.RegionID()] = struct{}{}
+	}
+	c.Assert(regions, HasLen, 3)
+
+	// Store 1 is changed by all the operators.
+	s.tc.StoreBalanceLimit = 2
+	c.Assert(s.schedule(nil), HasLen, 2)
+
+	// Limited by the headroom of the leader schedule limit.
+	s.tc.StoreBalanceLimit = 0
+	s.tc.LeaderScheduleLimit = 1
+	c.Assert(s.schedule(nil), HasLen, 1)
+}
+
+func (s *testBalanceLeaderSchedulerSuite) TestBestCandidate(c *C) {
+	// Stores:      1    2    3    4
+	// LeaderSize: 300   50   60   70
+	// Region1-4:   L    F    F    F
+	s.tc.BalanceCandidates = 20
+	s.tc.addLeaderStore(1, 30)
+	s.tc.addLeaderStore(2, 5)
+	s.tc.addLeaderStore(3, 6)
+	s.tc.addLeaderStore(4, 7)
+	addCandidateRegions(s.tc, 1, 2, 3, 4)
+	limiter := schedule.NewLimiter()
+	s.lb, _ = schedule.CreateScheduler("balance-leader", limiter)
+
+	// Region 3 has a pending peer, and region 4 has an operator, so region
+	// 2 is the largest candidate.
+	limiter.UpdateCounts(map[uint64]*schedule.Operator{
+		4: schedule.NewOperator("test", 4, nil, schedule.OpLeader),
+	})
+	for i := 0; i < 10; i++ {
+		op := s.schedule(nil)
+		c.Assert(op, NotNil)
+		c.Assert(op[0].RegionID(), Equals, uint64(2))
+		CheckTransferLeader(c, op[0], schedule.OpBalance, 1, 2)
+	}
+}
+
 func (s *testBalanceLeaderSchedulerSuite) TestBalanceSelector(c *C) {
 	// Stores:     1    2    3    4
 	// Leaders:    1    2    3   16
@@ -438,6 +535,152 @@ func (s *testBalanceRegionSchedulerSuite) TestStoreWeight(c *C) {
 	CheckTransferPeer(c, sb.Schedule(tc, schedule.NewOpInfluence(nil, tc))[0], schedule.OpBalance, 1, 3)
 }
 
+func (s *testBalanceRegionSchedulerSuite) TestRegionSizeLimit(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+
+	sb, err := schedule.CreateScheduler("balance-region", schedule.NewLimiter())
+	c.Assert(err, IsNil)
+	opt.SetMaxReplicas(1)
+
+	tc.addRegionStore(1, 10)
+	tc.addRegionStore(2, 10)
+	tc.addRegionStore(3, 9)
+	tc.addRegionStore(4, 20)
+	// Store 1 exceeds its soft limit, so it becomes the source.
+	tc.updateStoreRegionSizeLimit(1, 50, 0)
+	// Store 2 reaches its hard limit, store 3 exceeds its soft limit,
+	// so neither of them can be the target. Store 3 is smaller than store 1
+	// so that it is not chosen as the source either.
+	tc.updateStoreRegionSizeLimit(2, 0, 100)
+	tc.updateStoreRegionSizeLimit(3, 80, 0)
+
+	tc.addLeaderRegion(1, 1)
+	CheckTransferPeer(c, sb.Schedule(tc, schedule.NewOpInfluence(nil, tc))[0], schedule.OpBalance, 1, 4)
+
+	// Pending operators will bring store 1 back under its soft limit.
+	opInfluence := schedule.OpInfluence{1: &schedule.StoreInfluence{RegionSize: -60}}
+	c.Assert(sb.Schedule(tc, opInfluence), IsNil)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestStoreLimit(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+
+	sb, err := schedule.CreateScheduler("balance-region", schedule.NewLimiter())
+	c.Assert(err, IsNil)
+	opt.SetMaxReplicas(1)
+
+	tc.addRegionStore(1, 10)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 0)
+	tc.addLeaderRegion(1, 1)
+	CheckTransferPeer(c, sb.Schedule(tc, schedule.NewOpInfluence(nil, tc))[0], schedule.OpBalance, 1, 3)
+
+	// Store 3 runs out of the tokens to add peers.
+	tc.updateStoreLimit(3, core.StoreAddPeer, 1, 1)
+	CheckTransferPeer(c, sb.Schedule(tc, schedule.NewOpInfluence(nil, tc))[0], schedule.OpBalance, 1, 2)
+
+	// Store 1 runs out of the tokens to remove peers.
+	tc.updateStoreLimit(1, core.StoreRemovePeer, 1, 1)
+	c.Assert(sb.Schedule(tc, schedule.NewOpInfluence(nil, tc)), IsNil)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestBatch(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+	opt.BalanceBatchSize = 3
+	opt.SetMaxReplicas(1)
+
+	limiter := schedule.NewLimiter()
+	sb, err := schedule.CreateScheduler("balance-region", limiter)
+	c.Assert(err, IsNil)
+
+	tc.addRegionStore(1, 20)
+	tc.addRegionStore(2, 0)
+	tc.addRegionStore(3, 0)
+	tc.addRegionStore(4, 0)
+	for i := uint64(1); i <= 8; i++ {
+		tc.addLeaderRegion(i, 1)
+	}
+
+	ops := sb.Schedule(tc, schedule.NewOpInfluence(nil, tc))
+	c.Assert(ops, HasLen, 3)
+	regions := make(map[uint64]struct{})
+	for _, op := range ops {
+		c.Assert(op.Kind()&schedule.OpRegion, Not(Equals), schedule.OperatorKind(0))
+		regions[op.RegionID()] = struct{}{}
+	}
+	c.Assert(regions, HasLen, 3)
+
+	// Store 1 has a pending operator, so only one more can be added.
+	opt.StoreBalanceLimit = 2
+	pending := ops[0]
+	limiter.UpdateCounts(map[uint64]*schedule.Operator{
+		pending.RegionID(): pending,
+	})
+	ops = sb.Schedule(tc, schedule.NewOpInfluence(nil, tc))
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Not(Equals), pending.RegionID())
+
+	// Limited by the headroom of the region schedule limit.
+	opt.StoreBalanceLimit = 0
+	opt.RegionScheduleLimit = 3
+	c.Assert(sb.Schedule(tc, schedule.NewOpInfluence(nil, tc)), HasLen, 2)
+}
+
+func (s *testBalanceRegionSchedulerSuite) TestBestCandidate(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+	opt.BalanceCandidates = 20
+	opt.SetMaxReplicas(1)
+
+	limiter := schedule.NewLimiter()
+	sb, err := schedule.CreateScheduler("balance-region", limiter)
+	c.Assert(err, IsNil)
+
+	tc.addRegionStore(1, 30)
+	tc.addRegionStore(2, 5)
+	tc.addRegionStore(3, 6)
+	tc.addRegionStore(4, 7)
+	addCandidateRegions(tc, 1)
+
+	// Region 3 has a pending peer, and region 4 has an operator, so region
+	// 2 is the largest candidate.
+	limiter.UpdateCounts(map[uint64]*schedule.Operator{
+		4: schedule.NewOperator("test", 4, nil, schedule.OpRegion),
+	})
+	for i := 0; i < 10; i++ {
+		op := sb.Schedule(tc, schedule.NewOpInfluence(nil, tc))
+		c.Assert(op, NotNil)
+		c.Assert(op[0].RegionID(), Equals, uint64(2))
+		CheckTransferPeer(c, op[0], schedule.OpBalance, 1, 2)
+	}
+
+	// No candidate is left.
+	limiter.UpdateCounts(map[uint64]*schedule.Operator{
+		1: schedule.NewOperator("test", 1, nil, schedule.OpRegion),
+		2: schedule.NewOperator("test", 2, nil, schedule.OpRegion),
+		4: schedule.NewOperator("test", 4, nil, schedule.OpRegion),
+	})
+	c.Assert(sb.Schedule(tc, schedule.NewOpInfluence(nil, tc)), IsNil)
+}
+
+// addCandidateRegions adds regions 1-4 with sizes 10, 40, 60 and 50, and
+// region 3 has a pending peer.
+func addCandidateRegions(tc *mockCluster, leaderID uint64, followerIds ...uint64) {
+	for i, size := range []int64{10, 40, 60, 50} {
+		regionID := uint64(i + 1)
+		tc.addLeaderRegion(regionID, leaderID, followerIds...)
+		region := tc.GetRegion(regionID).Clone()
+		region.ApproximateSize = size
+		if regionID == 3 {
+			region.PendingPeers = region.GetPeers()[len(region.GetPeers())-1:]
+		}
+		tc.PutRegion(region)
+	}
+}
+
 var _ = Suite(&testReplicaCheckerSuite{})
 
 type testReplicaCheckerSuite struct{}
@@ -697,6 +940,32 @@ func (s *testReplicaCheckerSuite) TestDistinctScore2(c *C) {
 	c.Assert(rc.Check(region), IsNil)
 }
 
+func (s *testReplicaCheckerSuite) TestWeightedReplacement(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+
+	rc := schedule.NewReplicaChecker(tc, namespace.DefaultClassifier)
+
+	tc.addRegionStore(1, 40)
+	tc.addRegionStore(2, 10)
+	tc.addRegionStore(3, 10)
+	tc.addRegionStore(4, 5)
+	tc.addLeaderRegion(1, 1, 2, 3)
+
+	// Store 1 is far above the weighted average, so the peer is moved to the
+	// equally isolated store 4.
+	CheckTransferPeer(c, rc.Check(tc.GetRegion(1)), schedule.OpReplica, 1, 4)
+
+	// Store 1 is within the tolerance.
+	opt.ReplicaWeightTol = 2
+	c.Assert(rc.Check(tc.GetRegion(1)), IsNil)
+
+	// Store 1 is close to its weighted share.
+	opt.ReplicaWeightTol = 0.5
+	tc.updateStoreRegionWeight(1, 4)
+	c.Assert(rc.Check(tc.GetRegion(1)), IsNil)
+}
+
 var _ = Suite(&testMergeCheckerSuite{})
 
 type testMergeCheckerSuite struct {
@@ -1020,6 +1289,43 @@ func (s *testBalanceHotReadRegionSchedulerSuite) TestBalance(c *C) {
 	hb.Schedule(tc, schedule.NewOpInfluence(nil, tc))
 }
 
+func (s *testBalanceHotReadRegionSchedulerSuite) TestWeighted(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+	hb := newBalanceHotReadRegionsScheduler(schedule.NewLimiter())
+
+	tc.addRegionStore(1, 6)
+	tc.addRegionStore(2, 6)
+	tc.addRegionStore(3, 6)
+	tc.updateStoreLeaderWeight(1, 4)
+
+	// Store 1 has the most hot leaders, but the fewest by its weight.
+	//| store_id | leader_weight | hot_leaders | normalized |
+	//|----------|---------------|-------------|------------|
+	//|     1    |       4       |      3      |    0.75    |
+	//|     2    |       1       |      2      |     2      |
+	//|     3    |       1       |      1      |     1      |
+	leaders := []uint64{1, 1, 1, 2, 2, 3}
+	for i, leader := range leaders {
+		var followers []uint64
+		for id := uint64(1); id <= 3; id++ {
+			if id != leader {
+				followers = append(followers, id)
+			}
+		}
+		tc.addLeaderRegionWithReadInfo(uint64(i+1), leader, 512*1024*schedule.RegionHeartBeatReportInterval, followers...)
+	}
+	opt.HotRegionLowThreshold = 0
+
+	CheckTransferLeader(c, hb.Schedule(tc, schedule.NewOpInfluence(nil, tc))[0], schedule.OpHotRegion, 2, 1)
+
+	stats := hb.GetHotReadStatus().AsLeader
+	c.Assert(stats[1].Weight, Equals, 4.0)
+	c.Assert(stats[1].NormalizedRegionsCount, Equals, 0.75)
+	c.Assert(stats[1].NormalizedFlowBytes, Equals, float64(stats[1].TotalFlowBytes)/4)
+	c.Assert(stats[2].NormalizedRegionsCount, Equals, 2.0)
+}
+
 func checkRemovePeer(c *C, op *schedule.Operator, storeID uint64) {
 	if op.Len() == 1 {
 		c.Assert(op.Step(0).(schedule.RemovePeer).FromStore, Equals, storeID)
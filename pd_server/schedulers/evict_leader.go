@@ -16,6 +16,7 @@ package schedulers
 import (
 	"fmt"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/pd/server/core"
@@ -37,8 +38,7 @@ func init() {
 
 type evictLeaderScheduler struct {
 	*baseScheduler
-	name     string
-	storeID  uint64
+	storeID  uint64 // accessed atomically, as it can be changed by config.
 	selector schedule.Selector
 }
 
@@ -52,14 +52,13 @@ func newEvictLeaderScheduler(limiter *schedule.Limiter, storeID uint64) schedule
 	base := newBaseScheduler(limiter)
 	return &evictLeaderScheduler{
 		baseScheduler: base,
-		name:          fmt.Sprintf("evict-leader-scheduler-%d", storeID),
 		storeID:       storeID,
 		selector:      schedule.NewRandomSelector(filters),
 	}
 }
 
 func (s *evictLeaderScheduler) GetName() string {
-	return s.name
+	return fmt.Sprintf("evict-leader-scheduler-%d", s.getStoreID())
 }
 
 func (s *evictLeaderScheduler) GetType() string {
@@ -67,11 +66,45 @@ func (s *evictLeaderScheduler) GetType() string {
 }
 
 func (s *evictLeaderScheduler) Prepare(cluster schedule.Cluster) error {
-	return errors.Trace(cluster.BlockStore(s.storeID))
+	return errors.Trace(cluster.BlockStore(s.getStoreID()))
 }
 
 func (s *evictLeaderScheduler) Cleanup(cluster schedule.Cluster) {
-	cluster.UnblockStore(s.storeID)
+	cluster.UnblockStore(s.getStoreID())
+}
+
+func (s *evictLeaderScheduler) getStoreID() uint64 {
+	return atomic.LoadUint64(&s.storeID)
+}
+
+func (s *evictLeaderScheduler) GetConfig() map[string]interface{} {
+	return map[string]interface{}{"store_id": s.getStoreID()}
+}
+
+// UpdateConfig changes the store to evict leaders from, which renames the
+// scheduler.
+func (s *evictLeaderScheduler) UpdateConfig(cluster schedule.Cluster, config map[string]interface{}) error {
+	storeID, err := parseStoreConfig(config, s.getStoreID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := changeBlockedStore(cluster, s.getStoreID(), storeID); err != nil {
+		return errors.Trace(err)
+	}
+	atomic.StoreUint64(&s.storeID, storeID)
+	return nil
+}
+
+func (s *evictLeaderScheduler) ParseConfig(config map[string]interface{}) ([]string, error) {
+	storeID, err := parseStoreConfig(config, s.getStoreID())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return []string{strconv.FormatUint(storeID, 10)}, nil
+}
+
+func (s *evictLeaderScheduler) GetArgs() []string {
+	return []string{strconv.FormatUint(s.getStoreID(), 10)}
 }
 
 func (s *evictLeaderScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
@@ -80,7 +113,7 @@ func (s *evictLeaderScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool
 
 func (s *evictLeaderScheduler) Schedule(cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
-	region := cluster.RandLeaderRegion(s.storeID)
+	region := cluster.RandLeaderRegion(s.getStoreID())
 	if region == nil {
 		schedulerCounter.WithLabelValues(s.GetName(), "no_leader").Inc()
 		return nil
@@ -92,7 +125,7 @@ func (s *evictLeaderScheduler) Schedule(cluster schedule.Cluster, opInfluence sc
 	}
 	schedulerCounter.WithLabelValues(s.GetName(), "new_operator").Inc()
 	step := schedule.TransferLeader{FromStore: region.Leader.GetStoreId(), ToStore: target.GetId()}
-	op := schedule.NewOperator("evict-leader", region.GetId(), schedule.OpLeader, step)
+	op := schedule.NewOperator("evict-leader", region.GetId(), region.GetRegionEpoch(), schedule.OpLeader, step)
 	op.SetPriorityLevel(core.HighPriority)
 	return []*schedule.Operator{op}
 }
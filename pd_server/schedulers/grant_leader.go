@@ -16,6 +16,7 @@ package schedulers
 import (
 	"fmt"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -41,8 +42,7 @@ const scheduleInterval = time.Millisecond * 10
 // grantLeaderScheduler transfers all leaders to peers in the store.
 type grantLeaderScheduler struct {
 	*baseScheduler
-	name    string
-	storeID uint64
+	storeID uint64 // accessed atomically, as it can be changed by config.
 }
 
 // newGrantLeaderScheduler creates an admin scheduler that transfers all leaders
@@ -51,24 +51,57 @@ func newGrantLeaderScheduler(limiter *schedule.Limiter, storeID uint64) schedule
 	base := newBaseScheduler(limiter)
 	return &grantLeaderScheduler{
 		baseScheduler: base,
-		name:          fmt.Sprintf("grant-leader-scheduler-%d", storeID),
 		storeID:       storeID,
 	}
 }
 
 func (s *grantLeaderScheduler) GetName() string {
-	return s.name
+	return fmt.Sprintf("grant-leader-scheduler-%d", s.getStoreID())
 }
 
 func (s *grantLeaderScheduler) GetType() string {
 	return "grant-leader"
 }
 func (s *grantLeaderScheduler) Prepare(cluster schedule.Cluster) error {
-	return errors.Trace(cluster.BlockStore(s.storeID))
+	return errors.Trace(cluster.BlockStore(s.getStoreID()))
 }
 
 func (s *grantLeaderScheduler) Cleanup(cluster schedule.Cluster) {
-	cluster.UnblockStore(s.storeID)
+	cluster.UnblockStore(s.getStoreID())
+}
+
+func (s *grantLeaderScheduler) getStoreID() uint64 {
+	return atomic.LoadUint64(&s.storeID)
+}
+
+func (s *grantLeaderScheduler) GetConfig() map[string]interface{} {
+	return map[string]interface{}{"store_id": s.getStoreID()}
+}
+
+// UpdateConfig changes the store to grant leaders to, which renames the
+// scheduler.
+func (s *grantLeaderScheduler) UpdateConfig(cluster schedule.Cluster, config map[string]interface{}) error {
+	storeID, err := parseStoreConfig(config, s.getStoreID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := changeBlockedStore(cluster, s.getStoreID(), storeID); err != nil {
+		return errors.Trace(err)
+	}
+	atomic.StoreUint64(&s.storeID, storeID)
+	return nil
+}
+
+func (s *grantLeaderScheduler) ParseConfig(config map[string]interface{}) ([]string, error) {
+	storeID, err := parseStoreConfig(config, s.getStoreID())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return []string{strconv.FormatUint(storeID, 10)}, nil
+}
+
+func (s *grantLeaderScheduler) GetArgs() []string {
+	return []string{strconv.FormatUint(s.getStoreID(), 10)}
 }
 
 func (s *grantLeaderScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
@@ -77,14 +110,15 @@ func (s *grantLeaderScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool
 
 func (s *grantLeaderScheduler) Schedule(cluster schedule.Cluster, opInfluence schedule.OpInfluence) []*schedule.Operator {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
-	region := cluster.RandFollowerRegion(s.storeID)
+	storeID := s.getStoreID()
+	region := cluster.RandFollowerRegion(storeID)
 	if region == nil {
 		schedulerCounter.WithLabelValues(s.GetName(), "no_follower").Inc()
 		return nil
 	}
 	schedulerCounter.WithLabelValues(s.GetName(), "new_operator").Inc()
-	step := schedule.TransferLeader{FromStore: region.Leader.GetStoreId(), ToStore: s.storeID}
-	op := schedule.NewOperator("grant-leader", region.GetId(), schedule.OpLeader, step)
+	step := schedule.TransferLeader{FromStore: region.Leader.GetStoreId(), ToStore: storeID}
+	op := schedule.NewOperator("grant-leader", region.GetId(), region.GetRegionEpoch(), schedule.OpLeader, step)
 	op.SetPriorityLevel(core.HighPriority)
 	return []*schedule.Operator{op}
 }
@@ -119,6 +119,9 @@ func (h *balanceHotRegionsScheduler) GetType() string {
 }
 
 func (h *balanceHotRegionsScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
+	if reachPolicyLimit(cluster, h.limiter, schedule.OpHotRegion) {
+		return false
+	}
 	return h.limiter.OperatorCount(schedule.OpHotRegion) < h.limit
 }
 
@@ -148,7 +151,7 @@ func (h *balanceHotRegionsScheduler) balanceHotReadRegions(cluster schedule.Clus
 	if srcRegion != nil {
 		schedulerCounter.WithLabelValues(h.GetName(), "move_leader").Inc()
 		step := schedule.TransferLeader{FromStore: srcRegion.Leader.GetStoreId(), ToStore: newLeader.GetStoreId()}
-		return []*schedule.Operator{schedule.NewOperator("transferHotReadLeader", srcRegion.GetId(), schedule.OpHotRegion|schedule.OpLeader, step)}
+		return []*schedule.Operator{schedule.NewOperator("transferHotReadLeader", srcRegion.GetId(), srcRegion.GetRegionEpoch(), schedule.OpHotRegion|schedule.OpLeader, step)}
 	}
 
 	// balance by peer
@@ -174,7 +177,7 @@ func (h *balanceHotRegionsScheduler) balanceHotWriteRegions(cluster schedule.Clu
 	if srcRegion != nil {
 		schedulerCounter.WithLabelValues(h.GetName(), "move_leader").Inc()
 		step := schedule.TransferLeader{FromStore: srcRegion.Leader.GetStoreId(), ToStore: newLeader.GetStoreId()}
-		return []*schedule.Operator{schedule.NewOperator("transferHotWriteLeader", srcRegion.GetId(), schedule.OpHotRegion|schedule.OpLeader, step)}
+		return []*schedule.Operator{schedule.NewOperator("transferHotWriteLeader", srcRegion.GetId(), srcRegion.GetRegionEpoch(), schedule.OpHotRegion|schedule.OpLeader, step)}
 	}
 
 	schedulerCounter.WithLabelValues(h.GetName(), "skip").Inc()
@@ -225,15 +228,41 @@ func (h *balanceHotRegionsScheduler) calcScore(items []*core.RegionStat, cluster
 			storeStat.RegionsStat = append(storeStat.RegionsStat, s)
 		}
 	}
+
+	var kind core.ResourceKind = core.LeaderKind
+	if isCountReplica {
+		kind = core.RegionKind
+	}
+	for storeID, storeStat := range stats {
+		storeStat.Weight = hotStoreWeight(cluster, storeID, kind)
+		storeStat.NormalizedFlowBytes = float64(storeStat.TotalFlowBytes) / storeStat.Weight
+		storeStat.NormalizedRegionsCount = float64(storeStat.RegionsCount) / storeStat.Weight
+	}
 	return stats
 }
 
+// hotStoreWeight returns the weight to normalize the hot flow of a store by.
+// Leader moves use the leader weight and peer moves use the region weight, so
+// that a store receives hot load in proportion to its weight.
+func hotStoreWeight(cluster schedule.Cluster, storeID uint64, kind core.ResourceKind) float64 {
+	store := cluster.GetStore(storeID)
+	if store == nil {
+		return core.DefaultWeight
+	}
+	return store.ResourceWeight(kind)
+}
+
 func (h *balanceHotRegionsScheduler) balanceByPeer(cluster schedule.Cluster, storesStat core.StoreHotRegionsStat) (*core.RegionInfo, *metapb.Peer, *metapb.Peer) {
-	srcStoreID := h.selectSrcStore(storesStat)
+	srcStoreID := h.selectSrcStore(cluster, storesStat, core.RegionKind)
 	if srcStoreID == 0 {
 		return nil, nil, nil
 	}
 
+	// The source store should have the tokens to remove a peer.
+	if srcStore := cluster.GetStore(srcStoreID); srcStore != nil && schedule.NewStoreLimitFilter().FilterSource(cluster, srcStore) {
+		return nil, nil, nil
+	}
+
 	// get one source region and a target store.
 	// For each region in the source store, we try to find the best target store;
 	// If we can find a target store, then return from this method.
@@ -254,6 +283,8 @@ func (h *balanceHotRegionsScheduler) balanceByPeer(cluster schedule.Cluster, sto
 			schedule.NewExcludedFilter(srcRegion.GetStoreIds(), srcRegion.GetStoreIds()),
 			schedule.NewDistinctScoreFilter(cluster.GetLocationLabels(), cluster.GetRegionStores(srcRegion), srcStore),
 			schedule.NewStorageThresholdFilter(),
+			schedule.NewRegionSizeLimitFilter(),
+			schedule.NewStoreLimitFilter(),
 		}
 		destStoreIDs := make([]uint64, 0, len(stores))
 		for _, store := range stores {
@@ -263,9 +294,9 @@ func (h *balanceHotRegionsScheduler) balanceByPeer(cluster schedule.Cluster, sto
 			destStoreIDs = append(destStoreIDs, store.GetId())
 		}
 
-		destStoreID = h.selectDestStore(destStoreIDs, rs.FlowBytes, srcStoreID, storesStat)
+		destStoreID = h.selectDestStore(cluster, destStoreIDs, rs.FlowBytes, srcStoreID, storesStat, core.RegionKind)
 		if destStoreID != 0 {
-			h.adjustBalanceLimit(srcStoreID, storesStat)
+			h.adjustBalanceLimit(cluster, srcStoreID, storesStat, core.RegionKind)
 
 			var srcPeer *metapb.Peer
 			for _, peer := range srcRegion.GetPeers() {
@@ -295,7 +326,7 @@ func (h *balanceHotRegionsScheduler) balanceByPeer(cluster schedule.Cluster, sto
 }
 
 func (h *balanceHotRegionsScheduler) balanceByLeader(cluster schedule.Cluster, storesStat core.StoreHotRegionsStat) (*core.RegionInfo, *metapb.Peer) {
-	srcStoreID := h.selectSrcStore(storesStat)
+	srcStoreID := h.selectSrcStore(cluster, storesStat, core.LeaderKind)
 	if srcStoreID == 0 {
 		return nil, nil
 	}
@@ -323,14 +354,14 @@ func (h *balanceHotRegionsScheduler) balanceByLeader(cluster schedule.Cluster, s
 		if len(candidateStoreIDs) == 0 {
 			continue
 		}
-		destStoreID := h.selectDestStore(candidateStoreIDs, rs.FlowBytes, srcStoreID, storesStat)
+		destStoreID := h.selectDestStore(cluster, candidateStoreIDs, rs.FlowBytes, srcStoreID, storesStat, core.LeaderKind)
 		if destStoreID == 0 {
 			continue
 		}
 
 		destPeer := srcRegion.GetStorePeer(destStoreID)
 		if destPeer != nil {
-			h.adjustBalanceLimit(srcStoreID, storesStat)
+			h.adjustBalanceLimit(cluster, srcStoreID, storesStat, core.LeaderKind)
 			return srcRegion, destPeer
 		}
 	}
@@ -340,15 +371,17 @@ func (h *balanceHotRegionsScheduler) balanceByLeader(cluster schedule.Cluster, s
 // Select the store to move hot regions from.
 // We choose the store with the maximum number of hot region first.
 // Inside these stores, we choose the one with maximum flow bytes.
-func (h *balanceHotRegionsScheduler) selectSrcStore(stats core.StoreHotRegionsStat) (srcStoreID uint64) {
-	var (
-		maxFlowBytes           uint64
-		maxHotStoreRegionCount int
-	)
+// Both are normalized by the store weights of the kind.
+func (h *balanceHotRegionsScheduler) selectSrcStore(cluster schedule.Cluster, stats core.StoreHotRegionsStat, kind core.ResourceKind) (srcStoreID uint64) {
+	var maxFlowBytes, maxHotStoreRegionCount float64
 
 	for storeID, statistics := range stats {
-		count, flowBytes := statistics.RegionsStat.Len(), statistics.TotalFlowBytes
-		if count >= 2 && (count > maxHotStoreRegionCount || (count == maxHotStoreRegionCount && flowBytes > maxFlowBytes)) {
+		if statistics.RegionsStat.Len() < 2 {
+			continue
+		}
+		weight := hotStoreWeight(cluster, storeID, kind)
+		count, flowBytes := float64(statistics.RegionsStat.Len())/weight, float64(statistics.TotalFlowBytes)/weight
+		if count > maxHotStoreRegionCount || (count == maxHotStoreRegionCount && flowBytes > maxFlowBytes) {
 			maxHotStoreRegionCount = count
 			maxFlowBytes = flowBytes
 			srcStoreID = storeID
@@ -358,27 +391,32 @@ func (h *balanceHotRegionsScheduler) selectSrcStore(stats core.StoreHotRegionsSt
 }
 
 // selectDestStore selects a target store to hold the region of the source region.
-// We choose a target store based on the hot region number and flow bytes of this store.
-func (h *balanceHotRegionsScheduler) selectDestStore(candidateStoreIDs []uint64, regionFlowBytes uint64, srcStoreID uint64, storesStat core.StoreHotRegionsStat) (destStoreID uint64) {
+// We choose a target store based on the hot region number and flow bytes of this store,
+// normalized by the store weights of the kind.
+func (h *balanceHotRegionsScheduler) selectDestStore(cluster schedule.Cluster, candidateStoreIDs []uint64, regionFlowBytes uint64, srcStoreID uint64, storesStat core.StoreHotRegionsStat, kind core.ResourceKind) (destStoreID uint64) {
 	sr := storesStat[srcStoreID]
-	srcFlowBytes := sr.TotalFlowBytes
-	srcHotRegionsCount := sr.RegionsStat.Len()
+	srcWeight := hotStoreWeight(cluster, srcStoreID, kind)
+	srcFlowBytes := float64(sr.TotalFlowBytes) / srcWeight
+	// The normalized hot region count of the source store after the move.
+	srcHotRegionsCount := float64(sr.RegionsStat.Len()-1) / srcWeight
 
 	var (
-		minFlowBytes    uint64 = math.MaxUint64
-		minRegionsCount        = int(math.MaxInt32)
+		minFlowBytes    = math.MaxFloat64
+		minRegionsCount = math.MaxFloat64
 	)
 	for _, storeID := range candidateStoreIDs {
 		if s, ok := storesStat[storeID]; ok {
-			if srcHotRegionsCount-s.RegionsStat.Len() > 1 && minRegionsCount > s.RegionsStat.Len() {
+			weight := hotStoreWeight(cluster, storeID, kind)
+			regionsCount, flowBytes := float64(s.RegionsStat.Len())/weight, float64(s.TotalFlowBytes)/weight
+			if srcHotRegionsCount >= float64(s.RegionsStat.Len()+1)/weight && minRegionsCount > regionsCount {
 				destStoreID = storeID
-				minFlowBytes = s.TotalFlowBytes
-				minRegionsCount = s.RegionsStat.Len()
+				minFlowBytes = flowBytes
+				minRegionsCount = regionsCount
 				continue
 			}
-			if minRegionsCount == s.RegionsStat.Len() && minFlowBytes > s.TotalFlowBytes &&
-				uint64(float64(srcFlowBytes)*hotRegionScheduleFactor) > s.TotalFlowBytes+2*regionFlowBytes {
-				minFlowBytes = s.TotalFlowBytes
+			if minRegionsCount == regionsCount && minFlowBytes > flowBytes &&
+				srcFlowBytes*hotRegionScheduleFactor > float64(s.TotalFlowBytes+2*regionFlowBytes)/weight {
+				minFlowBytes = flowBytes
 				destStoreID = storeID
 			}
 		} else {
@@ -389,17 +427,19 @@ func (h *balanceHotRegionsScheduler) selectDestStore(candidateStoreIDs []uint64,
 	return
 }
 
-func (h *balanceHotRegionsScheduler) adjustBalanceLimit(storeID uint64, storesStat core.StoreHotRegionsStat) {
+func (h *balanceHotRegionsScheduler) adjustBalanceLimit(cluster schedule.Cluster, storeID uint64, storesStat core.StoreHotRegionsStat, kind core.ResourceKind) {
 	srcStoreStatistics := storesStat[storeID]
 
-	var hotRegionTotalCount float64
-	for _, m := range storesStat {
+	var hotRegionTotalCount, totalWeight float64
+	for id, m := range storesStat {
 		hotRegionTotalCount += float64(m.RegionsStat.Len())
+		totalWeight += hotStoreWeight(cluster, id, kind)
 	}
 
-	avgRegionCount := hotRegionTotalCount / float64(len(storesStat))
+	// The hot region count the source store should have by its weight.
+	expectedRegionCount := hotRegionTotalCount / totalWeight * hotStoreWeight(cluster, storeID, kind)
 	// Multiplied by hotRegionLimitFactor to avoid transfer back and forth
-	limit := uint64((float64(srcStoreStatistics.RegionsStat.Len()) - avgRegionCount) * hotRegionLimitFactor)
+	limit := uint64(math.Max(float64(srcStoreStatistics.RegionsStat.Len())-expectedRegionCount, 0) * hotRegionLimitFactor)
 	h.limit = maxUint64(1, limit)
 }
 
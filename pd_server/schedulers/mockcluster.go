@@ -148,13 +148,27 @@ func (mc *mockCluster) addRegionStore(storeID uint64, regionCount int) {
 
 func (mc *mockCluster) updateStoreLeaderWeight(storeID uint64, weight float64) {
 	store := mc.GetStore(storeID)
-	store.LeaderWeight = weight
+	store.SetWeight(core.LeaderKind, core.WeightSourceAPI, weight)
 	mc.PutStore(store)
 }
 
 func (mc *mockCluster) updateStoreRegionWeight(storeID uint64, weight float64) {
 	store := mc.GetStore(storeID)
-	store.RegionWeight = weight
+	store.SetWeight(core.RegionKind, core.WeightSourceAPI, weight)
+	mc.PutStore(store)
+}
+
+func (mc *mockCluster) updateStoreRegionSizeLimit(storeID uint64, soft, hard int64) {
+	store := mc.GetStore(storeID)
+	store.RegionSizeSoftLimit, store.RegionSizeHardLimit = soft, hard
+	mc.PutStore(store)
+}
+
+func (mc *mockCluster) updateStoreLimit(storeID uint64, typ core.StoreLimitType, rate, taken float64) {
+	store := mc.GetStore(storeID)
+	limit := core.NewStoreLimit(rate)
+	limit.Take(taken)
+	store.SetStoreLimit(typ, limit)
 	mc.PutStore(store)
 }
 
@@ -355,6 +369,7 @@ const (
 	defaultReplicaScheduleLimit = 32
 	defaultMergeScheduleLimit   = 20
 	defaultTolerantSizeRatio    = 2.5
+	defaultReplicaWeightTol     = 0.5
 )
 
 // MockSchedulerOptions is a mock of SchedulerOptions
@@ -372,6 +387,10 @@ type MockSchedulerOptions struct {
 	LocationLabels        []string
 	HotRegionLowThreshold int
 	TolerantSizeRatio     float64
+	ReplicaWeightTol      float64
+	BalanceCandidates     int
+	BalanceBatchSize      uint64
+	StoreBalanceLimit     uint64
 	LabelProperties       map[string][]*metapb.StoreLabel
 }
 
@@ -388,6 +407,8 @@ func newMockSchedulerOptions() *MockSchedulerOptions {
 	mso.MaxPendingPeerCount = defaultMaxPendingPeerCount
 	mso.MaxMergeRegionSize = defaultMaxMergeRegionSize
 	mso.TolerantSizeRatio = defaultTolerantSizeRatio
+	mso.ReplicaWeightTol = defaultReplicaWeightTol
+	mso.BalanceBatchSize = 1
 	return mso
 }
 
@@ -451,6 +472,26 @@ func (mso *MockSchedulerOptions) GetTolerantSizeRatio() float64 {
 	return mso.TolerantSizeRatio
 }
 
+// GetReplicaWeightTolerance mock method
+func (mso *MockSchedulerOptions) GetReplicaWeightTolerance() float64 {
+	return mso.ReplicaWeightTol
+}
+
+// GetBalanceCandidateCount mock method
+func (mso *MockSchedulerOptions) GetBalanceCandidateCount() int {
+	return mso.BalanceCandidates
+}
+
+// GetBalanceBatchSize mock method
+func (mso *MockSchedulerOptions) GetBalanceBatchSize() uint64 {
+	return mso.BalanceBatchSize
+}
+
+// GetStoreBalanceLimit mock method
+func (mso *MockSchedulerOptions) GetStoreBalanceLimit() uint64 {
+	return mso.StoreBalanceLimit
+}
+
 // SetMaxReplicas mock method
 func (mso *MockSchedulerOptions) SetMaxReplicas(replicas int) {
 	mso.MaxReplicas = replicas
@@ -14,8 +14,11 @@
 package schedulers
 
 import (
+	"math"
+
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
 	"github.com/pingcap/pd/server/namespace"
 	"github.com/pingcap/pd/server/schedule"
 	log "github.com/sirupsen/logrus"
@@ -51,6 +54,42 @@ func (s *testShuffleLeaderSuite) TestShuffle(c *C) {
 	}
 }
 
+var _ = Suite(&testShuffleRegionSuite{})
+
+type testShuffleRegionSuite struct{}
+
+func (s *testShuffleRegionSuite) TestStoreLimit(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+
+	sr, err := schedule.CreateScheduler("shuffle-region", schedule.NewLimiter())
+	c.Assert(err, IsNil)
+
+	tc.addRegionStore(1, 1)
+	tc.addRegionStore(2, 1)
+	tc.addRegionStore(3, 1)
+	tc.addRegionStore(4, 0)
+	tc.addLeaderRegion(1, 1, 2, 3)
+
+	// Store 4 is the only target, and it runs out of the tokens to add peers.
+	tc.updateStoreLimit(4, core.StoreAddPeer, 1, 1)
+	for i := 0; i < 10; i++ {
+		c.Assert(sr.Schedule(tc, schedule.NewOpInfluence(nil, tc)), IsNil)
+	}
+
+	// Only store 1 has the tokens to remove peers.
+	tc.updateStoreLimit(4, core.StoreAddPeer, 1, 0)
+	tc.updateStoreLimit(2, core.StoreRemovePeer, 1, 1)
+	tc.updateStoreLimit(3, core.StoreRemovePeer, 1, 1)
+	tc.updateStoreLimit(4, core.StoreRemovePeer, 1, 1)
+	for i := 0; i < 10; i++ {
+		CheckTransferPeer(c, sr.Schedule(tc, schedule.NewOpInfluence(nil, tc))[0], schedule.OpAdmin, 1, 4)
+	}
+
+	tc.updateStoreLimit(1, core.StoreRemovePeer, 1, 1)
+	c.Assert(sr.Schedule(tc, schedule.NewOpInfluence(nil, tc)), IsNil)
+}
+
 var _ = Suite(&testBalanceAdjacentRegionSuite{})
 
 type testBalanceAdjacentRegionSuite struct{}
@@ -108,6 +147,75 @@ func (s *testBalanceAdjacentRegionSuite) TestBalance(c *C) {
 	}
 }
 
+func (s *testBalanceAdjacentRegionSuite) TestStoreLimit(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+
+	tc.addLeaderStore(1, 5)
+	tc.addLeaderStore(2, 0)
+	tc.addLeaderStore(3, 0)
+	tc.addLeaderStore(4, 0)
+	tc.addLeaderRegionWithRange(1, "", "a", 1, 2, 3)
+	tc.addLeaderRegionWithRange(2, "a", "b", 1, 2, 3)
+
+	// A new scheduler scans the adjacent regions from the start.
+	scheduleOnce := func() []*schedule.Operator {
+		sc, err := schedule.CreateScheduler("adjacent-region", schedule.NewLimiter())
+		c.Assert(err, IsNil)
+		return sc.Schedule(tc, schedule.NewOpInfluence(nil, tc))
+	}
+
+	// Store 4 is the only target for the peer of region 1, and it runs out of
+	// the tokens to add peers.
+	tc.updateStoreLimit(4, core.StoreAddPeer, 1, 1)
+	c.Assert(scheduleOnce(), IsNil)
+
+	// Store 1 runs out of the tokens to remove peers.
+	tc.updateStoreLimit(4, core.StoreAddPeer, 1, 0)
+	tc.updateStoreLimit(1, core.StoreRemovePeer, 1, 1)
+	c.Assert(scheduleOnce(), IsNil)
+
+	tc.updateStoreLimit(1, core.StoreRemovePeer, 1, 0)
+	checkTransferPeerWithLeaderTransfer(c, scheduleOnce()[0], schedule.OpAdjacent, 1, 4)
+}
+
+var _ = Suite(&testPolicyLimitSuite{})
+
+type testPolicyLimitSuite struct{}
+
+// policyMockCluster is a cluster capped by a schedule policy.
+type policyMockCluster struct {
+	*mockCluster
+	limit uint64
+}
+
+func (c policyMockCluster) GetPolicyLimit() uint64 {
+	return c.limit
+}
+
+func (s *testPolicyLimitSuite) TestPolicyLimit(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+	limiter := schedule.NewLimiter()
+
+	for _, typ := range []string{"adjacent-region", "hot-region"} {
+		sc, err := schedule.CreateScheduler(typ, limiter)
+		c.Assert(err, IsNil)
+		c.Assert(sc.IsScheduleAllowed(tc), IsTrue)
+		c.Assert(sc.IsScheduleAllowed(policyMockCluster{mockCluster: tc, limit: 1}), IsTrue)
+		c.Assert(sc.IsScheduleAllowed(policyMockCluster{mockCluster: tc, limit: 0}), IsFalse)
+	}
+
+	// The adjacent-region scheduler allows more operators than the cap.
+	sc, err := schedule.CreateScheduler("adjacent-region", limiter)
+	c.Assert(err, IsNil)
+	op := schedule.NewOperator("test", 1, nil, schedule.OpAdjacent|schedule.OpLeader)
+	limiter.UpdateCounts(map[uint64]*schedule.Operator{1: op})
+	c.Assert(sc.IsScheduleAllowed(tc), IsTrue)
+	c.Assert(sc.IsScheduleAllowed(policyMockCluster{mockCluster: tc, limit: 1}), IsFalse)
+	c.Assert(sc.IsScheduleAllowed(policyMockCluster{mockCluster: tc, limit: 2}), IsTrue)
+}
+
 type sequencer struct {
 	maxID uint64
 	curID uint64
@@ -159,7 +267,7 @@ func (s *testScatterRegionSuite) scatter(c *C, numStores, numRegions uint64) {
 
 	for i := uint64(1); i <= numRegions; i++ {
 		region := tc.GetRegion(i)
-		if op := scatterer.Scatter(region); op != nil {
+		if op := scatterer.Scatter(region, schedule.ScatterModeRandom); op != nil {
 			log.Info(op)
 			tc.applyOperator(op)
 		}
@@ -179,6 +287,46 @@ func (s *testScatterRegionSuite) scatter(c *C, numStores, numRegions uint64) {
 	}
 }
 
+func (s *testScatterRegionSuite) TestWeighted(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+
+	weights := []float64{1, 1, 1, 1, 2, 2}
+	for i, weight := range weights {
+		tc.addRegionStore(uint64(i+1), 0)
+		tc.updateStoreRegionWeight(uint64(i+1), weight)
+	}
+	// Store 7 has the largest weight but reaches its hard limit.
+	tc.addRegionStore(7, 0)
+	tc.updateStoreRegionWeight(7, 8)
+	tc.updateStoreRegionSizeLimit(7, 0, 5)
+
+	const numRegions = 8
+	seq := newSequencer(uint64(len(weights)))
+	for i := uint64(1); i <= numRegions; i++ {
+		tc.addLeaderRegion(i, seq.next(), seq.next(), seq.next())
+	}
+
+	scatterer := schedule.NewRegionScatterer(tc, namespace.DefaultClassifier)
+	for i := uint64(1); i <= numRegions; i++ {
+		if op := scatterer.Scatter(tc.GetRegion(i), schedule.ScatterModeWeighted); op != nil {
+			tc.applyOperator(op)
+		}
+	}
+
+	countPeers := make(map[uint64]float64)
+	for i := uint64(1); i <= numRegions; i++ {
+		for _, peer := range tc.GetRegion(i).GetPeers() {
+			countPeers[peer.GetStoreId()]++
+		}
+	}
+	// Each store has peers in proportion to its weight.
+	for i, weight := range weights {
+		c.Assert(math.Abs(countPeers[uint64(i+1)]-numRegions*3*weight/8) <= 1, IsTrue)
+	}
+	c.Assert(countPeers[7], Equals, 0.0)
+}
+
 var _ = Suite(&testRejectLeaderSuite{})
 
 type testRejectLeaderSuite struct{}
@@ -210,3 +358,66 @@ func (s *testRejectLeaderSuite) TestRejectLeader(c *C) {
 	op = sl.Schedule(tc, schedule.NewOpInfluence(nil, tc))
 	c.Assert(op, IsNil)
 }
+
+var _ = Suite(&testConfigurableSchedulerSuite{})
+
+type testConfigurableSchedulerSuite struct{}
+
+func (s *testConfigurableSchedulerSuite) TestEvictLeader(c *C) {
+	opt := newTestScheduleConfig()
+	tc := newMockCluster(opt)
+	tc.addLeaderStore(1, 1)
+	tc.addLeaderStore(2, 0)
+	tc.addLeaderStore(3, 0)
+
+	sc, err := schedule.CreateScheduler("evict-leader", schedule.NewLimiter(), "1")
+	c.Assert(err, IsNil)
+	c.Assert(sc.Prepare(tc), IsNil)
+	cs := sc.(schedule.ConfigurableScheduler)
+	c.Assert(cs.GetConfig()["store_id"], Equals, uint64(1))
+
+	// Invalid config.
+	c.Assert(cs.UpdateConfig(tc, map[string]interface{}{"store_id": -1.0}), NotNil)
+	c.Assert(cs.UpdateConfig(tc, map[string]interface{}{"store_id": "2"}), NotNil)
+	c.Assert(cs.UpdateConfig(tc, map[string]interface{}{"foo": 2.0}), NotNil)
+	c.Assert(cs.UpdateConfig(tc, map[string]interface{}{"store_id": 4.0}), NotNil)
+	// Store 3 is blocked by another scheduler.
+	c.Assert(tc.BlockStore(3), IsNil)
+	c.Assert(cs.UpdateConfig(tc, map[string]interface{}{"store_id": 3.0}), NotNil)
+	c.Assert(sc.GetName(), Equals, "evict-leader-scheduler-1")
+
+	// The config is parsed without being applied.
+	_, err = cs.ParseConfig(map[string]interface{}{"foo": 2.0})
+	c.Assert(err, NotNil)
+	args, err := cs.ParseConfig(map[string]interface{}{"store_id": 2.0})
+	c.Assert(err, IsNil)
+	c.Assert(args, DeepEquals, []string{"2"})
+	c.Assert(sc.GetName(), Equals, "evict-leader-scheduler-1")
+
+	// The blocked store is changed with the scheduler name.
+	c.Assert(cs.UpdateConfig(tc, map[string]interface{}{"store_id": 2.0}), IsNil)
+	c.Assert(sc.GetName(), Equals, "evict-leader-scheduler-2")
+	c.Assert(cs.GetArgs(), DeepEquals, []string{"2"})
+	c.Assert(tc.BlockStore(1), IsNil)
+	c.Assert(tc.BlockStore(2), NotNil)
+}
+
+func (s *testConfigurableSchedulerSuite) TestAdjacentRegion(c *C) {
+	sc, err := schedule.CreateScheduler("adjacent-region", schedule.NewLimiter(), "10", "5")
+	c.Assert(err, IsNil)
+	cs := sc.(schedule.ConfigurableScheduler)
+	c.Assert(cs.GetArgs(), DeepEquals, []string{"10", "5"})
+
+	c.Assert(cs.UpdateConfig(nil, map[string]interface{}{"peer_limit": 1.5}), NotNil)
+	c.Assert(cs.UpdateConfig(nil, map[string]interface{}{"peer_limit": 2.0}), IsNil)
+	c.Assert(cs.GetConfig(), DeepEquals, map[string]interface{}{"leader_limit": uint64(10), "peer_limit": uint64(2)})
+	args, err := cs.ParseConfig(map[string]interface{}{"leader_limit": 3.0})
+	c.Assert(err, IsNil)
+	c.Assert(args, DeepEquals, []string{"3", "2"})
+	c.Assert(cs.GetArgs(), DeepEquals, []string{"10", "2"})
+
+	// The scheduler is recreated with the same config from the args.
+	sc, err = schedule.CreateScheduler("adjacent-region", schedule.NewLimiter(), cs.GetArgs()...)
+	c.Assert(err, IsNil)
+	c.Assert(sc.(schedule.ConfigurableScheduler).GetConfig(), DeepEquals, cs.GetConfig())
+}
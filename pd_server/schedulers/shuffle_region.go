@@ -35,6 +35,7 @@ func newShuffleRegionScheduler(limiter *schedule.Limiter) schedule.Scheduler {
 	filters := []schedule.Filter{
 		schedule.NewStateFilter(),
 		schedule.NewHealthFilter(),
+		schedule.NewStoreLimitFilter(),
 	}
 	base := newBaseScheduler(limiter)
 	return &shuffleRegionScheduler{
@@ -14,8 +14,10 @@
 package schedulers
 
 import (
+	"math"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/montanaflynn/stats"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/server/cache"
@@ -64,6 +66,74 @@ func scheduleAddPeer(cluster schedule.Cluster, s schedule.Selector, filters ...s
 	return newPeer
 }
 
+// reachPolicyLimit checks if the operators of the kind reach the cap of the
+// schedule policy of the cluster, for schedulers with limits of their own.
+func reachPolicyLimit(cluster schedule.Cluster, limiter *schedule.Limiter, kind schedule.OperatorKind) bool {
+	if c, ok := cluster.(schedule.PolicyCluster); ok {
+		return limiter.OperatorCount(kind) >= c.GetPolicyLimit()
+	}
+	return false
+}
+
+// parseConfigUint64 gets the unsigned integer config item of the key. It
+// returns false if the item is not in the config.
+func parseConfigUint64(config map[string]interface{}, key string) (uint64, bool, error) {
+	val, ok := config[key]
+	if !ok {
+		return 0, false, nil
+	}
+	f, ok := val.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return 0, false, errors.Errorf("%s should be a non-negative integer, but %v", key, val)
+	}
+	return uint64(f), true, nil
+}
+
+// checkConfigKeys checks if the config only has the items of the keys.
+func checkConfigKeys(config map[string]interface{}, keys ...string) error {
+	for key := range config {
+		found := false
+		for _, k := range keys {
+			found = found || k == key
+		}
+		if !found {
+			return errors.Errorf("unknown config item %s", key)
+		}
+	}
+	return nil
+}
+
+// parseStoreConfig parses the store_id of the config of a scheduler on a
+// store, and returns the current store if it is not in the config.
+func parseStoreConfig(config map[string]interface{}, storeID uint64) (uint64, error) {
+	if err := checkConfigKeys(config, "store_id"); err != nil {
+		return 0, errors.Trace(err)
+	}
+	newStoreID, ok, err := parseConfigUint64(config, "store_id")
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if !ok {
+		return storeID, nil
+	}
+	return newStoreID, nil
+}
+
+// changeBlockedStore blocks the new store in place of the old one.
+func changeBlockedStore(cluster schedule.Cluster, oldStoreID, newStoreID uint64) error {
+	if oldStoreID == newStoreID {
+		return nil
+	}
+	if cluster.GetStore(newStoreID) == nil {
+		return errors.Errorf("store %d not found", newStoreID)
+	}
+	if err := cluster.BlockStore(newStoreID); err != nil {
+		return errors.Trace(err)
+	}
+	cluster.UnblockStore(oldStoreID)
+	return nil
+}
+
 func minUint64(a, b uint64) uint64 {
 	if a < b {
 		return a
@@ -96,6 +166,143 @@ func shouldBalance(sourceSize int64, sourceWeight float64, targetSize int64, tar
 	return (float64(sourceSize)-moveSize)/sourceWeight > (float64(targetSize)+moveSize)/targetWeight
 }
 
+// balanceGain returns how much moving a region of moveSize from the source
+// to the target reduces the difference of their weighted sizes.
+func balanceGain(sourceSize int64, sourceWeight float64, targetSize int64, targetWeight float64, moveSize float64) float64 {
+	if sourceWeight == 0 || targetWeight == 0 {
+		return 0
+	}
+	before := math.Abs(float64(sourceSize)/sourceWeight - float64(targetSize)/targetWeight)
+	after := math.Abs((float64(sourceSize)-moveSize)/sourceWeight - (float64(targetSize)+moveSize)/targetWeight)
+	return before - after
+}
+
+// balanceCandidateSampleFactor is the max times to pick regions for each
+// balance candidate, as the picked regions may be duplicated or excluded.
+const balanceCandidateSampleFactor = 3
+
+// sampleRegions picks up to count distinct regions by pick, excluding the
+// regions which should not be balanced now and the regions of the batch.
+func sampleRegions(cluster schedule.Cluster, limiter *schedule.Limiter, tracer *schedule.Tracer, schedulerName string, count int, batchRegions map[uint64]struct{}, pick func() *core.RegionInfo) []*core.RegionInfo {
+	regions := make([]*core.RegionInfo, 0, count)
+	picked := make(map[uint64]struct{}, count)
+	for i := 0; i < count*balanceCandidateSampleFactor && len(regions) < count; i++ {
+		region := pick()
+		if region == nil {
+			schedulerCounter.WithLabelValues(schedulerName, "no_region").Inc()
+			break
+		}
+		if _, ok := picked[region.GetId()]; ok {
+			continue
+		}
+		picked[region.GetId()] = struct{}{}
+		if _, ok := batchRegions[region.GetId()]; ok {
+			continue
+		}
+		if reason := excludeCandidate(cluster, limiter, region); reason != "" {
+			log.Debugf("[%s] exclude candidate region%d: %s", schedulerName, region.GetId(), reason)
+			tracer.Record("exclude-candidate", 0, region.GetId(), "%s", reason)
+			schedulerCounter.WithLabelValues(schedulerName, reason).Inc()
+			continue
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// excludeCandidate returns the reason to exclude the region from balance
+// candidates, or empty if it is a candidate.
+func excludeCandidate(cluster schedule.Cluster, limiter *schedule.Limiter, region *core.RegionInfo) string {
+	switch {
+	case cluster.IsRegionHot(region.GetId()):
+		return "region_hot"
+	case len(region.PendingPeers) > 0:
+		return "pending_peer"
+	case len(region.DownPeers) > 0:
+		return "down_peer"
+	case limiter.HasOperator(region.GetId()):
+		return "has_operator"
+	}
+	return ""
+}
+
+// balanceBatchRetryFactor is the max times to call scheduleOnce for each
+// operator of a batch, as the operators may conflict with the former ones.
+const balanceBatchRetryFactor = 2
+
+// scheduleBatch calls scheduleOnce to create a batch of operators on
+// distinct regions. The batch is limited by the balance batch size, the
+// headroom of the schedule limit of the kind, and the pending operators of
+// each store. The influence of each operator is added to opInfluence, so
+// that the later operators in the batch don't overshoot.
+func scheduleBatch(cluster schedule.Cluster, limiter *schedule.Limiter, schedulerName string, kind schedule.OperatorKind, limit uint64,
+	opInfluence schedule.OpInfluence, scheduleOnce func(schedule.Cluster, schedule.OpInfluence, map[uint64]struct{}) []*schedule.Operator) []*schedule.Operator {
+	count := limiter.OperatorCount(kind)
+	if count >= limit {
+		return nil
+	}
+	size := maxUint64(1, minUint64(cluster.GetBalanceBatchSize(), limit-count))
+	storeLimit := cluster.GetStoreBalanceLimit()
+
+	var batch []*schedule.Operator
+	regions := make(map[uint64]struct{})
+	storeCounts := make(map[uint64]uint64)
+	for i := uint64(0); i < size*balanceBatchRetryFactor && uint64(len(batch)) < size; i++ {
+		ops := scheduleOnce(cluster, opInfluence, regions)
+		if len(ops) == 0 {
+			break
+		}
+		if reason := checkBatchConflict(limiter, ops, regions, storeCounts, storeLimit); reason != "" {
+			log.Debugf("[%s] skip operator of region%d: %s", schedulerName, ops[0].RegionID(), reason)
+			schedulerCounter.WithLabelValues(schedulerName, reason).Inc()
+			continue
+		}
+		for _, op := range ops {
+			regions[op.RegionID()] = struct{}{}
+			for _, id := range op.Stores() {
+				storeCounts[id]++
+			}
+			if region := cluster.GetRegion(op.RegionID()); region != nil {
+				op.Influence(opInfluence, region)
+			}
+		}
+		batch = append(batch, ops...)
+	}
+	return batch
+}
+
+// inBatch checks if any of the operators is on a region of the batch.
+func inBatch(ops []*schedule.Operator, batchRegions map[uint64]struct{}) bool {
+	for _, op := range ops {
+		if _, ok := batchRegions[op.RegionID()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBatchConflict returns the reason that the operators can't join the
+// batch, or empty if they can.
+func checkBatchConflict(limiter *schedule.Limiter, ops []*schedule.Operator, regions map[uint64]struct{}, storeCounts map[uint64]uint64, storeLimit uint64) string {
+	if inBatch(ops, regions) {
+		return "batch_conflict"
+	}
+	for _, op := range ops {
+		if limiter.HasOperator(op.RegionID()) {
+			return "has_operator"
+		}
+		if storeLimit == 0 {
+			continue
+		}
+		for _, id := range op.Stores() {
+			if limiter.StoreOperatorCount(id)+storeCounts[id] >= storeLimit {
+				return "store_limit"
+			}
+		}
+	}
+	return ""
+}
+
 func adjustBalanceLimit(cluster schedule.Cluster, kind core.ResourceKind) uint64 {
 	stores := cluster.GetStores()
 	counts := make([]float64, 0, len(stores))
@@ -91,6 +91,8 @@ type Server struct {
 	resignCh chan struct{}
 	// For async region heartbeat.
 	hbStreams *heartbeatStreams
+	// For recording the heartbeats and splits.
+	recorder *trafficRecorder
 }
 
 // CreateServer creates the UNINITIALIZED pd server with given configuration.
@@ -211,6 +213,12 @@ func (s *Server) startServer() error {
 	if s.classifier, err = namespace.CreateClassifier(s.cfg.NamespaceClassifier, s.kv, s.idAlloc); err != nil {
 		return errors.Trace(err)
 	}
+	if s.cfg.RecordFile != "" {
+		if s.recorder, err = newTrafficRecorder(s.cfg.RecordFile, int64(s.cfg.RecordFileMaxSize)<<20); err != nil {
+			return errors.Trace(err)
+		}
+		log.Infof("record heartbeats and splits to %s", s.cfg.RecordFile)
+	}
 
 	// Server has started.
 	atomic.StoreInt64(&s.isServing, 1)
@@ -272,6 +280,10 @@ func (s *Server) Close() {
 		s.hbStreams.Close()
 	}
 
+	if err := s.recorder.close(); err != nil {
+		log.Errorf("close recorder error: %v", err)
+	}
+
 	log.Info("close server")
 }
 
@@ -453,11 +465,21 @@ func (s *Server) GetScheduleConfig() *ScheduleConfig {
 }
 
 // SetScheduleConfig sets the balance config information.
-func (s *Server) SetScheduleConfig(cfg ScheduleConfig) {
+func (s *Server) SetScheduleConfig(cfg ScheduleConfig) error {
+	if err := cfg.validate(); err != nil {
+		return errors.Trace(err)
+	}
 	old := s.scheduleOpt.load()
 	s.scheduleOpt.store(&cfg)
 	s.scheduleOpt.persist(s.kv)
+	if cluster := s.GetRaftCluster(); cluster != nil {
+		if err := cluster.cachedCluster.setWeightPolicy(cfg.WeightPolicy); err != nil {
+			return errors.Trace(err)
+		}
+		cluster.cachedCluster.setWeightRampDuration(cfg.WeightRampDuration.Duration)
+	}
 	log.Infof("schedule config is updated: %+v, old: %+v", cfg, old)
+	return nil
 }
 
 // GetReplicationConfig get the replication config.
@@ -486,6 +508,7 @@ func (s *Server) GetNamespaceConfig(name string) *NamespaceConfig {
 		RegionScheduleLimit:  s.scheduleOpt.GetRegionScheduleLimit(name),
 		ReplicaScheduleLimit: s.scheduleOpt.GetReplicaScheduleLimit(name),
 		MaxReplicas:          uint64(s.scheduleOpt.GetMaxReplicas(name)),
+		RegionWeightMode:     s.scheduleOpt.GetRegionWeightMode(name),
 	}
 
 	return cfg
@@ -499,7 +522,12 @@ func (s *Server) GetNamespaceConfigWithAdjust(name string) *NamespaceConfig {
 }
 
 // SetNamespaceConfig sets the namespace config.
-func (s *Server) SetNamespaceConfig(name string, cfg NamespaceConfig) {
+func (s *Server) SetNamespaceConfig(name string, cfg NamespaceConfig) error {
+	if cfg.RegionWeightMode != "" {
+		if err := validateRegionWeightMode(cfg.RegionWeightMode); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	if n, ok := s.scheduleOpt.ns[name]; ok {
 		old := s.scheduleOpt.ns[name].load()
 		n.store(&cfg)
@@ -510,6 +538,7 @@ func (s *Server) SetNamespaceConfig(name string, cfg NamespaceConfig) {
 		s.scheduleOpt.persist(s.kv)
 		log.Infof("namespace:%v config is added: %+v", name, cfg)
 	}
+	return nil
 }
 
 // DeleteNamespaceConfig deletes the namespace config.
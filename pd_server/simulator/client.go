@@ -0,0 +1,278 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server/core"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const (
+	pdTimeout             = 3 * time.Second
+	regionResponseChanLen = 1024
+)
+
+// Client is the PD client of a simulated store.
+type Client interface {
+	GetClusterID() uint64
+	AllocID(ctx context.Context) (uint64, error)
+	Bootstrap(ctx context.Context, store *metapb.Store, region *metapb.Region) error
+	PutStore(ctx context.Context, store *metapb.Store) error
+	StoreHeartbeat(ctx context.Context, stats *pdpb.StoreStats) error
+	RegionHeartbeat(region *core.RegionInfo) error
+	AskSplit(ctx context.Context, region *core.RegionInfo) (*pdpb.AskSplitResponse, error)
+	ReportSplit(ctx context.Context, left, right *metapb.Region) error
+	// RegionHeartbeatResponses returns the schedule commands sent to the
+	// store.
+	RegionHeartbeatResponses() <-chan *pdpb.RegionHeartbeatResponse
+	Close()
+}
+
+type client struct {
+	conn      *grpc.ClientConn
+	pd        pdpb.PDClient
+	clusterID uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// The region heartbeat stream is created on the first region heartbeat,
+	// because PD closes it if the cluster is not bootstrapped.
+	streamMu sync.Mutex
+	stream   pdpb.PD_RegionHeartbeatClient
+	respCh   chan *pdpb.RegionHeartbeatResponse
+}
+
+// NewClient creates a PD client connecting to the first of the client urls.
+func NewClient(pdAddr string) (Client, error) {
+	c, err := newClient(pdAddr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c, nil
+}
+
+func newClient(pdAddr string) (*client, error) {
+	u, err := url.Parse(strings.Split(pdAddr, ",")[0])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	conn, err := grpc.Dial(u.Host, grpc.WithInsecure())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c := &client{
+		conn:   conn,
+		pd:     pdpb.NewPDClient(conn),
+		respCh: make(chan *pdpb.RegionHeartbeatResponse, regionResponseChanLen),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	ctx, cancel := context.WithTimeout(c.ctx, pdTimeout)
+	defer cancel()
+	members, err := c.pd.GetMembers(ctx, &pdpb.GetMembersRequest{})
+	if err != nil {
+		c.Close()
+		return nil, errors.Trace(err)
+	}
+	c.clusterID = members.GetHeader().GetClusterId()
+	return c, nil
+}
+
+func (c *client) requestHeader() *pdpb.RequestHeader {
+	return &pdpb.RequestHeader{ClusterId: c.clusterID}
+}
+
+func checkHeader(header *pdpb.ResponseHeader) error {
+	if err := header.GetError(); err != nil {
+		return errors.Errorf("%s: %s", err.GetType(), err.GetMessage())
+	}
+	return nil
+}
+
+func (c *client) GetClusterID() uint64 {
+	return c.clusterID
+}
+
+func (c *client) AllocID(ctx context.Context) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.AllocID(ctx, &pdpb.AllocIDRequest{Header: c.requestHeader()})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return resp.GetId(), errors.Trace(checkHeader(resp.GetHeader()))
+}
+
+func (c *client) Bootstrap(ctx context.Context, store *metapb.Store, region *metapb.Region) error {
+	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.Bootstrap(ctx, &pdpb.BootstrapRequest{
+		Header: c.requestHeader(),
+		Store:  store,
+		Region: region,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(checkHeader(resp.GetHeader()))
+}
+
+func (c *client) PutStore(ctx context.Context, store *metapb.Store) error {
+	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.PutStore(ctx, &pdpb.PutStoreRequest{
+		Header: c.requestHeader(),
+		Store:  store,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(checkHeader(resp.GetHeader()))
+}
+
+func (c *client) StoreHeartbeat(ctx context.Context, stats *pdpb.StoreStats) error {
+	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.StoreHeartbeat(ctx, &pdpb.StoreHeartbeatRequest{
+		Header: c.requestHeader(),
+		Stats:  stats,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(checkHeader(resp.GetHeader()))
+}
+
+func (c *client) RegionHeartbeat(region *core.RegionInfo) error {
+	return errors.Trace(c.sendRegionHeartbeat(&pdpb.RegionHeartbeatRequest{
+		Header:          c.requestHeader(),
+		Region:          region.Region,
+		Leader:          region.Leader,
+		DownPeers:       region.DownPeers,
+		PendingPeers:    region.PendingPeers,
+		BytesWritten:    region.WrittenBytes,
+		BytesRead:       region.ReadBytes,
+		ApproximateSize: uint64(region.ApproximateSize) << 20,
+	}))
+}
+
+func (c *client) sendRegionHeartbeat(req *pdpb.RegionHeartbeatRequest) error {
+	stream, err := c.getStream()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = stream.Send(req); err != nil {
+		c.resetStream(stream)
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (c *client) getStream() (pdpb.PD_RegionHeartbeatClient, error) {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+	if c.stream != nil {
+		return c.stream, nil
+	}
+	stream, err := c.pd.RegionHeartbeat(c.ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	c.stream = stream
+	c.wg.Add(1)
+	go c.receiveRegionHeartbeat(stream)
+	return stream, nil
+}
+
+func (c *client) resetStream(stream pdpb.PD_RegionHeartbeatClient) {
+	c.streamMu.Lock()
+	defer c.streamMu.Unlock()
+	if c.stream == stream {
+		c.stream = nil
+	}
+}
+
+func (c *client) receiveRegionHeartbeat(stream pdpb.PD_RegionHeartbeatClient) {
+	defer c.wg.Done()
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if c.ctx.Err() == nil {
+				log.Warnf("receive region heartbeat response error: %v", err)
+			}
+			c.resetStream(stream)
+			return
+		}
+		if err = checkHeader(resp.GetHeader()); err != nil {
+			log.Warnf("region heartbeat error: %v", err)
+			continue
+		}
+		select {
+		case c.respCh <- resp:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *client) RegionHeartbeatResponses() <-chan *pdpb.RegionHeartbeatResponse {
+	return c.respCh
+}
+
+func (c *client) AskSplit(ctx context.Context, region *core.RegionInfo) (*pdpb.AskSplitResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.AskSplit(ctx, &pdpb.AskSplitRequest{
+		Header: c.requestHeader(),
+		Region: region.Region,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return resp, errors.Trace(checkHeader(resp.GetHeader()))
+}
+
+func (c *client) ReportSplit(ctx context.Context, left, right *metapb.Region) error {
+	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
+	defer cancel()
+	resp, err := c.pd.ReportSplit(ctx, &pdpb.ReportSplitRequest{
+		Header: c.requestHeader(),
+		Left:   left,
+		Right:  right,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(checkHeader(resp.GetHeader()))
+}
+
+func (c *client) Close() {
+	c.cancel()
+	c.wg.Wait()
+	if err := c.conn.Close(); err != nil {
+		log.Warnf("close pd client error: %v", err)
+	}
+}
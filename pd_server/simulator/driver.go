@@ -0,0 +1,335 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/core"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	waitLeaderTimeout  = 30 * time.Second
+	waitLeaderInterval = 100 * time.Millisecond
+)
+
+// Driver runs a scenario against a PD server. The real coordinator, the
+// schedulers and the checkers of the server schedule the simulated stores.
+type Driver struct {
+	server   *server.Server
+	scenario *Scenario
+	raft     *RaftEngine
+	nodes    map[uint64]*Node
+	storeIDs []uint64
+
+	tick          int
+	lastEventTick int
+	lastTaskTick  int
+	nextEvent     int
+}
+
+// NewDriver creates a driver of the scenario. The server should be running.
+func NewDriver(s *server.Server, scenario *Scenario) *Driver {
+	events := append([]*EventConfig(nil), scenario.Events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Tick < events[j].Tick })
+	scenario.Events = events
+
+	d := &Driver{
+		server:   s,
+		scenario: scenario,
+		raft:     NewRaftEngine(),
+		nodes:    make(map[uint64]*Node),
+	}
+	for _, e := range events {
+		d.lastEventTick = e.Tick
+	}
+	return d
+}
+
+// Run prepares the cluster, and ticks until it converges or the max ticks
+// are reached.
+func (d *Driver) Run(ctx context.Context) (*Report, error) {
+	if err := d.Prepare(ctx); err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer d.Close()
+
+	ticker := time.NewTicker(d.scenario.TickInterval.Duration)
+	defer ticker.Stop()
+	for d.tick < d.scenario.MaxTicks && !d.isConverged() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, errors.Trace(ctx.Err())
+		}
+		d.Tick(ctx)
+	}
+	return d.report()
+}
+
+// Prepare applies the config of the scenario, bootstraps the cluster, and
+// reports the stores and the regions.
+func (d *Driver) Prepare(ctx context.Context) error {
+	if err := waitLeader(ctx, d.server); err != nil {
+		return errors.Trace(err)
+	}
+	if err := d.server.SetScheduleConfig(d.scenario.Schedule); err != nil {
+		return errors.Trace(err)
+	}
+	d.server.SetReplicationConfig(d.scenario.Replication)
+
+	for _, cfg := range d.scenario.Stores {
+		client, err := NewClient(d.server.GetAddr())
+		if err != nil {
+			return errors.Trace(err)
+		}
+		d.nodes[cfg.ID] = NewNode(cfg, client, d.raft, d.scenario)
+		d.storeIDs = append(d.storeIDs, cfg.ID)
+	}
+	sort.Slice(d.storeIDs, func(i, j int) bool { return d.storeIDs[i] < d.storeIDs[j] })
+
+	if err := d.initRegions(ctx); err != nil {
+		return errors.Trace(err)
+	}
+	if err := d.bootstrap(ctx); err != nil {
+		return errors.Trace(err)
+	}
+
+	for _, cfg := range d.scenario.Stores {
+		node := d.nodes[cfg.ID]
+		if err := node.client.PutStore(ctx, node.Store); err != nil {
+			return errors.Trace(err)
+		}
+		if cfg.LeaderWeight > 0 || cfg.RegionWeight > 0 {
+			if err := d.setStoreWeight(cfg.ID, cfg.LeaderWeight, cfg.RegionWeight, 0); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		node.storeHeartbeat(ctx)
+	}
+	d.heartbeatDirtyRegions()
+	return nil
+}
+
+// waitLeader waits for the server to be the leader.
+func waitLeader(ctx context.Context, s *server.Server) error {
+	for start := time.Now(); !s.IsLeader(); {
+		if time.Since(start) > waitLeaderTimeout {
+			return errors.New("wait for pd leader timeout")
+		}
+		select {
+		case <-time.After(waitLeaderInterval):
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		}
+	}
+	return nil
+}
+
+// initRegions creates the regions of the scenario, with the ids allocated
+// by PD.
+func (d *Driver) initRegions(ctx context.Context) error {
+	client := d.nodes[d.storeIDs[0]].client
+	for _, cfg := range d.scenario.Regions {
+		keys, err := splitKeys([]byte(cfg.StartKey), []byte(cfg.EndKey), cfg.Count)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		keys = append([][]byte{[]byte(cfg.StartKey)}, keys...)
+		keys = append(keys, []byte(cfg.EndKey))
+		for i := 0; i < cfg.Count; i++ {
+			id, err := client.AllocID(ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			// The epoch is newer than the bootstrap region.
+			meta := &metapb.Region{
+				Id:          id,
+				StartKey:    keys[i],
+				EndKey:      keys[i+1],
+				RegionEpoch: &metapb.RegionEpoch{ConfVer: 2, Version: 2},
+			}
+			for _, storeID := range cfg.Peers {
+				peerID, err := client.AllocID(ctx)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				meta.Peers = append(meta.Peers, &metapb.Peer{Id: peerID, StoreId: storeID})
+			}
+			region := core.NewRegionInfo(meta, meta.Peers[0])
+			region.ApproximateSize = cfg.Size
+			region.ReadBytes = cfg.ReadBytes
+			region.WrittenBytes = cfg.WrittenBytes
+			d.raft.SetRegion(region)
+		}
+	}
+	return nil
+}
+
+// bootstrap bootstraps the cluster with the first region and its leader
+// store. PD requires the bootstrap region to have a single peer and to cover
+// all keys, so it is replaced by the regions of the scenario later.
+func (d *Driver) bootstrap(ctx context.Context) error {
+	region := d.raft.GetRegions()[0]
+	node := d.nodes[region.Leader.GetStoreId()]
+	meta := &metapb.Region{
+		Id:          region.GetId(),
+		Peers:       []*metapb.Peer{region.Leader},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	if err := node.client.Bootstrap(ctx, node.Store, meta); err != nil {
+		return errors.Trace(err)
+	}
+	for start := time.Now(); d.server.GetRaftCluster() == nil; {
+		if time.Since(start) > waitLeaderTimeout {
+			return errors.New("wait for cluster timeout")
+		}
+		time.Sleep(waitLeaderInterval)
+	}
+	return nil
+}
+
+// Tick applies the events of the tick, and runs the nodes for a tick.
+func (d *Driver) Tick(ctx context.Context) {
+	d.tick++
+	for d.nextEvent < len(d.scenario.Events) && d.scenario.Events[d.nextEvent].Tick <= d.tick {
+		e := d.scenario.Events[d.nextEvent]
+		if err := d.applyEvent(ctx, e); err != nil {
+			log.Errorf("apply %s event at tick %d error: %v", e.Type, e.Tick, err)
+		}
+		d.nextEvent++
+	}
+	for _, id := range d.storeIDs {
+		if d.nodes[id].Tick(ctx) > 0 {
+			d.lastTaskTick = d.tick
+		}
+	}
+	d.heartbeatDirtyRegions()
+}
+
+func (d *Driver) applyEvent(ctx context.Context, e *EventConfig) error {
+	log.Infof("apply %s event at tick %d", e.Type, d.tick)
+	d.lastEventTick = d.tick
+	switch e.Type {
+	case StoreDownEvent:
+		d.raft.SetStoreDown(e.StoreID, time.Now())
+	case StoreUpEvent:
+		d.raft.SetStoreUp(e.StoreID)
+	case WeightChangeEvent:
+		rampDuration := d.server.GetScheduleConfig().WeightRampDuration.Duration
+		return errors.Trace(d.setStoreWeight(e.StoreID, e.LeaderWeight, e.RegionWeight, rampDuration))
+	case RegionSplitEvent:
+		for i := 0; i < e.Count; i++ {
+			if err := d.splitRegion(ctx, e.RegionID); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// setStoreWeight sets the weights of the store by the API of PD. A zero
+// weight keeps the current target weight.
+func (d *Driver) setStoreWeight(storeID uint64, leader, region float64, rampDuration time.Duration) error {
+	cluster := d.server.GetRaftCluster()
+	if cluster == nil {
+		return errors.New("cluster is not bootstrapped")
+	}
+	store, err := cluster.GetStore(storeID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if leader == 0 {
+		leader, _ = store.LeaderWeights.Resolve()
+	}
+	if region == 0 {
+		region, _ = store.RegionWeights.Resolve()
+	}
+	return errors.Trace(cluster.SetStoreWeight(storeID, leader, region, rampDuration))
+}
+
+// splitRegion splits the region as TiKV does, by asking PD for the new ids
+// and reporting the result. A zero region id splits the largest region.
+func (d *Driver) splitRegion(ctx context.Context, regionID uint64) error {
+	var region *core.RegionInfo
+	if regionID == 0 {
+		for _, r := range d.raft.GetRegions() {
+			if region == nil || r.ApproximateSize > region.ApproximateSize {
+				region = r
+			}
+		}
+	} else {
+		region = d.raft.GetRegion(regionID)
+	}
+	if region == nil {
+		return errors.Errorf("region %d not found", regionID)
+	}
+	node, ok := d.nodes[region.Leader.GetStoreId()]
+	if !ok {
+		return errors.Errorf("region %d has no leader", region.GetId())
+	}
+	resp, err := node.client.AskSplit(ctx, region)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	left, right, err := d.raft.splitRegion(region.GetId(), resp.GetNewRegionId(), resp.GetNewPeerIds())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(node.client.ReportSplit(ctx, left.Region, right.Region))
+}
+
+// heartbeatDirtyRegions reports the changed regions by their leaders at
+// once, as TiKV does after applying a change.
+func (d *Driver) heartbeatDirtyRegions() {
+	for _, region := range d.raft.TakeDirtyRegions() {
+		if node, ok := d.nodes[region.Leader.GetStoreId()]; ok && d.raft.IsStoreUp(node.GetId()) {
+			node.RegionHeartbeat(region)
+		}
+	}
+}
+
+// isConverged checks if there is no new task for ConvergenceTicks after the
+// last event.
+func (d *Driver) isConverged() bool {
+	if d.tick <= d.lastEventTick {
+		return false
+	}
+	for _, node := range d.nodes {
+		if node.TaskCount() > 0 {
+			return false
+		}
+	}
+	return d.tick-d.convergenceTick() >= d.scenario.ConvergenceTicks
+}
+
+func (d *Driver) convergenceTick() int {
+	if d.lastTaskTick > d.lastEventTick {
+		return d.lastTaskTick
+	}
+	return d.lastEventTick
+}
+
+// Close closes the clients of the nodes.
+func (d *Driver) Close() {
+	for _, node := range d.nodes {
+		node.client.Close()
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server/core"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// Node is a simulated store. It sends heartbeats to PD, and applies the
+// schedule commands of the regions it leads.
+type Node struct {
+	*metapb.Store
+	capacity  uint64
+	startTime time.Time
+	client    Client
+	raft      *RaftEngine
+	scenario  *Scenario
+	tick      int
+	tasks     map[uint64]Task
+	// The number of tasks started by type.
+	taskCounts map[string]int
+}
+
+// NewNode creates a simulated store.
+func NewNode(cfg *StoreConfig, client Client, raft *RaftEngine, scenario *Scenario) *Node {
+	store := &metapb.Store{
+		Id:      cfg.ID,
+		Address: "mock://tikv-" + strconv.FormatUint(cfg.ID, 10),
+	}
+	for k, v := range cfg.Labels {
+		store.Labels = append(store.Labels, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	sort.Slice(store.Labels, func(i, j int) bool { return store.Labels[i].Key < store.Labels[j].Key })
+	return &Node{
+		Store:      store,
+		capacity:   uint64(cfg.Capacity),
+		startTime:  time.Now(),
+		client:     client,
+		raft:       raft,
+		scenario:   scenario,
+		tasks:      make(map[uint64]Task),
+		taskCounts: make(map[string]int),
+	}
+}
+
+// Tick runs the node for a tick. It returns the number of new tasks.
+func (n *Node) Tick(ctx context.Context) int {
+	if !n.raft.IsStoreUp(n.GetId()) {
+		return 0
+	}
+	// Spread the heartbeats of the stores.
+	n.tick++
+	if (n.tick+int(n.GetId()))%n.scenario.StoreHeartbeatTicks == 0 {
+		n.storeHeartbeat(ctx)
+	}
+	if (n.tick+int(n.GetId()))%n.scenario.RegionHeartbeatTicks == 0 {
+		n.regionHeartbeats()
+	}
+	newTasks := n.receiveTasks()
+	n.stepTasks()
+	return newTasks
+}
+
+// TaskCount returns the number of unfinished tasks.
+func (n *Node) TaskCount() int {
+	return len(n.tasks)
+}
+
+func (n *Node) storeHeartbeat(ctx context.Context) {
+	regionCount, usedSize, readBytes, writtenBytes := n.raft.GetStoreStats(n.GetId())
+	used := uint64(usedSize) << 20
+	var available uint64
+	if n.capacity > used {
+		available = n.capacity - used
+	}
+	var sendingSnapCount uint32
+	for _, task := range n.tasks {
+		if _, ok := task.(*addPeer); ok {
+			sendingSnapCount++
+		}
+	}
+	stats := &pdpb.StoreStats{
+		StoreId:            n.GetId(),
+		Capacity:           n.capacity,
+		Available:          available,
+		UsedSize:           used,
+		RegionCount:        uint32(regionCount),
+		SendingSnapCount:   sendingSnapCount,
+		ReceivingSnapCount: uint32(n.raft.GetStorePendingPeerCount(n.GetId())),
+		StartTime:          uint32(n.startTime.Unix()),
+		BytesRead:          readBytes,
+		BytesWritten:       writtenBytes,
+	}
+	if err := n.client.StoreHeartbeat(ctx, stats); err != nil {
+		log.Warnf("[store %d] store heartbeat error: %v", n.GetId(), err)
+	}
+}
+
+func (n *Node) regionHeartbeats() {
+	for _, region := range n.raft.GetRegions() {
+		if region.Leader.GetStoreId() == n.GetId() {
+			n.RegionHeartbeat(region)
+		}
+	}
+}
+
+// RegionHeartbeat reports a region led by the node.
+func (n *Node) RegionHeartbeat(region *core.RegionInfo) {
+	if err := n.client.RegionHeartbeat(n.raft.HeartbeatRegion(region, time.Now())); err != nil {
+		log.Warnf("[region %d] region heartbeat error: %v", region.GetId(), err)
+	}
+}
+
+func (n *Node) receiveTasks() int {
+	var count int
+	for {
+		select {
+		case resp := <-n.client.RegionHeartbeatResponses():
+			if _, ok := n.tasks[resp.GetRegionId()]; ok || n.isStale(resp) {
+				continue
+			}
+			task := responseToTask(resp, n.scenario.SnapshotSpeed)
+			if task == nil {
+				continue
+			}
+			log.Debugf("[store %d] start task: %s", n.GetId(), task.Desc())
+			n.tasks[resp.GetRegionId()] = task
+			n.taskCounts[taskType(task)]++
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+// isStale checks if the command is for an outdated region, which happens
+// when PD responds to the heartbeats before a task finishes.
+func (n *Node) isStale(resp *pdpb.RegionHeartbeatResponse) bool {
+	region := n.raft.GetRegion(resp.GetRegionId())
+	if region == nil {
+		return true
+	}
+	epoch := resp.GetRegionEpoch()
+	return epoch.GetVersion() < region.GetRegionEpoch().GetVersion() || epoch.GetConfVer() < region.GetRegionEpoch().GetConfVer()
+}
+
+func (n *Node) stepTasks() {
+	for id, task := range n.tasks {
+		// The leader may be changed by a store down.
+		region := n.raft.GetRegion(id)
+		if region == nil || region.Leader.GetStoreId() != n.GetId() {
+			delete(n.tasks, id)
+			continue
+		}
+		if task.Step(n.raft) {
+			log.Debugf("[store %d] finish task: %s", n.GetId(), task.Desc())
+			delete(n.tasks, id)
+		}
+	}
+}
+
+func taskType(task Task) string {
+	switch task.(type) {
+	case *transferLeader:
+		return "transfer-leader"
+	case *addPeer:
+		return "add-peer"
+	case *removePeer:
+		return "remove-peer"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/pd/pkg/logutil"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/api"
+	"github.com/pingcap/pd/server/simulator"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+var (
+	recordFile = flag.String("record", "", "traffic log recorded by pd with --record-file")
+	configFile = flag.String("config", "", "pd config file for the schedule and replication config")
+	speed      = flag.Float64("speed", 1, "replay speed relative to the log, 0 for as fast as possible")
+	wait       = flag.Duration("wait", 5*time.Second, "time to wait for the schedule commands after the last record")
+	logLevel   = flag.String("L", "info", "log level: debug, info, warn, error, fatal")
+)
+
+func main() {
+	flag.Parse()
+	log.SetLevel(logutil.StringToLogLevel(*logLevel))
+	if *recordFile == "" {
+		log.Fatal("need to specify the traffic log by -record")
+	}
+
+	f, err := os.Open(*recordFile)
+	if err != nil {
+		log.Fatalf("open traffic log error: %v", err)
+	}
+	defer f.Close()
+
+	// Run a fresh PD server in the process to replay to.
+	cfg := server.NewTestSingleConfig()
+	defer os.RemoveAll(cfg.DataDir)
+	s, err := server.CreateServer(cfg, api.NewHandler)
+	if err != nil {
+		log.Fatalf("create pd server error: %v", err)
+	}
+	if err = s.Run(); err != nil {
+		log.Fatalf("run pd server error: %v", err)
+	}
+	defer s.Close()
+
+	var replayCfg *server.Config
+	if *configFile != "" {
+		replayCfg = server.NewConfig()
+		if err = replayCfg.Parse([]string{"--config", *configFile}); err != nil {
+			log.Errorf("parse config error: %v", err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	go func() {
+		sig := <-sc
+		log.Infof("got signal [%d] to exit", sig)
+		cancel()
+	}()
+
+	report, err := simulator.NewReplayer(s, server.NewTrafficReader(f), replayCfg, *speed, *wait).Run(ctx)
+	if err != nil {
+		log.Errorf("replay error: %v", err)
+		return
+	}
+	fmt.Print(report)
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/logutil"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/api"
+	"github.com/pingcap/pd/server/simulator"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+var (
+	caseFile = flag.String("case", "", "scenario file of the simulation")
+	logLevel = flag.String("L", "info", "log level: debug, info, warn, error, fatal")
+)
+
+func main() {
+	flag.Parse()
+	log.SetLevel(logutil.StringToLogLevel(*logLevel))
+	if *caseFile == "" {
+		log.Fatal("need to specify the scenario file by -case")
+	}
+
+	report, err := run(*caseFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(report)
+}
+
+// run simulates the scenario with a PD server in the process, which schedules
+// the simulated stores, and returns the report.
+func run(caseFile string) (*simulator.Report, error) {
+	scenario, err := simulator.LoadScenario(caseFile)
+	if err != nil {
+		return nil, errors.Annotate(err, "load scenario error")
+	}
+
+	cfg := server.NewTestSingleConfig()
+	defer os.RemoveAll(cfg.DataDir)
+	s, err := server.CreateServer(cfg, api.NewHandler)
+	if err != nil {
+		return nil, errors.Annotate(err, "create pd server error")
+	}
+	if err = s.Run(); err != nil {
+		return nil, errors.Annotate(err, "run pd server error")
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(sc)
+	go func() {
+		select {
+		case sig := <-sc:
+			log.Infof("got signal [%d] to exit", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	report, err := simulator.NewDriver(s, scenario).Run(ctx)
+	if err != nil {
+		return nil, errors.Annotate(err, "simulation error")
+	}
+	return report, nil
+}
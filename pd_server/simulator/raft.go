@@ -0,0 +1,286 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server/core"
+)
+
+// RaftEngine records the regions of the simulated cluster, which are shared
+// by the simulated stores. It plays the role of raft to apply the region
+// changes.
+type RaftEngine struct {
+	sync.RWMutex
+	regions *core.RegionsInfo
+	// downStores is the time the stores are down.
+	downStores map[uint64]time.Time
+	// dirtyRegions is the regions changed since the last heartbeats.
+	dirtyRegions map[uint64]struct{}
+	// movedSize is the size in MB of the applied snapshots.
+	movedSize int64
+}
+
+// NewRaftEngine creates a RaftEngine.
+func NewRaftEngine() *RaftEngine {
+	return &RaftEngine{
+		regions:      core.NewRegionsInfo(),
+		downStores:   make(map[uint64]time.Time),
+		dirtyRegions: make(map[uint64]struct{}),
+	}
+}
+
+// GetRegion returns a copy of the region.
+func (r *RaftEngine) GetRegion(regionID uint64) *core.RegionInfo {
+	r.RLock()
+	defer r.RUnlock()
+	region := r.regions.GetRegion(regionID)
+	if region == nil {
+		return nil
+	}
+	return region.Clone()
+}
+
+// GetRegions returns the regions sorted by id.
+func (r *RaftEngine) GetRegions() []*core.RegionInfo {
+	r.RLock()
+	defer r.RUnlock()
+	regions := r.regions.GetRegions()
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetId() < regions[j].GetId() })
+	return regions
+}
+
+// SetRegion updates the region, and marks it to be reported.
+func (r *RaftEngine) SetRegion(region *core.RegionInfo) {
+	r.Lock()
+	defer r.Unlock()
+	r.regions.SetRegion(region)
+	r.dirtyRegions[region.GetId()] = struct{}{}
+}
+
+// TakeDirtyRegions returns the regions changed since the last call.
+func (r *RaftEngine) TakeDirtyRegions() []*core.RegionInfo {
+	r.Lock()
+	defer r.Unlock()
+	regions := make([]*core.RegionInfo, 0, len(r.dirtyRegions))
+	for id := range r.dirtyRegions {
+		if region := r.regions.GetRegion(id); region != nil {
+			regions = append(regions, region.Clone())
+		}
+	}
+	r.dirtyRegions = make(map[uint64]struct{})
+	sort.Slice(regions, func(i, j int) bool { return regions[i].GetId() < regions[j].GetId() })
+	return regions
+}
+
+// GetStoreStats returns the region count, the used size in MB, and the
+// read and written bytes of the store.
+func (r *RaftEngine) GetStoreStats(storeID uint64) (int, int64, uint64, uint64) {
+	r.RLock()
+	defer r.RUnlock()
+	var count int
+	var size int64
+	var readBytes, writtenBytes uint64
+	for _, region := range r.regions.GetRegions() {
+		if region.GetStorePeer(storeID) == nil {
+			continue
+		}
+		count++
+		size += region.ApproximateSize
+		writtenBytes += region.WrittenBytes
+		if region.Leader.GetStoreId() == storeID {
+			readBytes += region.ReadBytes
+		}
+	}
+	return count, size, readBytes, writtenBytes
+}
+
+// GetStorePendingPeerCount returns the number of snapshots the store is
+// receiving.
+func (r *RaftEngine) GetStorePendingPeerCount(storeID uint64) int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.regions.GetStorePendingPeerCount(storeID)
+}
+
+// IsStoreUp checks if the store is not down.
+func (r *RaftEngine) IsStoreUp(storeID uint64) bool {
+	r.RLock()
+	defer r.RUnlock()
+	_, ok := r.downStores[storeID]
+	return !ok
+}
+
+// SetStoreDown marks the store down, and its region leaders are elected on
+// other up peers.
+func (r *RaftEngine) SetStoreDown(storeID uint64, now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	r.downStores[storeID] = now
+	for _, region := range r.regions.GetRegions() {
+		if region.GetStorePeer(storeID) == nil {
+			continue
+		}
+		region = region.Clone()
+		if region.Leader.GetStoreId() == storeID {
+			for _, peer := range region.GetPeers() {
+				if _, ok := r.downStores[peer.GetStoreId()]; !ok && region.GetPendingPeer(peer.GetId()) == nil {
+					region.Leader = peer
+					break
+				}
+			}
+		}
+		r.regions.SetRegion(region)
+		r.dirtyRegions[region.GetId()] = struct{}{}
+	}
+}
+
+// SetStoreUp marks the store up.
+func (r *RaftEngine) SetStoreUp(storeID uint64) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.downStores, storeID)
+	for _, region := range r.regions.GetRegions() {
+		if region.GetStorePeer(storeID) != nil {
+			r.dirtyRegions[region.GetId()] = struct{}{}
+		}
+	}
+}
+
+// HeartbeatRegion returns the region to report, with the peers on down
+// stores as down peers.
+func (r *RaftEngine) HeartbeatRegion(region *core.RegionInfo, now time.Time) *core.RegionInfo {
+	r.RLock()
+	defer r.RUnlock()
+	region = region.Clone()
+	region.DownPeers = nil
+	for _, peer := range region.GetPeers() {
+		if downTime, ok := r.downStores[peer.GetStoreId()]; ok {
+			region.DownPeers = append(region.DownPeers, &pdpb.PeerStats{
+				Peer:        peer,
+				DownSeconds: uint64(now.Sub(downTime).Seconds()),
+			})
+		}
+	}
+	return region
+}
+
+// MovedSize returns the size in MB of the applied snapshots.
+func (r *RaftEngine) MovedSize() int64 {
+	r.RLock()
+	defer r.RUnlock()
+	return r.movedSize
+}
+
+func (r *RaftEngine) addMovedSize(size int64) {
+	r.Lock()
+	defer r.Unlock()
+	r.movedSize += size
+}
+
+// splitRegion splits the region in the middle of its key range. The left
+// half is the new region with the allocated ids.
+func (r *RaftEngine) splitRegion(regionID uint64, newRegionID uint64, newPeerIDs []uint64) (*core.RegionInfo, *core.RegionInfo, error) {
+	right := r.GetRegion(regionID)
+	if right == nil {
+		return nil, nil, errors.Errorf("region %d not found", regionID)
+	}
+	if len(newPeerIDs) != len(right.GetPeers()) {
+		return nil, nil, errors.Errorf("region %d has %d peers, but %d new peer ids", regionID, len(right.GetPeers()), len(newPeerIDs))
+	}
+	keys, err := splitKeys(right.GetStartKey(), right.GetEndKey(), 2)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	left := right.Clone()
+	left.Id = newRegionID
+	left.Peers = make([]*metapb.Peer, 0, len(newPeerIDs))
+	left.PendingPeers = nil
+	for i, peer := range right.GetPeers() {
+		newPeer := &metapb.Peer{Id: newPeerIDs[i], StoreId: peer.GetStoreId()}
+		left.Peers = append(left.Peers, newPeer)
+		if peer.GetId() == right.Leader.GetId() {
+			left.Leader = newPeer
+		}
+		if right.GetPendingPeer(peer.GetId()) != nil {
+			left.PendingPeers = append(left.PendingPeers, newPeer)
+		}
+	}
+	left.EndKey = keys[0]
+	right.StartKey = keys[0]
+	size, readBytes, writtenBytes := right.ApproximateSize, right.ReadBytes, right.WrittenBytes
+	for _, region := range []*core.RegionInfo{left, right} {
+		region.RegionEpoch.Version++
+		region.ApproximateSize = size / 2
+		region.ReadBytes = readBytes / 2
+		region.WrittenBytes = writtenBytes / 2
+	}
+	// The left half takes the odd part.
+	left.ApproximateSize += size % 2
+
+	r.SetRegion(right)
+	r.SetRegion(left)
+	return left, right, nil
+}
+
+// splitKeys returns count-1 keys splitting [start, end) evenly, where an
+// empty end is the max key. The keys are padded to a fixed length, and are
+// interpolated as big-endian numbers.
+func splitKeys(start, end []byte, count int) ([][]byte, error) {
+	length := len(start)
+	if len(end) > length {
+		length = len(end)
+	}
+	// Extra bytes for the precision of interpolation.
+	length += 8
+
+	lo := new(big.Int).SetBytes(padKey(start, length, 0))
+	var hi *big.Int
+	if len(end) == 0 {
+		hi = new(big.Int).SetBytes(padKey(nil, length, 0xff))
+	} else {
+		hi = new(big.Int).SetBytes(padKey(end, length, 0))
+	}
+	span := new(big.Int).Sub(hi, lo)
+	if span.Cmp(big.NewInt(int64(count))) < 0 {
+		return nil, errors.Errorf("cannot split [%q, %q) into %d regions", start, end, count)
+	}
+
+	keys := make([][]byte, 0, count-1)
+	for i := 1; i < count; i++ {
+		step := new(big.Int).Mul(span, big.NewInt(int64(i)))
+		step.Div(step, big.NewInt(int64(count)))
+		key := new(big.Int).Add(lo, step).Bytes()
+		// Restore the leading zeros.
+		keys = append(keys, append(make([]byte, length-len(key)), key...))
+	}
+	return keys, nil
+}
+
+func padKey(key []byte, length int, pad byte) []byte {
+	res := make([]byte, length)
+	copy(res, key)
+	for i := len(key); i < length; i++ {
+		res[i] = pad
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/server/core"
+)
+
+func TestSimulator(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testRaftEngineSuite{})
+
+type testRaftEngineSuite struct{}
+
+// newTestRegion creates a region with peers on the stores, and the first
+// peer is the leader.
+func newTestRegion(regionID uint64, size int64, storeIDs ...uint64) *core.RegionInfo {
+	meta := &metapb.Region{
+		Id:          regionID,
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	for _, id := range storeIDs {
+		meta.Peers = append(meta.Peers, &metapb.Peer{Id: regionID*100 + id, StoreId: id})
+	}
+	region := core.NewRegionInfo(meta, meta.Peers[0])
+	region.ApproximateSize = size
+	return region
+}
+
+func (s *testRaftEngineSuite) TestSplitKeys(c *C) {
+	keys, err := splitKeys([]byte("a"), []byte("b"), 4)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 3)
+	last := []byte("a")
+	for _, key := range keys {
+		c.Assert(bytes.Compare(last, key), Less, 0)
+		last = key
+	}
+	c.Assert(bytes.Compare(last, []byte("b")), Less, 0)
+
+	// An empty end key is the max key.
+	keys, err = splitKeys(nil, nil, 2)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 1)
+	c.Assert(keys[0][0], Equals, byte(0x7f))
+
+	keys, err = splitKeys([]byte("a"), []byte("b"), 1)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 0)
+
+	_, err = splitKeys([]byte("a"), []byte("a"), 2)
+	c.Assert(err, NotNil)
+}
+
+func (s *testRaftEngineSuite) TestSplitRegion(c *C) {
+	r := NewRaftEngine()
+	region := newTestRegion(1, 97, 1, 2, 3)
+	region.EndKey = []byte("z")
+	region.WrittenBytes = 100
+	r.SetRegion(region)
+	c.Assert(r.TakeDirtyRegions(), HasLen, 1)
+
+	_, _, err := r.splitRegion(1, 2, []uint64{4, 5})
+	c.Assert(err, NotNil)
+	left, right, err := r.splitRegion(1, 2, []uint64{4, 5, 6})
+	c.Assert(err, IsNil)
+	c.Assert(left.GetId(), Equals, uint64(2))
+	c.Assert(left.Leader.GetId(), Equals, uint64(4))
+	c.Assert(left.GetStartKey(), HasLen, 0)
+	c.Assert(left.GetEndKey(), DeepEquals, right.GetStartKey())
+	c.Assert(right.GetEndKey(), DeepEquals, []byte("z"))
+	c.Assert(left.ApproximateSize, Equals, int64(49))
+	c.Assert(right.ApproximateSize, Equals, int64(48))
+	c.Assert(right.WrittenBytes, Equals, uint64(50))
+	c.Assert(right.GetRegionEpoch().GetVersion(), Equals, uint64(2))
+	c.Assert(r.TakeDirtyRegions(), HasLen, 2)
+
+	count, size, _, writtenBytes := r.GetStoreStats(1)
+	c.Assert(count, Equals, 2)
+	c.Assert(size, Equals, int64(97))
+	c.Assert(writtenBytes, Equals, uint64(100))
+}
+
+func (s *testRaftEngineSuite) TestStoreDown(c *C) {
+	r := NewRaftEngine()
+	region := newTestRegion(1, 10, 1, 2, 3)
+	region.EndKey = []byte("a")
+	r.SetRegion(region)
+	region = newTestRegion(2, 10, 2, 3)
+	region.StartKey = []byte("a")
+	r.SetRegion(region)
+	r.TakeDirtyRegions()
+
+	now := time.Now()
+	r.SetStoreDown(1, now.Add(-time.Minute))
+	c.Assert(r.IsStoreUp(1), IsFalse)
+	c.Assert(r.GetRegion(1).Leader.GetStoreId(), Equals, uint64(2))
+	c.Assert(r.TakeDirtyRegions(), HasLen, 1)
+
+	region = r.HeartbeatRegion(r.GetRegion(1), now)
+	c.Assert(region.DownPeers, HasLen, 1)
+	c.Assert(region.DownPeers[0].GetPeer().GetStoreId(), Equals, uint64(1))
+	c.Assert(region.DownPeers[0].GetDownSeconds(), Equals, uint64(60))
+
+	r.SetStoreUp(1)
+	c.Assert(r.IsStoreUp(1), IsTrue)
+	c.Assert(r.HeartbeatRegion(r.GetRegion(1), now).DownPeers, HasLen, 0)
+}
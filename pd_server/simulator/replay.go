@@ -0,0 +1,297 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// ReplayCommand is a schedule command sent by PD during a replay.
+type ReplayCommand struct {
+	// Time is the time in the log when the command is received.
+	Time     time.Time                     `json:"time"`
+	RegionID uint64                        `json:"region_id"`
+	Response *pdpb.RegionHeartbeatResponse `json:"response"`
+}
+
+func (c *ReplayCommand) String() string {
+	resp := c.Response
+	if tl := resp.GetTransferLeader(); tl != nil {
+		return fmt.Sprintf("region %d: transfer leader to store %d", c.RegionID, tl.GetPeer().GetStoreId())
+	}
+	if cp := resp.GetChangePeer(); cp != nil {
+		return fmt.Sprintf("region %d: %s peer %d on store %d", c.RegionID, cp.GetChangeType(), cp.GetPeer().GetId(), cp.GetPeer().GetStoreId())
+	}
+	return fmt.Sprintf("region %d: %s", c.RegionID, resp)
+}
+
+// ReplayReport is the result of a replay.
+type ReplayReport struct {
+	// Records is the number of the replayed records by type.
+	Records map[string]int `json:"records"`
+	// Errors is the number of the records failed to replay.
+	Errors   int              `json:"errors"`
+	Commands []*ReplayCommand `json:"commands"`
+}
+
+func (r *ReplayReport) String() string {
+	var buf bytes.Buffer
+	for _, t := range []server.TrafficRecordType{
+		server.RecordPutStore,
+		server.RecordStoreHeartbeat,
+		server.RecordRegionHeartbeat,
+		server.RecordAskSplit,
+		server.RecordReportSplit,
+	} {
+		fmt.Fprintf(&buf, "%s: %d\n", t, r.Records[t.String()])
+	}
+	fmt.Fprintf(&buf, "errors: %d\n", r.Errors)
+	fmt.Fprintf(&buf, "commands: %d\n", len(r.Commands))
+	for _, c := range r.Commands {
+		fmt.Fprintf(&buf, "%s %s\n", c.Time.Format("2006-01-02 15:04:05.000"), c)
+	}
+	return buf.String()
+}
+
+// Replayer feeds a traffic log recorded by PD to a fresh server, and
+// captures the schedule commands the server sends. The commands are not
+// applied, so the cluster follows the log only.
+type Replayer struct {
+	server *server.Server
+	reader *server.TrafficReader
+	// cfg is the schedule and replication config to replay with. Nil keeps
+	// the config of the server.
+	cfg *server.Config
+	// speed is the replay speed relative to the log. Zero replays as fast as
+	// possible.
+	speed float64
+	// wait is the time to wait for the commands after the last record.
+	wait time.Duration
+
+	client       *client
+	stores       map[uint64]struct{}
+	bootstrapped bool
+	// pending is the records before the first region heartbeat, which are
+	// replayed after the cluster is bootstrapped.
+	pending []*server.TrafficRecord
+
+	logStart  time.Time
+	wallStart time.Time
+	logTime   time.Time
+	report    *ReplayReport
+}
+
+// NewReplayer creates a Replayer. The server should be running.
+func NewReplayer(s *server.Server, reader *server.TrafficReader, cfg *server.Config, speed float64, wait time.Duration) *Replayer {
+	return &Replayer{
+		server: s,
+		reader: reader,
+		cfg:    cfg,
+		speed:  speed,
+		wait:   wait,
+		stores: make(map[uint64]struct{}),
+		report: &ReplayReport{Records: make(map[string]int)},
+	}
+}
+
+// Run replays the log to the end.
+func (r *Replayer) Run(ctx context.Context) (*ReplayReport, error) {
+	if err := waitLeader(ctx, r.server); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if r.cfg != nil {
+		if err := r.server.SetScheduleConfig(r.cfg.Schedule); err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.server.SetReplicationConfig(r.cfg.Replication)
+	}
+	c, err := newClient(r.server.GetAddr())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r.client = c
+	defer c.Close()
+
+	for {
+		record, err := r.reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			log.Warn("the last record is truncated")
+			break
+		}
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err = r.waitRecord(ctx, record); err != nil {
+			return nil, errors.Trace(err)
+		}
+		r.receiveCommands()
+		r.replay(ctx, record)
+	}
+
+	select {
+	case <-time.After(r.wait):
+	case <-ctx.Done():
+		return nil, errors.Trace(ctx.Err())
+	}
+	r.receiveCommands()
+	return r.report, nil
+}
+
+// waitRecord waits until the time of the record at the replay speed.
+func (r *Replayer) waitRecord(ctx context.Context, record *server.TrafficRecord) error {
+	if r.logStart.IsZero() {
+		r.logStart, r.wallStart = record.Time, time.Now()
+	}
+	r.logTime = record.Time
+	if r.speed <= 0 {
+		return nil
+	}
+	elapsed := time.Duration(float64(record.Time.Sub(r.logStart)) / r.speed)
+	select {
+	case <-time.After(time.Until(r.wallStart.Add(elapsed))):
+		return nil
+	case <-ctx.Done():
+		return errors.Trace(ctx.Err())
+	}
+}
+
+func (r *Replayer) replay(ctx context.Context, record *server.TrafficRecord) {
+	if !r.bootstrapped {
+		req, ok := record.Request.(*pdpb.RegionHeartbeatRequest)
+		if !ok {
+			r.pending = append(r.pending, record)
+			return
+		}
+		if err := r.bootstrap(ctx, req); err != nil {
+			log.Errorf("bootstrap error: %v", err)
+			r.report.Errors++
+			return
+		}
+		pending := r.pending
+		r.pending = nil
+		for _, p := range pending {
+			r.replay(ctx, p)
+		}
+	}
+
+	r.report.Records[record.Type.String()]++
+	if err := r.send(ctx, record); err != nil {
+		log.Warnf("replay %s error: %v", record.Type, err)
+		r.report.Errors++
+	}
+}
+
+// bootstrap bootstraps the cluster with the region of the first region
+// heartbeat. The bootstrap region has a single peer and covers all keys as
+// PD requires, and its zero epoch is replaced by the heartbeat.
+func (r *Replayer) bootstrap(ctx context.Context, req *pdpb.RegionHeartbeatRequest) error {
+	leader := req.GetLeader()
+	region := &metapb.Region{
+		Id:          req.GetRegion().GetId(),
+		Peers:       []*metapb.Peer{leader},
+		RegionEpoch: &metapb.RegionEpoch{},
+	}
+	if err := r.client.Bootstrap(ctx, newReplayStore(leader.GetStoreId()), region); err != nil {
+		return errors.Trace(err)
+	}
+	r.stores[leader.GetStoreId()] = struct{}{}
+	r.bootstrapped = true
+	return nil
+}
+
+func (r *Replayer) send(ctx context.Context, record *server.TrafficRecord) error {
+	header := r.client.requestHeader()
+	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
+	defer cancel()
+	switch req := record.Request.(type) {
+	case *pdpb.PutStoreRequest:
+		r.stores[req.GetStore().GetId()] = struct{}{}
+		return errors.Trace(r.client.PutStore(ctx, req.GetStore()))
+	case *pdpb.StoreHeartbeatRequest:
+		if err := r.ensureStores(ctx, req.GetStats().GetStoreId()); err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(r.client.StoreHeartbeat(ctx, req.GetStats()))
+	case *pdpb.RegionHeartbeatRequest:
+		for _, peer := range req.GetRegion().GetPeers() {
+			if err := r.ensureStores(ctx, peer.GetStoreId()); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		req.Header = header
+		return errors.Trace(r.client.sendRegionHeartbeat(req))
+	case *pdpb.AskSplitRequest:
+		req.Header = header
+		resp, err := r.client.pd.AskSplit(ctx, req)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return errors.Trace(checkHeader(resp.GetHeader()))
+	case *pdpb.ReportSplitRequest:
+		return errors.Trace(r.client.ReportSplit(ctx, req.GetLeft(), req.GetRight()))
+	default:
+		return errors.Errorf("unknown request %T", req)
+	}
+}
+
+// ensureStores puts the stores not in the log, which are started before
+// the recording.
+func (r *Replayer) ensureStores(ctx context.Context, storeIDs ...uint64) error {
+	for _, id := range storeIDs {
+		if _, ok := r.stores[id]; ok {
+			continue
+		}
+		if err := r.client.PutStore(ctx, newReplayStore(id)); err != nil {
+			return errors.Trace(err)
+		}
+		r.stores[id] = struct{}{}
+	}
+	return nil
+}
+
+func (r *Replayer) receiveCommands() {
+	for {
+		select {
+		case resp := <-r.client.RegionHeartbeatResponses():
+			r.report.Commands = append(r.report.Commands, &ReplayCommand{
+				Time:     r.logTime,
+				RegionID: resp.GetRegionId(),
+				Response: resp,
+			})
+		default:
+			return
+		}
+	}
+}
+
+func newReplayStore(storeID uint64) *metapb.Store {
+	return &metapb.Store{
+		Id:      storeID,
+		Address: "replay://tikv-" + strconv.FormatUint(storeID, 10),
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+)
+
+// Report is the result of a simulation.
+type Report struct {
+	Ticks     int  `json:"ticks"`
+	Converged bool `json:"converged"`
+	// ConvergenceTick is the tick of the last operator or event, and
+	// ConvergenceTime is its simulated time.
+	ConvergenceTick int           `json:"convergence_tick"`
+	ConvergenceTime time.Duration `json:"convergence_time"`
+	// MovedSize is the size in MB of the snapshots sent.
+	MovedSize  int64          `json:"moved_size"`
+	TaskCounts map[string]int `json:"task_counts"`
+	// LeaderDeviation and RegionDeviation are the max absolute deviation
+	// ratio of the stores from their ideal sizes under the store weights.
+	LeaderDeviation float64             `json:"leader_deviation"`
+	RegionDeviation float64             `json:"region_deviation"`
+	Plan            *server.BalancePlan `json:"plan"`
+}
+
+func (d *Driver) report() (*Report, error) {
+	plan, err := d.server.GetHandler().GetBalancePlan(nil)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	r := &Report{
+		Ticks:           d.tick,
+		Converged:       d.isConverged(),
+		ConvergenceTick: d.convergenceTick(),
+		MovedSize:       d.raft.MovedSize(),
+		TaskCounts:      make(map[string]int),
+		Plan:            plan,
+	}
+	r.ConvergenceTime = time.Duration(r.ConvergenceTick) * d.scenario.TickInterval.Duration
+	for _, node := range d.nodes {
+		for t, count := range node.taskCounts {
+			r.TaskCounts[t] += count
+		}
+	}
+	for _, s := range plan.Stores {
+		r.LeaderDeviation = math.Max(r.LeaderDeviation, math.Abs(s.LeaderDeviationRatio))
+		r.RegionDeviation = math.Max(r.RegionDeviation, math.Abs(s.RegionDeviationRatio))
+	}
+	return r, nil
+}
+
+func (r *Report) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "ticks: %d, converged: %v\n", r.Ticks, r.Converged)
+	fmt.Fprintf(&buf, "convergence time: %v (tick %d)\n", r.ConvergenceTime, r.ConvergenceTick)
+	fmt.Fprintf(&buf, "moved size: %d MB\n", r.MovedSize)
+	types := make([]string, 0, len(r.TaskCounts))
+	for t := range r.TaskCounts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(&buf, "%s: %d\n", t, r.TaskCounts[t])
+	}
+	fmt.Fprintf(&buf, "max leader deviation: %.2f%%, max region deviation: %.2f%%\n", r.LeaderDeviation*100, r.RegionDeviation*100)
+	for _, s := range r.Plan.Stores {
+		fmt.Fprintf(&buf, "store %d: leader %d/%.0f MB (weight %.2f), region %d/%.0f MB (weight %.2f)\n",
+			s.StoreID, s.LeaderSize, s.IdealLeaderSize, s.LeaderWeight, s.RegionSize, s.IdealRegionSize, s.RegionWeight)
+	}
+	return buf.String()
+}
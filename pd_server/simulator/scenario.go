@@ -0,0 +1,230 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/typeutil"
+	"github.com/pingcap/pd/server"
+)
+
+const (
+	defaultTickInterval         = 100 * time.Millisecond
+	defaultMaxTicks             = 6000
+	defaultConvergenceTicks     = 300
+	defaultStoreHeartbeatTicks  = 10
+	defaultRegionHeartbeatTicks = 60
+	defaultSnapshotSpeed        = 8
+	defaultRegionSize           = 96
+)
+
+// Event types of a scenario.
+const (
+	StoreDownEvent    = "store-down"
+	StoreUpEvent      = "store-up"
+	WeightChangeEvent = "weight-change"
+	RegionSplitEvent  = "region-split"
+)
+
+// Scenario describes the simulated cluster and the events to apply. A tick
+// is the unit of the simulated time.
+type Scenario struct {
+	// TickInterval is the wall time of a tick.
+	TickInterval typeutil.Duration `toml:"tick-interval" json:"tick-interval"`
+	// MaxTicks is the max number of ticks to simulate.
+	MaxTicks int `toml:"max-ticks" json:"max-ticks"`
+	// ConvergenceTicks is the number of ticks without new operators after
+	// the last event for the cluster to be considered converged.
+	ConvergenceTicks int `toml:"convergence-ticks" json:"convergence-ticks"`
+	// StoreHeartbeatTicks and RegionHeartbeatTicks are the heartbeat
+	// intervals of the simulated stores.
+	StoreHeartbeatTicks  int `toml:"store-heartbeat-ticks" json:"store-heartbeat-ticks"`
+	RegionHeartbeatTicks int `toml:"region-heartbeat-ticks" json:"region-heartbeat-ticks"`
+	// SnapshotSpeed is the size in MB of a snapshot sent in a tick.
+	SnapshotSpeed int64 `toml:"snapshot-speed" json:"snapshot-speed"`
+
+	Schedule    server.ScheduleConfig    `toml:"schedule" json:"schedule"`
+	Replication server.ReplicationConfig `toml:"replication" json:"replication"`
+
+	Stores  []*StoreConfig  `toml:"stores" json:"stores"`
+	Regions []*RegionConfig `toml:"regions" json:"regions"`
+	Events  []*EventConfig  `toml:"events" json:"events"`
+}
+
+// StoreConfig is a simulated store. Zero weights are left to PD.
+type StoreConfig struct {
+	ID           uint64            `toml:"id" json:"id"`
+	Capacity     typeutil.ByteSize `toml:"capacity" json:"capacity"`
+	LeaderWeight float64           `toml:"leader-weight" json:"leader-weight"`
+	RegionWeight float64           `toml:"region-weight" json:"region-weight"`
+	Labels       map[string]string `toml:"labels" json:"labels"`
+}
+
+// RegionConfig is a group of simulated regions, which split the key range
+// [StartKey, EndKey) evenly.
+type RegionConfig struct {
+	Count    int    `toml:"count" json:"count"`
+	StartKey string `toml:"start-key" json:"start-key"`
+	EndKey   string `toml:"end-key" json:"end-key"`
+	// Size is the approximate size of a region in MB.
+	Size int64 `toml:"size" json:"size"`
+	// Peers are the stores of the regions, and the first one is the leader.
+	Peers []uint64 `toml:"peers" json:"peers"`
+	// ReadBytes and WrittenBytes are the flow of a region in a region
+	// heartbeat interval.
+	ReadBytes    uint64 `toml:"read-bytes" json:"read-bytes"`
+	WrittenBytes uint64 `toml:"written-bytes" json:"written-bytes"`
+}
+
+// EventConfig is an event happening at a tick.
+type EventConfig struct {
+	Tick int    `toml:"tick" json:"tick"`
+	Type string `toml:"type" json:"type"`
+	// StoreID is the store of the store-down, store-up and weight-change
+	// events.
+	StoreID uint64 `toml:"store-id" json:"store-id"`
+	// LeaderWeight and RegionWeight are the new weights of the
+	// weight-change event. Zero keeps the weight unchanged.
+	LeaderWeight float64 `toml:"leader-weight" json:"leader-weight"`
+	RegionWeight float64 `toml:"region-weight" json:"region-weight"`
+	// RegionID is the region to split. Zero splits the largest regions,
+	// Count times.
+	RegionID uint64 `toml:"region-id" json:"region-id"`
+	Count    int    `toml:"count" json:"count"`
+}
+
+// LoadScenario loads a scenario from the toml file. The schedule and
+// replication config not specified are the defaults of PD.
+func LoadScenario(path string) (*Scenario, error) {
+	cfg := server.NewConfig()
+	if err := cfg.Parse(nil); err != nil {
+		return nil, errors.Trace(err)
+	}
+	s := &Scenario{
+		Schedule:    cfg.Schedule,
+		Replication: cfg.Replication,
+	}
+	if _, err := toml.DecodeFile(path, s); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := s.adjust(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return s, nil
+}
+
+func (s *Scenario) adjust() error {
+	adjustDuration(&s.TickInterval, defaultTickInterval)
+	adjustInt(&s.MaxTicks, defaultMaxTicks)
+	adjustInt(&s.ConvergenceTicks, defaultConvergenceTicks)
+	adjustInt(&s.StoreHeartbeatTicks, defaultStoreHeartbeatTicks)
+	adjustInt(&s.RegionHeartbeatTicks, defaultRegionHeartbeatTicks)
+	if s.SnapshotSpeed == 0 {
+		s.SnapshotSpeed = defaultSnapshotSpeed
+	}
+	for _, r := range s.Regions {
+		adjustInt(&r.Count, 1)
+		if r.Size == 0 {
+			r.Size = defaultRegionSize
+		}
+	}
+	for _, e := range s.Events {
+		adjustInt(&e.Count, 1)
+	}
+	return errors.Trace(s.validate())
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Stores) == 0 {
+		return errors.New("no store in the scenario")
+	}
+	stores := make(map[uint64]struct{}, len(s.Stores))
+	for _, store := range s.Stores {
+		if store.ID == 0 {
+			return errors.New("invalid zero store id")
+		}
+		if _, ok := stores[store.ID]; ok {
+			return errors.Errorf("duplicated store %d", store.ID)
+		}
+		if store.LeaderWeight < 0 || store.RegionWeight < 0 {
+			return errors.Errorf("store %d has negative weight", store.ID)
+		}
+		stores[store.ID] = struct{}{}
+	}
+
+	if len(s.Regions) == 0 {
+		return errors.New("no region in the scenario")
+	}
+	for i, r := range s.Regions {
+		if r.EndKey != "" && r.StartKey >= r.EndKey {
+			return errors.Errorf("region group %d has invalid key range [%q, %q)", i, r.StartKey, r.EndKey)
+		}
+		if len(r.Peers) == 0 {
+			return errors.Errorf("region group %d has no peer", i)
+		}
+		peers := make(map[uint64]struct{}, len(r.Peers))
+		for _, id := range r.Peers {
+			if _, ok := stores[id]; !ok {
+				return errors.Errorf("region group %d has peer on unknown store %d", i, id)
+			}
+			if _, ok := peers[id]; ok {
+				return errors.Errorf("region group %d has multiple peers on store %d", i, id)
+			}
+			peers[id] = struct{}{}
+		}
+	}
+	// The key ranges of the groups must not overlap.
+	groups := append([]*RegionConfig(nil), s.Regions...)
+	sort.Slice(groups, func(i, j int) bool { return groups[i].StartKey < groups[j].StartKey })
+	for i := 1; i < len(groups); i++ {
+		if prev := groups[i-1]; prev.EndKey == "" || prev.EndKey > groups[i].StartKey {
+			return errors.Errorf("region groups [%q, %q) and [%q, %q) overlap", prev.StartKey, prev.EndKey, groups[i].StartKey, groups[i].EndKey)
+		}
+	}
+
+	for _, e := range s.Events {
+		if e.Tick <= 0 {
+			return errors.Errorf("%s event has invalid tick %d", e.Type, e.Tick)
+		}
+		switch e.Type {
+		case StoreDownEvent, StoreUpEvent, WeightChangeEvent:
+			if _, ok := stores[e.StoreID]; !ok {
+				return errors.Errorf("%s event at tick %d has unknown store %d", e.Type, e.Tick, e.StoreID)
+			}
+			if e.LeaderWeight < 0 || e.RegionWeight < 0 {
+				return errors.Errorf("%s event at tick %d has negative weight", e.Type, e.Tick)
+			}
+		case RegionSplitEvent:
+		default:
+			return errors.Errorf("unknown event type %q", e.Type)
+		}
+	}
+	return nil
+}
+
+func adjustInt(v *int, defValue int) {
+	if *v == 0 {
+		*v = defValue
+	}
+}
+
+func adjustDuration(v *typeutil.Duration, defValue time.Duration) {
+	if v.Duration == 0 {
+		v.Duration = defValue
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testScenarioSuite{})
+
+type testScenarioSuite struct{}
+
+func newTestScenario() *Scenario {
+	return &Scenario{
+		Stores: []*StoreConfig{
+			{ID: 1},
+			{ID: 2, LeaderWeight: 2, RegionWeight: 2},
+		},
+		Regions: []*RegionConfig{
+			{Count: 10, StartKey: "a", EndKey: "b", Peers: []uint64{1, 2}},
+			{EndKey: "a", Peers: []uint64{2}},
+		},
+		Events: []*EventConfig{
+			{Tick: 10, Type: StoreDownEvent, StoreID: 2},
+			{Tick: 20, Type: RegionSplitEvent},
+		},
+	}
+}
+
+func (s *testScenarioSuite) TestAdjust(c *C) {
+	scenario := newTestScenario()
+	c.Assert(scenario.adjust(), IsNil)
+	c.Assert(scenario.TickInterval.Duration, Equals, defaultTickInterval)
+	c.Assert(scenario.MaxTicks, Equals, defaultMaxTicks)
+	c.Assert(scenario.SnapshotSpeed, Equals, int64(defaultSnapshotSpeed))
+	c.Assert(scenario.Regions[0].Count, Equals, 10)
+	c.Assert(scenario.Regions[1].Count, Equals, 1)
+	c.Assert(scenario.Regions[1].Size, Equals, int64(defaultRegionSize))
+	c.Assert(scenario.Events[1].Count, Equals, 1)
+}
+
+func (s *testScenarioSuite) TestValidate(c *C) {
+	invalids := []func(*Scenario){
+		func(s *Scenario) { s.Stores = nil },
+		func(s *Scenario) { s.Stores[1].ID = 1 },
+		func(s *Scenario) { s.Stores[0].RegionWeight = -1 },
+		func(s *Scenario) { s.Regions = nil },
+		func(s *Scenario) { s.Regions[0].EndKey = "a" },
+		func(s *Scenario) { s.Regions[0].Peers = []uint64{1, 1} },
+		func(s *Scenario) { s.Regions[0].Peers = []uint64{3} },
+		func(s *Scenario) { s.Regions[1].EndKey = "a1" },
+		func(s *Scenario) { s.Events[0].Tick = 0 },
+		func(s *Scenario) { s.Events[0].StoreID = 3 },
+		func(s *Scenario) { s.Events[0].Type = "unknown" },
+	}
+	for _, invalid := range invalids {
+		scenario := newTestScenario()
+		invalid(scenario)
+		c.Assert(scenario.adjust(), NotNil)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"fmt"
+
+	"github.com/pingcap/kvproto/pkg/eraftpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server/core"
+)
+
+// Task is a schedule command applied by the leader store of a region.
+type Task interface {
+	Desc() string
+	RegionID() uint64
+	// Step runs the task for a tick, and returns whether it is finished.
+	Step(r *RaftEngine) bool
+}
+
+// responseToTask converts a region heartbeat response to a task. A snapshot
+// of snapshotSpeed MB is sent in a tick for an added peer.
+func responseToTask(resp *pdpb.RegionHeartbeatResponse, snapshotSpeed int64) Task {
+	regionID := resp.GetRegionId()
+	if tl := resp.GetTransferLeader(); tl != nil {
+		return &transferLeader{regionID: regionID, peer: tl.GetPeer()}
+	}
+	if cp := resp.GetChangePeer(); cp != nil {
+		switch cp.GetChangeType() {
+		case eraftpb.ConfChangeType_AddNode:
+			return &addPeer{regionID: regionID, peer: cp.GetPeer(), speed: snapshotSpeed}
+		case eraftpb.ConfChangeType_RemoveNode:
+			return &removePeer{regionID: regionID, peer: cp.GetPeer()}
+		}
+	}
+	return nil
+}
+
+// transferLeader transfers the leader in a tick.
+type transferLeader struct {
+	regionID uint64
+	peer     *metapb.Peer
+}
+
+func (t *transferLeader) Desc() string {
+	return fmt.Sprintf("transfer leader of region %d to store %d", t.regionID, t.peer.GetStoreId())
+}
+
+func (t *transferLeader) RegionID() uint64 {
+	return t.regionID
+}
+
+func (t *transferLeader) Step(r *RaftEngine) bool {
+	region := r.GetRegion(t.regionID)
+	if region == nil {
+		return true
+	}
+	peer := region.GetStorePeer(t.peer.GetStoreId())
+	if peer == nil || region.GetPendingPeer(peer.GetId()) != nil || !r.IsStoreUp(peer.GetStoreId()) {
+		return true
+	}
+	region.Leader = peer
+	r.SetRegion(region)
+	return true
+}
+
+// addPeer adds a pending peer, which becomes normal after the snapshot is
+// sent.
+type addPeer struct {
+	regionID uint64
+	peer     *metapb.Peer
+	speed    int64
+	added    bool
+	size     int64
+	sent     int64
+}
+
+func (t *addPeer) Desc() string {
+	return fmt.Sprintf("add peer of region %d on store %d", t.regionID, t.peer.GetStoreId())
+}
+
+func (t *addPeer) RegionID() uint64 {
+	return t.regionID
+}
+
+func (t *addPeer) Step(r *RaftEngine) bool {
+	region := r.GetRegion(t.regionID)
+	if region == nil {
+		return true
+	}
+	if !t.added {
+		if region.GetStorePeer(t.peer.GetStoreId()) != nil {
+			return true
+		}
+		region.Peers = append(region.Peers, t.peer)
+		region.PendingPeers = append(region.PendingPeers, t.peer)
+		region.RegionEpoch.ConfVer++
+		r.SetRegion(region)
+		t.added, t.size = true, region.ApproximateSize
+		return false
+	}
+	if region.GetPeer(t.peer.GetId()) == nil {
+		// The peer is removed before the snapshot is sent.
+		return true
+	}
+	if !r.IsStoreUp(t.peer.GetStoreId()) {
+		return false
+	}
+	t.sent += t.speed
+	if t.sent < t.size {
+		return false
+	}
+	removePendingPeer(region, t.peer.GetId())
+	r.SetRegion(region)
+	r.addMovedSize(t.size)
+	return true
+}
+
+// removePeer removes a peer in a tick.
+type removePeer struct {
+	regionID uint64
+	peer     *metapb.Peer
+}
+
+func (t *removePeer) Desc() string {
+	return fmt.Sprintf("remove peer of region %d on store %d", t.regionID, t.peer.GetStoreId())
+}
+
+func (t *removePeer) RegionID() uint64 {
+	return t.regionID
+}
+
+func (t *removePeer) Step(r *RaftEngine) bool {
+	region := r.GetRegion(t.regionID)
+	if region == nil || region.GetPeer(t.peer.GetId()) == nil || region.Leader.GetId() == t.peer.GetId() {
+		return true
+	}
+	region.RemoveStorePeer(t.peer.GetStoreId())
+	removePendingPeer(region, t.peer.GetId())
+	region.RegionEpoch.ConfVer++
+	r.SetRegion(region)
+	return true
+}
+
+func removePendingPeer(region *core.RegionInfo, peerID uint64) {
+	var pendingPeers []*metapb.Peer
+	for _, p := range region.PendingPeers {
+		if p.GetId() != peerID {
+			pendingPeers = append(pendingPeers, p)
+		}
+	}
+	region.PendingPeers = pendingPeers
+}
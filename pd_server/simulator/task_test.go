@@ -0,0 +1,104 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/eraftpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testTaskSuite{})
+
+type testTaskSuite struct{}
+
+func (s *testTaskSuite) TestAddPeer(c *C) {
+	r := NewRaftEngine()
+	r.SetRegion(newTestRegion(1, 20, 1, 2))
+	task := responseToTask(&pdpb.RegionHeartbeatResponse{
+		RegionId: 1,
+		ChangePeer: &pdpb.ChangePeer{
+			Peer:       &metapb.Peer{Id: 10, StoreId: 3},
+			ChangeType: eraftpb.ConfChangeType_AddNode,
+		},
+	}, 8)
+	c.Assert(task, FitsTypeOf, &addPeer{})
+
+	// The peer is pending until the snapshot of 20 MB is sent in 3 ticks.
+	c.Assert(task.Step(r), IsFalse)
+	region := r.GetRegion(1)
+	c.Assert(region.GetPendingPeer(10), NotNil)
+	c.Assert(region.GetRegionEpoch().GetConfVer(), Equals, uint64(2))
+	c.Assert(task.Step(r), IsFalse)
+
+	r.SetStoreDown(3, time.Now())
+	c.Assert(task.Step(r), IsFalse)
+	r.SetStoreUp(3)
+
+	c.Assert(task.Step(r), IsFalse)
+	c.Assert(task.Step(r), IsTrue)
+	c.Assert(r.GetRegion(1).GetPendingPeer(10), IsNil)
+	c.Assert(r.MovedSize(), Equals, int64(20))
+}
+
+func (s *testTaskSuite) TestRemovePeer(c *C) {
+	r := NewRaftEngine()
+	r.SetRegion(newTestRegion(1, 20, 1, 2, 3))
+	newRemovePeer := func(storeID uint64) Task {
+		return responseToTask(&pdpb.RegionHeartbeatResponse{
+			RegionId: 1,
+			ChangePeer: &pdpb.ChangePeer{
+				Peer:       r.GetRegion(1).GetStorePeer(storeID),
+				ChangeType: eraftpb.ConfChangeType_RemoveNode,
+			},
+		}, 8)
+	}
+
+	// The leader is not removed.
+	c.Assert(newRemovePeer(1).Step(r), IsTrue)
+	c.Assert(r.GetRegion(1).GetPeers(), HasLen, 3)
+
+	c.Assert(newRemovePeer(3).Step(r), IsTrue)
+	region := r.GetRegion(1)
+	c.Assert(region.GetPeers(), HasLen, 2)
+	c.Assert(region.GetStorePeer(3), IsNil)
+	c.Assert(region.GetRegionEpoch().GetConfVer(), Equals, uint64(2))
+}
+
+func (s *testTaskSuite) TestTransferLeader(c *C) {
+	r := NewRaftEngine()
+	region := newTestRegion(1, 20, 1, 2, 3)
+	region.PendingPeers = append(region.PendingPeers, region.GetStorePeer(3))
+	r.SetRegion(region)
+	newTransferLeader := func(storeID uint64) Task {
+		return responseToTask(&pdpb.RegionHeartbeatResponse{
+			RegionId:       1,
+			TransferLeader: &pdpb.TransferLeader{Peer: r.GetRegion(1).GetStorePeer(storeID)},
+		}, 8)
+	}
+
+	// The leader is not transferred to a pending peer or a down store.
+	c.Assert(newTransferLeader(3).Step(r), IsTrue)
+	c.Assert(r.GetRegion(1).Leader.GetStoreId(), Equals, uint64(1))
+	r.SetStoreDown(2, time.Now())
+	c.Assert(newTransferLeader(2).Step(r), IsTrue)
+	c.Assert(r.GetRegion(1).Leader.GetStoreId(), Equals, uint64(1))
+
+	r.SetStoreUp(2)
+	c.Assert(newTransferLeader(2).Step(r), IsTrue)
+	c.Assert(r.GetRegion(1).Leader.GetStoreId(), Equals, uint64(2))
+}
@@ -14,6 +14,7 @@
 package server
 
 import (
+	"math"
 	"strconv"
 
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -76,10 +77,17 @@ func (s *storeStatistics) Observe(store *core.StoreInfo) {
 	id := strconv.FormatUint(store.GetId(), 10)
 	storeStatusGauge.WithLabelValues(s.namespace, id, "region_score").Set(store.RegionScore())
 	storeStatusGauge.WithLabelValues(s.namespace, id, "leader_score").Set(store.LeaderScore())
+	storeStatusGauge.WithLabelValues(s.namespace, id, "space_factor").Set(math.Max(store.SpaceFactor, 1))
 	storeStatusGauge.WithLabelValues(s.namespace, id, "region_size").Set(float64(store.RegionSize))
 	storeStatusGauge.WithLabelValues(s.namespace, id, "region_count").Set(float64(store.RegionCount))
 	storeStatusGauge.WithLabelValues(s.namespace, id, "leader_size").Set(float64(store.LeaderSize))
 	storeStatusGauge.WithLabelValues(s.namespace, id, "leader_count").Set(float64(store.LeaderCount))
+	if limit := store.GetStoreLimit(core.StoreAddPeer); limit != nil {
+		storeStatusGauge.WithLabelValues(s.namespace, id, "add_peer_tokens").Set(limit.Available())
+	}
+	if limit := store.GetStoreLimit(core.StoreRemovePeer); limit != nil {
+		storeStatusGauge.WithLabelValues(s.namespace, id, "remove_peer_tokens").Set(limit.Available())
+	}
 }
 
 func (s *storeStatistics) Collect() {
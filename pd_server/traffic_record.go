@@ -0,0 +1,264 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	trafficRecordBufferSize    = 64 * 1024
+	trafficRecordFlushInterval = time.Second
+	// trafficRecordHeaderSize is the size of the timestamp and the type of
+	// a record.
+	trafficRecordHeaderSize = 9
+	maxTrafficRecordSize    = 64 * 1024 * 1024
+)
+
+// TrafficRecordType is the type of a recorded request.
+type TrafficRecordType byte
+
+// Types of the recorded requests.
+const (
+	RecordStoreHeartbeat TrafficRecordType = iota + 1
+	RecordRegionHeartbeat
+	RecordAskSplit
+	RecordReportSplit
+	RecordPutStore
+)
+
+var trafficRecordTypeNames = map[TrafficRecordType]string{
+	RecordStoreHeartbeat:  "store-heartbeat",
+	RecordRegionHeartbeat: "region-heartbeat",
+	RecordAskSplit:        "ask-split",
+	RecordReportSplit:     "report-split",
+	RecordPutStore:        "put-store",
+}
+
+func (t TrafficRecordType) String() string {
+	if name, ok := trafficRecordTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// TrafficRecord is a request recorded by the leader.
+type TrafficRecord struct {
+	Time    time.Time
+	Type    TrafficRecordType
+	Request proto.Message
+}
+
+// trafficRecorder appends the requests to a file. A record is the unix
+// nano timestamp in 8 bytes, the type in 1 byte, the length of the request
+// in uvarint, and the request in protobuf. The file is rotated to
+// "<path>.1" once it exceeds the max size, so at most two files are kept.
+type trafficRecorder struct {
+	sync.Mutex
+	path    string
+	maxSize int64
+	size    int64
+	f       *os.File
+	w       *bufio.Writer
+	header  [trafficRecordHeaderSize + binary.MaxVarintLen64]byte
+	done    chan struct{}
+}
+
+func newTrafficRecorder(path string, maxSize int64) (*trafficRecorder, error) {
+	r := &trafficRecorder{
+		path:    path,
+		maxSize: maxSize,
+		done:    make(chan struct{}),
+	}
+	if err := r.open(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	go r.flushLoop()
+	return r, nil
+}
+
+func (r *trafficRecorder) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	r.f, r.w, r.size = f, bufio.NewWriterSize(f, trafficRecordBufferSize), info.Size()
+	return nil
+}
+
+// flushLoop flushes the buffered records in time so that the log is useful
+// even if PD crashes or the traffic stops.
+func (r *trafficRecorder) flushLoop() {
+	ticker := time.NewTicker(trafficRecordFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Lock()
+			if r.f != nil {
+				if err := r.w.Flush(); err != nil {
+					log.Errorf("flush traffic records error: %v", err)
+				}
+			}
+			r.Unlock()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// record appends the request. A nil recorder records nothing, and the
+// errors are only logged to not affect the request.
+func (r *trafficRecorder) record(t TrafficRecordType, req proto.Message) {
+	if r == nil {
+		return
+	}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		log.Errorf("marshal %s record error: %v", t, err)
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if r.f == nil {
+		return
+	}
+	binary.BigEndian.PutUint64(r.header[:], uint64(time.Now().UnixNano()))
+	r.header[8] = byte(t)
+	n := trafficRecordHeaderSize + binary.PutUvarint(r.header[trafficRecordHeaderSize:], uint64(len(data)))
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(n+len(data)) > r.maxSize {
+		if err = r.rotate(); err != nil {
+			log.Errorf("rotate traffic record file error: %v", err)
+			return
+		}
+	}
+	if _, err = r.w.Write(r.header[:n]); err == nil {
+		_, err = r.w.Write(data)
+	}
+	r.size += int64(n + len(data))
+	if err != nil {
+		log.Errorf("write %s record error: %v", t, err)
+	}
+}
+
+// rotate renames the current file to "<path>.1", replacing the old one, and
+// starts a new file.
+func (r *trafficRecorder) rotate() error {
+	err := r.w.Flush()
+	if e := r.f.Close(); err == nil {
+		err = e
+	}
+	r.f = nil
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = os.Rename(r.path, r.path+".1"); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(r.open())
+}
+
+func (r *trafficRecorder) close() error {
+	if r == nil {
+		return nil
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	close(r.done)
+	err := r.w.Flush()
+	if e := r.f.Close(); err == nil {
+		err = e
+	}
+	r.f = nil
+	return errors.Trace(err)
+}
+
+// TrafficReader reads the records of a traffic log.
+type TrafficReader struct {
+	r *bufio.Reader
+}
+
+// NewTrafficReader creates a TrafficReader.
+func NewTrafficReader(r io.Reader) *TrafficReader {
+	return &TrafficReader{r: bufio.NewReaderSize(r, trafficRecordBufferSize)}
+}
+
+// Next returns the next record. It returns io.EOF at the end of the log,
+// and io.ErrUnexpectedEOF if the last record is truncated.
+func (r *TrafficReader) Next() (*TrafficRecord, error) {
+	var header [trafficRecordHeaderSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		return nil, err
+	}
+	length, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if length > maxTrafficRecordSize {
+		return nil, errors.Errorf("invalid record length %d", length)
+	}
+	data := make([]byte, length)
+	if _, err = io.ReadFull(r.r, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	record := &TrafficRecord{
+		Time: time.Unix(0, int64(binary.BigEndian.Uint64(header[:]))),
+		Type: TrafficRecordType(header[8]),
+	}
+	switch record.Type {
+	case RecordStoreHeartbeat:
+		record.Request = &pdpb.StoreHeartbeatRequest{}
+	case RecordRegionHeartbeat:
+		record.Request = &pdpb.RegionHeartbeatRequest{}
+	case RecordAskSplit:
+		record.Request = &pdpb.AskSplitRequest{}
+	case RecordReportSplit:
+		record.Request = &pdpb.ReportSplitRequest{}
+	case RecordPutStore:
+		record.Request = &pdpb.PutStoreRequest{}
+	default:
+		return nil, errors.Errorf("unknown record type %d", record.Type)
+	}
+	if err = proto.Unmarshal(data, record.Request); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return record, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
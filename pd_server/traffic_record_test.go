@@ -0,0 +1,137 @@
+// Copyright 2018 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testTrafficRecordSuite{})
+
+type testTrafficRecordSuite struct{}
+
+func (s *testTrafficRecordSuite) TestRecordAndRead(c *C) {
+	dir, err := ioutil.TempDir("", "traffic_record")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "record")
+
+	r, err := newTrafficRecorder(path, 0)
+	c.Assert(err, IsNil)
+	r.record(RecordStoreHeartbeat, &pdpb.StoreHeartbeatRequest{Stats: &pdpb.StoreStats{StoreId: 1, RegionCount: 10}})
+	r.record(RecordRegionHeartbeat, &pdpb.RegionHeartbeatRequest{
+		Region: &metapb.Region{Id: 2, StartKey: []byte("a")},
+		Leader: &metapb.Peer{Id: 3, StoreId: 1},
+	})
+	r.record(RecordReportSplit, &pdpb.ReportSplitRequest{Left: &metapb.Region{Id: 4}, Right: &metapb.Region{Id: 2}})
+	c.Assert(r.close(), IsNil)
+	// Closed and nil recorders record nothing.
+	r.record(RecordAskSplit, &pdpb.AskSplitRequest{})
+	var nilRecorder *trafficRecorder
+	nilRecorder.record(RecordAskSplit, &pdpb.AskSplitRequest{})
+	c.Assert(nilRecorder.close(), IsNil)
+
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	reader := NewTrafficReader(bytes.NewReader(data))
+	record, err := reader.Next()
+	c.Assert(err, IsNil)
+	c.Assert(record.Type, Equals, RecordStoreHeartbeat)
+	c.Assert(record.Request.(*pdpb.StoreHeartbeatRequest).GetStats().GetRegionCount(), Equals, uint32(10))
+	record, err = reader.Next()
+	c.Assert(err, IsNil)
+	c.Assert(record.Type, Equals, RecordRegionHeartbeat)
+	c.Assert(record.Request.(*pdpb.RegionHeartbeatRequest).GetRegion().GetStartKey(), DeepEquals, []byte("a"))
+	last, err := reader.Next()
+	c.Assert(err, IsNil)
+	c.Assert(last.Type, Equals, RecordReportSplit)
+	c.Assert(last.Time.Before(record.Time), IsFalse)
+	_, err = reader.Next()
+	c.Assert(err, Equals, io.EOF)
+
+	// A truncated record is reported.
+	reader = NewTrafficReader(bytes.NewReader(data[:len(data)-1]))
+	for i := 0; i < 2; i++ {
+		_, err = reader.Next()
+		c.Assert(err, IsNil)
+	}
+	_, err = reader.Next()
+	c.Assert(err, Equals, io.ErrUnexpectedEOF)
+
+	// Records are appended to the existing log.
+	r, err = newTrafficRecorder(path, 0)
+	c.Assert(err, IsNil)
+	r.record(RecordAskSplit, &pdpb.AskSplitRequest{Region: &metapb.Region{Id: 5}})
+	c.Assert(r.close(), IsNil)
+	data, err = ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	reader = NewTrafficReader(bytes.NewReader(data))
+	for i := 0; i < 3; i++ {
+		_, err = reader.Next()
+		c.Assert(err, IsNil)
+	}
+	record, err = reader.Next()
+	c.Assert(err, IsNil)
+	c.Assert(record.Request.(*pdpb.AskSplitRequest).GetRegion().GetId(), Equals, uint64(5))
+}
+
+func (s *testTrafficRecordSuite) TestFlushAndRotate(c *C) {
+	dir, err := ioutil.TempDir("", "traffic_record")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "record")
+
+	req := &pdpb.StoreHeartbeatRequest{Stats: &pdpb.StoreStats{StoreId: 1}}
+	r, err := newTrafficRecorder(path, 1<<10)
+	c.Assert(err, IsNil)
+	defer r.close()
+
+	// The buffered record is flushed without more records or closing.
+	r.record(RecordStoreHeartbeat, req)
+	time.Sleep(trafficRecordFlushInterval + 500*time.Millisecond)
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(data, Not(HasLen), 0)
+
+	// The file is rotated once it exceeds the max size.
+	for i := 0; i < 100; i++ {
+		r.record(RecordStoreHeartbeat, req)
+	}
+	c.Assert(r.close(), IsNil)
+	for _, p := range []string{path, path + ".1"} {
+		info, err := os.Stat(p)
+		c.Assert(err, IsNil)
+		c.Assert(info.Size(), LessEqual, int64(1<<10))
+		data, err := ioutil.ReadFile(p)
+		c.Assert(err, IsNil)
+		reader := NewTrafficReader(bytes.NewReader(data))
+		for {
+			record, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			c.Assert(err, IsNil)
+			c.Assert(record.Type, Equals, RecordStoreHeartbeat)
+		}
+	}
+}